@@ -0,0 +1,248 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	cfg "sigs.k8s.io/controller-runtime/pkg/config/v1alpha1"
+)
+
+//+kubebuilder:object:root=true
+
+// OperatorConfig is the Schema for the configuration file of the operator, the resync period is set
+// by syncPeriod and the MaxConcurrentReconciles of each controller by controller.groupKindConcurrency
+type OperatorConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// ControllerManagerConfigurationSpec returns the configurations for controllers
+	cfg.ControllerManagerConfigurationSpec `json:",inline"`
+
+	// Images are the images used when a custom resource omits them
+	// +optional
+	Images Images `json:"images,omitempty"`
+
+	// ImageRewrite points all the images of the instances to other registries, including the default images
+	// +optional
+	ImageRewrite ImageRewrite `json:"imageRewrite,omitempty"`
+
+	// Ports are the ports used when a custom resource does not set them
+	// +optional
+	Ports Ports `json:"ports,omitempty"`
+
+	// Probes are the timings of the default readiness and liveness probes
+	// +optional
+	Probes Probes `json:"probes,omitempty"`
+
+	// Migration configures the Jobs that copy the persistent data when the volume claim template of an
+	// instance changes
+	// +optional
+	Migration Migration `json:"migration,omitempty"`
+}
+
+// Migration configures the Jobs that copy the persistent data of the instances
+type Migration struct {
+	// Image is the image of the copy Jobs, it needs sh and cp
+	// +optional
+	Image string `json:"image,omitempty"`
+}
+
+// ImageRewrite is the policy of clusters that pull the images from mirror registries, such as air-gapped
+// clusters
+type ImageRewrite struct {
+	// Registries maps a registry, or a registry and repository prefix, to the one to pull from instead,
+	// e.g. "docker.io: registry.example.com/dockerhub". The longest matching prefix wins, the images without
+	// registry are on docker.io.
+	// +optional
+	Registries map[string]string `json:"registries,omitempty"`
+	// ImagePullSecrets are added to the spec of all the instances, they must exist in their namespaces
+	// +optional
+	ImagePullSecrets []corev1.LocalObjectReference `json:"imagePullSecrets,omitempty"`
+}
+
+// Images is a pair of neuron and ekuiper images of the same major.minor version, both must be pinned to a
+// version tag so that the instances created with the defaults do not change on a pull
+type Images struct {
+	// Neuron is the default image of the neuron container
+	// +optional
+	Neuron string `json:"neuron,omitempty"`
+	// EKuiper is the default image of the ekuiper container
+	// +optional
+	EKuiper string `json:"ekuiper,omitempty"`
+}
+
+// Ports are the default web port of neuron and REST port of ekuiper
+type Ports struct {
+	// Neuron is the web port of neuron when spec.neuronPort is not set, the NEURON_WEB_PORT env var is set
+	// when it is not the 7000 that neuron listens on by default
+	// +optional
+	Neuron int32 `json:"neuron,omitempty"`
+	// EKuiper is the REST port of ekuiper when spec.ekuiperConfig.basic.restPort is not set
+	// +optional
+	EKuiper int32 `json:"ekuiper,omitempty"`
+}
+
+type Probes struct {
+	// +optional
+	InitialDelaySeconds int32 `json:"initialDelaySeconds,omitempty"`
+	// +optional
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// +optional
+	PeriodSeconds int32 `json:"periodSeconds,omitempty"`
+	// +optional
+	SuccessThreshold int32 `json:"successThreshold,omitempty"`
+	// +optional
+	FailureThreshold int32 `json:"failureThreshold,omitempty"`
+}
+
+// Default sets the values that are not set in the configuration file
+func (c *OperatorConfig) Default() {
+	if c.Ports.Neuron == 0 {
+		c.Ports.Neuron = 7000
+	}
+	if c.Ports.EKuiper == 0 {
+		c.Ports.EKuiper = 9081
+	}
+	if c.Probes.InitialDelaySeconds == 0 {
+		c.Probes.InitialDelaySeconds = 10
+	}
+	if c.Probes.TimeoutSeconds == 0 {
+		c.Probes.TimeoutSeconds = 1
+	}
+	if c.Probes.PeriodSeconds == 0 {
+		c.Probes.PeriodSeconds = 5
+	}
+	if c.Probes.SuccessThreshold == 0 {
+		c.Probes.SuccessThreshold = 1
+	}
+	if c.Probes.FailureThreshold == 0 {
+		c.Probes.FailureThreshold = 12
+	}
+	if c.Migration.Image == "" {
+		c.Migration.Image = "busybox:1.36"
+	}
+}
+
+// Validate checks that the default images are a pinned pair of the same major.minor version, and the default
+// ports and the image rewrite policy
+func (c *OperatorConfig) Validate() error {
+	if (c.Images.Neuron == "") != (c.Images.EKuiper == "") {
+		return fmt.Errorf("images.neuron and images.ekuiper must be set together")
+	}
+	if c.Images.Neuron != "" {
+		var versions []string
+		for _, image := range []string{c.Images.Neuron, c.Images.EKuiper} {
+			version := MajorMinor(ImageVersion(image))
+			if version == "" {
+				return fmt.Errorf("default image %s must be pinned to a version tag such as 2.3.0", image)
+			}
+			versions = append(versions, version)
+		}
+		if versions[0] != versions[1] {
+			return fmt.Errorf("default images %s and %s must have the same major.minor version",
+				c.Images.Neuron, c.Images.EKuiper)
+		}
+	}
+	if c.Ports.Neuron < 0 || c.Ports.Neuron > 65535 {
+		return fmt.Errorf("ports.neuron must be a port number, got %d", c.Ports.Neuron)
+	}
+	if c.Ports.EKuiper < 0 || c.Ports.EKuiper > 65535 {
+		return fmt.Errorf("ports.ekuiper must be a port number, got %d", c.Ports.EKuiper)
+	}
+	for from, to := range c.ImageRewrite.Registries {
+		if from == "" || to == "" {
+			return fmt.Errorf("imageRewrite.registries must not rewrite %q to %q", from, to)
+		}
+	}
+	for _, secret := range c.ImageRewrite.ImagePullSecrets {
+		if secret.Name == "" {
+			return fmt.Errorf("imageRewrite.imagePullSecrets must have a name")
+		}
+	}
+	return nil
+}
+
+// ImageVersion returns the tag of an image, or its digest if it has no tag
+func ImageVersion(image string) string {
+	name, digest, _ := strings.Cut(image, "@")
+	// the tag is after the last colon, unless the colon is part of the registry host:port
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		return name[i+1:]
+	}
+	return digest
+}
+
+// MajorMinor returns the major.minor of a version such as 2.3.0, v1.8 or 1.8.0-slim, or "" if the
+// version does not start with a major and a minor number
+func MajorMinor(version string) string {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return ""
+	}
+	// the minor can be followed by a suffix when there is no patch, as in 1.8-slim
+	parts[1], _, _ = strings.Cut(parts[1], "-")
+	for _, part := range parts[:2] {
+		if _, err := strconv.ParseUint(part, 10, 32); err != nil {
+			return ""
+		}
+	}
+	return parts[0] + "." + parts[1]
+}
+
+// RewriteImage points an image to the registry of the longest matching prefix of the image rewrite policy,
+// it returns the image unchanged if no prefix matches
+func (c *OperatorConfig) RewriteImage(image string) string {
+	if image == "" || len(c.ImageRewrite.Registries) == 0 {
+		return image
+	}
+	name := normalizeImage(image)
+	match, to := "", ""
+	for prefix, registry := range c.ImageRewrite.Registries {
+		prefix = strings.TrimSuffix(prefix, "/")
+		if len(prefix) > len(match) && strings.HasPrefix(name, prefix) &&
+			(len(name) == len(prefix) || strings.ContainsRune("/:@", rune(name[len(prefix)]))) {
+			match, to = prefix, registry
+		}
+	}
+	if match == "" {
+		return image
+	}
+	return strings.TrimSuffix(to, "/") + name[len(match):]
+}
+
+// normalizeImage returns the fully qualified image, with the docker.io registry and library repository
+// of the short names
+func normalizeImage(image string) string {
+	first, rest, found := strings.Cut(image, "/")
+	if found && (strings.ContainsAny(first, ".:") || first == "localhost") {
+		return image
+	}
+	if !found {
+		rest = "library/" + first
+	} else {
+		rest = image
+	}
+	return "docker.io/" + rest
+}
+
+func init() {
+	SchemeBuilder.Register(&OperatorConfig{})
+}
@@ -0,0 +1,100 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+)
+
+func TestImageVersion(t *testing.T) {
+	assert.Equal(t, "2.3.0", ImageVersion("emqx/neuron:2.3.0"))
+	assert.Equal(t, "2.3.0", ImageVersion("registry.example.com:5000/emqx/neuron:2.3.0"))
+	assert.Equal(t, "", ImageVersion("registry.example.com:5000/emqx/neuron"))
+	assert.Equal(t, "1.8.0-slim", ImageVersion("lfedge/ekuiper:1.8.0-slim@sha256:0123"))
+	assert.Equal(t, "sha256:0123", ImageVersion("lfedge/ekuiper@sha256:0123"))
+}
+
+func TestValidate(t *testing.T) {
+	config := &OperatorConfig{}
+	assert.Nil(t, config.Validate())
+
+	config.Images = Images{Neuron: "emqx/neuron:2.3.0"}
+	assert.ErrorContains(t, config.Validate(), "must be set together")
+
+	config.Images.EKuiper = "lfedge/ekuiper:latest-slim"
+	assert.ErrorContains(t, config.Validate(), "lfedge/ekuiper:latest-slim must be pinned")
+
+	config.Images.EKuiper = "lfedge/ekuiper"
+	assert.ErrorContains(t, config.Validate(), "lfedge/ekuiper must be pinned")
+
+	config.Images.EKuiper = "lfedge/ekuiper@sha256:0123"
+	assert.ErrorContains(t, config.Validate(), "lfedge/ekuiper@sha256:0123 must be pinned")
+
+	config.Images.EKuiper = "lfedge/ekuiper:1.8.0-slim"
+	assert.ErrorContains(t, config.Validate(), "must have the same major.minor version")
+
+	config.Images.EKuiper = "lfedge/ekuiper:2.3.1-slim@sha256:0123"
+	assert.Nil(t, config.Validate())
+}
+
+func TestMajorMinor(t *testing.T) {
+	assert.Equal(t, "2.3", MajorMinor("2.3.0"))
+	assert.Equal(t, "1.8", MajorMinor("v1.8"))
+	assert.Equal(t, "1.8", MajorMinor("1.8-slim"))
+	assert.Equal(t, "1.10", MajorMinor("1.10.2-alpine"))
+	assert.Equal(t, "", MajorMinor("latest"))
+	assert.Equal(t, "", MajorMinor("2"))
+	assert.Equal(t, "", MajorMinor("sha256:0123"))
+}
+
+func TestRewriteImage(t *testing.T) {
+	config := &OperatorConfig{}
+	assert.Equal(t, "emqx/neuron:2.3.0", config.RewriteImage("emqx/neuron:2.3.0"))
+
+	config.ImageRewrite.Registries = map[string]string{
+		"docker.io":           "registry.example.com/dockerhub",
+		"docker.io/lfedge/":   "registry.example.com/lfedge/",
+		"quay.io":             "registry.example.com:5000/quay",
+		"registry.example.co": "unused.example.com",
+	}
+	assert.Equal(t, "registry.example.com/dockerhub/emqx/neuron:2.3.0", config.RewriteImage("emqx/neuron:2.3.0"))
+	assert.Equal(t, "registry.example.com/dockerhub/library/busybox", config.RewriteImage("busybox"))
+	assert.Equal(t, "registry.example.com/dockerhub/library/busybox:1.36", config.RewriteImage("docker.io/library/busybox:1.36"))
+	assert.Equal(t, "registry.example.com/lfedge/ekuiper:1.8.0-slim", config.RewriteImage("lfedge/ekuiper:1.8.0-slim"))
+	assert.Equal(t, "registry.example.com:5000/quay/prometheus/busybox@sha256:0123",
+		config.RewriteImage("quay.io/prometheus/busybox@sha256:0123"))
+	// the rewritten images and the other registries are left alone
+	assert.Equal(t, "registry.example.com/dockerhub/emqx/neuron:2.3.0",
+		config.RewriteImage("registry.example.com/dockerhub/emqx/neuron:2.3.0"))
+	assert.Equal(t, "localhost:5000/emqx/neuron", config.RewriteImage("localhost:5000/emqx/neuron"))
+}
+
+func TestValidatePorts(t *testing.T) {
+	config := &OperatorConfig{Ports: Ports{Neuron: 70000}}
+	assert.ErrorContains(t, config.Validate(), "ports.neuron must be a port number")
+
+	config.Ports = Ports{EKuiper: -1}
+	assert.ErrorContains(t, config.Validate(), "ports.ekuiper must be a port number")
+
+	config = &OperatorConfig{}
+	config.Default()
+	assert.Equal(t, Ports{Neuron: 7000, EKuiper: 9081}, config.Ports)
+	assert.Nil(t, config.Validate())
+}
+
+func TestValidateImageRewrite(t *testing.T) {
+	config := &OperatorConfig{ImageRewrite: ImageRewrite{Registries: map[string]string{"docker.io": ""}}}
+	assert.ErrorContains(t, config.Validate(), "imageRewrite.registries")
+
+	config.ImageRewrite.Registries["docker.io"] = "registry.example.com"
+	config.ImageRewrite.ImagePullSecrets = []corev1.LocalObjectReference{{}}
+	assert.ErrorContains(t, config.Validate(), "imageRewrite.imagePullSecrets")
+}
+
+func TestDefaultMigration(t *testing.T) {
+	config := &OperatorConfig{}
+	config.Default()
+	assert.Equal(t, "busybox:1.36", config.Migration.Image)
+	assert.Nil(t, config.Validate())
+}
@@ -0,0 +1,114 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// adoptionAnnotations are the annotations naming the existing resources adopted by an instance
+var adoptionAnnotations = []string{AdoptDeploymentAnnotation, AdoptServiceAnnotation, AdoptPVCsAnnotation}
+
+// GetAdoptedPVCs returns the existing PersistentVolumeClaims adopted by the instance, keyed by the name of the
+// persistent volume of the pod
+func GetAdoptedPVCs(ins EdgeInterface) (map[string]string, error) {
+	return parseAdoptedPVCs(ins.GetAnnotations()[AdoptPVCsAnnotation])
+}
+
+// parseAdoptedPVCs parses a comma separated list of volume=claim pairs
+func parseAdoptedPVCs(value string) (map[string]string, error) {
+	claims := map[string]string{}
+	if strings.TrimSpace(value) == "" {
+		return claims, nil
+	}
+	for _, pair := range strings.Split(value, ",") {
+		volume, claim, ok := strings.Cut(strings.TrimSpace(pair), "=")
+		if !ok || volume == "" || claim == "" {
+			return nil, fmt.Errorf("%q is not a volume=claim pair", pair)
+		}
+		if _, ok := claims[volume]; ok {
+			return nil, fmt.Errorf("volume %s is adopted twice", volume)
+		}
+		claims[volume] = claim
+	}
+	return claims, nil
+}
+
+// getPersistentVolumeNames returns the names of the persistent volumes of the pod
+func getPersistentVolumeNames(ins EdgeInterface) []string {
+	var names []string
+	if ins.GetNeuron() != nil {
+		names = append(names, "neuron-data")
+	}
+	if ins.GetEKuiper() != nil {
+		names = append(names, "ekuiper-data", "ekuiper-plugins")
+	}
+	return names
+}
+
+func validateAdoption(ins EdgeInterface, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	annotations := ins.GetAnnotations()
+	for _, key := range []string{AdoptDeploymentAnnotation, AdoptServiceAnnotation} {
+		if name, ok := annotations[key]; ok {
+			for _, msg := range validation.IsDNS1123Subdomain(name) {
+				allErrs = append(allErrs, field.Invalid(path.Key(key), name, msg))
+			}
+		}
+	}
+
+	value, ok := annotations[AdoptPVCsAnnotation]
+	if !ok {
+		return allErrs
+	}
+	claims, err := parseAdoptedPVCs(value)
+	if err != nil {
+		return append(allErrs, field.Invalid(path.Key(AdoptPVCsAnnotation), value, err.Error()))
+	}
+	if ins.GetVolumeClaimTemplate() == nil {
+		allErrs = append(allErrs, field.Invalid(path.Key(AdoptPVCsAnnotation), value,
+			"PersistentVolumeClaims can only be adopted with spec.volumeClaimTemplate"))
+	}
+	volumes := getPersistentVolumeNames(ins)
+	for _, volume := range sortedKeys(claims) {
+		if !contains(volumes, volume) {
+			allErrs = append(allErrs, field.NotSupported(path.Key(AdoptPVCsAnnotation), volume, volumes))
+		}
+		for _, msg := range validation.IsDNS1123Subdomain(claims[volume]) {
+			allErrs = append(allErrs, field.Invalid(path.Key(AdoptPVCsAnnotation), claims[volume], msg))
+		}
+	}
+	return allErrs
+}
+
+// validateAdoptionUpdate forbids adopting resources once the instance has created its own
+func validateAdoptionUpdate(new, old EdgeInterface, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, key := range adoptionAnnotations {
+		if new.GetAnnotations()[key] != old.GetAnnotations()[key] {
+			allErrs = append(allErrs, field.Forbidden(path.Key(key), "can only be set when the instance is created"))
+		}
+	}
+	return allErrs
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package v1alpha1
+
+import (
+	"reflect"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// CloneFrom copies the configuration of an existing instance in the same namespace into a new instance once
+// it is ready: the neuron nodes with their settings, groups and tags, and the ekuiper streams, tables, rules
+// and plugins. Only the components that both instances run are copied.
+type CloneFrom struct {
+	// Kind is the kind of the source instance
+	//+kubebuilder:validation:Enum=Neuron;EKuiper;NeuronEX
+	Kind string `json:"kind"`
+	// Name is the name of the source instance
+	Name string `json:"name"`
+	// NeuronTokenSecretRef selects the key of a secret holding a token of the neuron REST API, accepted by
+	// both instances. It is not needed when neuron runs without auth.
+	// +optional
+	NeuronTokenSecretRef *corev1.SecretKeySelector `json:"neuronTokenSecretRef,omitempty"`
+	// Substitutions replace strings in the copied configuration, such as the IP addresses of the devices
+	// +optional
+	Substitutions []Substitution `json:"substitutions,omitempty"`
+}
+
+// Substitution replaces all the occurrences of a string
+type Substitution struct {
+	// From is the string to replace
+	//+kubebuilder:validation:MinLength=1
+	From string `json:"from"`
+	// To is the replacement
+	// +optional
+	To string `json:"to,omitempty"`
+}
+
+// GetComponentType returns the component type of the source instance
+func (c *CloneFrom) GetComponentType() ComponentType {
+	switch c.Kind {
+	case "Neuron":
+		return ComponentTypeNeuron
+	case "EKuiper":
+		return ComponentTypeEKuiper
+	default:
+		return ComponentTypeNeuronEx
+	}
+}
+
+// ClonePhase is the progress of the copy of the configuration
+type ClonePhase string
+
+const (
+	// ClonePending waits for both instances to be ready
+	ClonePending ClonePhase = "Pending"
+	// CloneCompleted is set once the configuration is copied
+	CloneCompleted ClonePhase = "Completed"
+	// CloneFailed is set when the copy fails, it is retried
+	CloneFailed ClonePhase = "Failed"
+)
+
+// CloneStatus is the progress of the copy of the configuration of the source instance
+type CloneStatus struct {
+	// Phase is the progress of the copy
+	Phase ClonePhase `json:"phase"`
+	// CompletionTime is the time the configuration was copied
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message tells why the copy failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+func validateCloneFrom(ins EdgeInterface, path *field.Path) field.ErrorList {
+	clone := ins.GetCloneFrom()
+	if clone == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsDNS1123Subdomain(clone.Name) {
+		allErrs = append(allErrs, field.Invalid(path.Child("name"), clone.Name, msg))
+	}
+	source := clone.GetComponentType()
+	if source == ins.GetComponentType() && clone.Name == ins.GetName() {
+		allErrs = append(allErrs, field.Invalid(path.Child("name"), clone.Name, "an instance cannot clone itself"))
+	}
+	hasNeuron := source != ComponentTypeEKuiper && ins.GetNeuron() != nil
+	hasEKuiper := source != ComponentTypeNeuron && ins.GetEKuiper() != nil
+	if !hasNeuron && !hasEKuiper {
+		allErrs = append(allErrs, field.Invalid(path.Child("kind"), clone.Kind,
+			"the source instance runs none of the components of the instance"))
+	}
+	for i, sub := range clone.Substitutions {
+		if sub.From == "" {
+			allErrs = append(allErrs, field.Required(path.Child("substitutions").Index(i).Child("from"), ""))
+		}
+	}
+	return allErrs
+}
+
+// validateCloneFromUpdate forbids cloning an instance once it is created
+func validateCloneFromUpdate(new, old EdgeInterface, path *field.Path) field.ErrorList {
+	if !reflect.DeepEqual(new.GetCloneFrom(), old.GetCloneFrom()) {
+		return field.ErrorList{field.Forbidden(path, "can only be set when the instance is created")}
+	}
+	return nil
+}
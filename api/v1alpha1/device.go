@@ -0,0 +1,71 @@
+package v1alpha1
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+const (
+	// DeviceVolumePrefix prefixes the names of the volumes of the devices, followed by their index
+	DeviceVolumePrefix = "device-"
+	// DeviceLabelPrefix prefixes the labels that the device agent sets on the nodes for their serial
+	// ports and USB devices
+	DeviceLabelPrefix = "device.edge.emqx.io/"
+	// USBDeviceLinkDir is the directory of the node where the device agent links the serial ports of the
+	// USB devices by vendor and product
+	USBDeviceLinkDir = "/dev/edge"
+)
+
+// Device is a serial or USB device of the node passed through to the neuron container
+type Device struct {
+	// HostPath is the device on the node, such as /dev/ttyUSB0. It defaults to the link of the device
+	// agent when a USB device is requested by vendor and product.
+	//+kubebuilder:validation:Pattern=`^/dev/.+`
+	// +optional
+	HostPath string `json:"hostPath,omitempty"`
+	// VendorID is the hexadecimal USB vendor ID of the device, such as 0403. Requesting a device by
+	// vendor and product relies on the device agent to label the nodes and link the device.
+	//+kubebuilder:validation:Pattern=`^[0-9a-f]{4}$`
+	// +optional
+	VendorID string `json:"vendorID,omitempty"`
+	// ProductID is the hexadecimal USB product ID of the device, such as 6001
+	//+kubebuilder:validation:Pattern=`^[0-9a-f]{4}$`
+	// +optional
+	ProductID string `json:"productID,omitempty"`
+	// ContainerPath is where the device appears in the neuron container, defaults to the host path
+	// +optional
+	ContainerPath string `json:"containerPath,omitempty"`
+	// NodeLabel is the label of the nodes that have the device, as "key" or "key=value". The pod is
+	// only scheduled on these nodes.
+	// +optional
+	NodeLabel string `json:"nodeLabel,omitempty"`
+	// Resource is the extended resource of a device plugin that exposes the device, such as
+	// smarter-devices/ttyUSB0. The plugin then mounts the device and grants access to it. It is required
+	// unless the neuron container is privileged, the device is then mounted from a hostPath.
+	// +optional
+	Resource corev1.ResourceName `json:"resource,omitempty"`
+}
+
+// GetContainerPath returns the path of the device in the container
+func (d *Device) GetContainerPath() string {
+	if d.ContainerPath == "" {
+		return d.HostPath
+	}
+	return d.ContainerPath
+}
+
+// TTYDeviceLabel returns the node label of a serial port
+func TTYDeviceLabel(tty string) string {
+	return DeviceLabelPrefix + tty
+}
+
+// USBDeviceLabel returns the node label of a USB device
+func USBDeviceLabel(vendorID, productID string) string {
+	return fmt.Sprintf("%susb-%s-%s", DeviceLabelPrefix, vendorID, productID)
+}
+
+// USBDeviceLink returns the link on the node to the serial port of a USB device
+func USBDeviceLink(vendorID, productID string) string {
+	return fmt.Sprintf("%s/usb-%s-%s", USBDeviceLinkDir, vendorID, productID)
+}
@@ -5,3 +5,52 @@ const (
 	ComponentKey = "app.kubernetes.io/component"
 	ManagedByKey = "app.kubernetes.io/managed-by"
 )
+
+const (
+	// PausedAnnotation pauses the reconciliation of an instance when set to "true"
+	PausedAnnotation = "edge.emqx.io/paused"
+	// ConfigHashAnnotation is set on the pod template to the hash of the Secrets and ConfigMaps mounted in the pod
+	ConfigHashAnnotation = "edge.emqx.io/config-hash"
+	// ResolveDigestsAnnotation resolves the image digests again when its value changes, with spec.pinImageDigests
+	ResolveDigestsAnnotation = "edge.emqx.io/resolve-digests"
+	// OriginalImagesAnnotation is set by the defaulting webhook to the images of the spec that the image
+	// rewrite policy rewrote, such as "neuron=emqx/neuron:2.3.0,ekuiper=lfedge/ekuiper:1.8.0-slim"
+	OriginalImagesAnnotation = "edge.emqx.io/original-images"
+	// AdoptDeploymentAnnotation is the name of an existing Deployment that the instance adopts instead of
+	// creating its own
+	AdoptDeploymentAnnotation = "edge.emqx.io/adopt-deployment"
+	// AdoptServiceAnnotation is the name of an existing Service that the instance adopts instead of creating
+	// its own
+	AdoptServiceAnnotation = "edge.emqx.io/adopt-service"
+	// AdoptPVCsAnnotation maps the persistent volumes of the instance to existing PersistentVolumeClaims, such
+	// as "neuron-data=data-neuron-0"
+	AdoptPVCsAnnotation = "edge.emqx.io/adopt-pvcs"
+	// AdoptedSelectorAnnotation is set by the operator to the JSON of the immutable selector of the adopted
+	// Deployment, which is kept instead of the labels of the instance
+	AdoptedSelectorAnnotation = "edge.emqx.io/adopted-selector"
+	// SnapshotAnnotation takes a snapshot set of the persistent volumes named after its value, each time
+	// its value changes, with spec.volumeSnapshots
+	SnapshotAnnotation = "edge.emqx.io/snapshot"
+	// RollbackAnnotation restores the spec of a revision of the instance, its value is the number of the
+	// revision or "last-good". The operator removes it once the spec is restored.
+	RollbackAnnotation = "edge.emqx.io/rollback-to"
+	// ApplyNowAnnotation applies the changes held until the next maintenance window right away when set to
+	// "true". The operator removes it once they are applied.
+	ApplyNowAnnotation = "edge.emqx.io/apply-now"
+	// DiscardDataAnnotation confirms that the data of the emptyDir volumes is lost when "true", so that a
+	// volume claim template added to an instance without one is applied. The operator removes it once the
+	// pods mount the new claims.
+	DiscardDataAnnotation = "edge.emqx.io/discard-emptydir-data"
+)
+
+// IsInstanceAnnotation returns true for the annotations that control the instance itself, they are not
+// propagated to the components
+func IsInstanceAnnotation(key string) bool {
+	switch key {
+	case PausedAnnotation, ResolveDigestsAnnotation, AdoptDeploymentAnnotation, AdoptServiceAnnotation,
+		AdoptPVCsAnnotation, AdoptedSelectorAnnotation, SnapshotAnnotation, RollbackAnnotation, ApplyNowAnnotation,
+		DiscardDataAnnotation, OriginalImagesAnnotation:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,237 @@
+package v1alpha1
+
+import (
+	"strconv"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// EKuiperStoreType is the kind of database ekuiper keeps its metadata in
+// +kubebuilder:validation:Enum=sqlite;redis
+type EKuiperStoreType string
+
+const (
+	EKuiperStoreSqlite EKuiperStoreType = "sqlite"
+	EKuiperStoreRedis  EKuiperStoreType = "redis"
+)
+
+// EKuiperConfig is the typed configuration of ekuiper, every field that is set overrides the matching
+// KUIPER__ environment variable of the ekuiper container
+type EKuiperConfig struct {
+	// +optional
+	Basic *EKuiperBasicConfig `json:"basic,omitempty"`
+	// +optional
+	Rule *EKuiperRuleConfig `json:"rule,omitempty"`
+	// +optional
+	Store *EKuiperStoreConfig `json:"store,omitempty"`
+	// +optional
+	Portable *EKuiperPortableConfig `json:"portable,omitempty"`
+	// +optional
+	Sink *EKuiperSinkConfig `json:"sink,omitempty"`
+}
+
+// EKuiperBasicConfig is the basic section of kuiper.yaml
+type EKuiperBasicConfig struct {
+	// +optional
+	Debug *bool `json:"debug,omitempty"`
+	// +optional
+	ConsoleLog *bool `json:"consoleLog,omitempty"`
+	// +optional
+	FileLog *bool `json:"fileLog,omitempty"`
+	// +optional
+	Timezone string `json:"timezone,omitempty"`
+	// +optional
+	IgnoreCase *bool `json:"ignoreCase,omitempty"`
+	// RestPort is the port of the REST API, it sets the container port, the probes and the service port of ekuiper.
+	// When it is not set, KUIPER__BASIC__RESTPORT or else ports.ekuiper of the operator config is used.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	// +optional
+	RestPort *int32 `json:"restPort,omitempty"`
+	// +optional
+	RestIP string `json:"restIp,omitempty"`
+	// +optional
+	Authentication *bool `json:"authentication,omitempty"`
+	// +optional
+	Prometheus *bool `json:"prometheus,omitempty"`
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	// +optional
+	PrometheusPort *int32 `json:"prometheusPort,omitempty"`
+}
+
+// EKuiperRuleConfig is the rule section of kuiper.yaml, the defaults of all rules
+type EKuiperRuleConfig struct {
+	//+kubebuilder:validation:Minimum=0
+	//+kubebuilder:validation:Maximum=2
+	// +optional
+	QOS *int32 `json:"qos,omitempty"`
+	// CheckpointInterval is the interval in milliseconds between the checkpoints of a rule
+	//+kubebuilder:validation:Minimum=1
+	// +optional
+	CheckpointInterval *int32 `json:"checkpointInterval,omitempty"`
+	// +optional
+	SendError *bool `json:"sendError,omitempty"`
+	// +optional
+	RestartStrategy *EKuiperRestartStrategy `json:"restartStrategy,omitempty"`
+}
+
+// EKuiperRestartStrategy is how ekuiper restarts a failed rule
+type EKuiperRestartStrategy struct {
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	Attempts *int32 `json:"attempts,omitempty"`
+	// Delay is the delay in milliseconds before the first restart
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	Delay *int32 `json:"delay,omitempty"`
+	// MaxDelay is the maximum delay in milliseconds between two restarts
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDelay *int32 `json:"maxDelay,omitempty"`
+}
+
+// EKuiperStoreConfig is the store section of kuiper.yaml
+type EKuiperStoreConfig struct {
+	// +optional
+	Type EKuiperStoreType `json:"type,omitempty"`
+	// +optional
+	Redis *EKuiperRedisConfig `json:"redis,omitempty"`
+	// +optional
+	Sqlite *EKuiperSqliteConfig `json:"sqlite,omitempty"`
+}
+
+// EKuiperRedisConfig is the redis store of ekuiper
+type EKuiperRedisConfig struct {
+	Host string `json:"host"`
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	// +optional
+	Port *int32 `json:"port,omitempty"`
+	// PasswordSecretRef selects the key of a secret holding the redis password
+	// +optional
+	PasswordSecretRef *corev1.SecretKeySelector `json:"passwordSecretRef,omitempty"`
+	// Timeout is the connection timeout in milliseconds
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	Timeout *int32 `json:"timeout,omitempty"`
+}
+
+// EKuiperSqliteConfig is the sqlite store of ekuiper
+type EKuiperSqliteConfig struct {
+	// Name is the file name of the database in the ekuiper data directory
+	// +optional
+	Name string `json:"name,omitempty"`
+}
+
+// EKuiperPortableConfig is the portable section of kuiper.yaml, for the portable plugins
+type EKuiperPortableConfig struct {
+	// +optional
+	PythonBin string `json:"pythonBin,omitempty"`
+	// InitTimeout is the timeout in milliseconds of a portable plugin start
+	//+kubebuilder:validation:Minimum=1
+	// +optional
+	InitTimeout *int32 `json:"initTimeout,omitempty"`
+}
+
+// EKuiperSinkConfig is the sink section of kuiper.yaml, the cache defaults of all sinks
+type EKuiperSinkConfig struct {
+	// +optional
+	EnableCache *bool `json:"enableCache,omitempty"`
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	MemoryCacheThreshold *int32 `json:"memoryCacheThreshold,omitempty"`
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	MaxDiskCache *int32 `json:"maxDiskCache,omitempty"`
+	//+kubebuilder:validation:Minimum=1
+	// +optional
+	BufferPageSize *int32 `json:"bufferPageSize,omitempty"`
+	// ResendInterval is the interval in milliseconds between the resends of the cached messages
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	ResendInterval *int32 `json:"resendInterval,omitempty"`
+	// +optional
+	CleanCacheAtStop *bool `json:"cleanCacheAtStop,omitempty"`
+}
+
+// ekuiperEnv collects the KUIPER__ environment variables of the set fields of a config
+type ekuiperEnv []corev1.EnvVar
+
+func (e *ekuiperEnv) str(name, value string) {
+	if value != "" {
+		*e = append(*e, corev1.EnvVar{Name: "KUIPER__" + name, Value: value})
+	}
+}
+
+func (e *ekuiperEnv) bool(name string, value *bool) {
+	if value != nil {
+		e.str(name, strconv.FormatBool(*value))
+	}
+}
+
+func (e *ekuiperEnv) int(name string, value *int32) {
+	if value != nil {
+		e.str(name, strconv.Itoa(int(*value)))
+	}
+}
+
+// Env returns the environment variables of the ekuiper container that apply the config
+func (c *EKuiperConfig) Env() []corev1.EnvVar {
+	if c == nil {
+		return nil
+	}
+	env := &ekuiperEnv{}
+	if b := c.Basic; b != nil {
+		env.bool("BASIC__DEBUG", b.Debug)
+		env.bool("BASIC__CONSOLELOG", b.ConsoleLog)
+		env.bool("BASIC__FILELOG", b.FileLog)
+		env.str("BASIC__TIMEZONE", b.Timezone)
+		env.bool("BASIC__IGNORECASE", b.IgnoreCase)
+		env.int("BASIC__RESTPORT", b.RestPort)
+		env.str("BASIC__RESTIP", b.RestIP)
+		env.bool("BASIC__AUTHENTICATION", b.Authentication)
+		env.bool("BASIC__PROMETHEUS", b.Prometheus)
+		env.int("BASIC__PROMETHEUSPORT", b.PrometheusPort)
+	}
+	if r := c.Rule; r != nil {
+		env.int("RULE__QOS", r.QOS)
+		env.int("RULE__CHECKPOINTINTERVAL", r.CheckpointInterval)
+		env.bool("RULE__SENDERROR", r.SendError)
+		if s := r.RestartStrategy; s != nil {
+			env.int("RULE__RESTARTSTRATEGY__ATTEMPTS", s.Attempts)
+			env.int("RULE__RESTARTSTRATEGY__DELAY", s.Delay)
+			env.int("RULE__RESTARTSTRATEGY__MAXDELAY", s.MaxDelay)
+		}
+	}
+	if s := c.Store; s != nil {
+		env.str("STORE__TYPE", string(s.Type))
+		if r := s.Redis; r != nil {
+			env.str("STORE__REDIS__HOST", r.Host)
+			env.int("STORE__REDIS__PORT", r.Port)
+			env.int("STORE__REDIS__TIMEOUT", r.Timeout)
+			if r.PasswordSecretRef != nil {
+				*env = append(*env, corev1.EnvVar{
+					Name:      "KUIPER__STORE__REDIS__PASSWORD",
+					ValueFrom: &corev1.EnvVarSource{SecretKeyRef: r.PasswordSecretRef},
+				})
+			}
+		}
+		if s.Sqlite != nil {
+			env.str("STORE__SQLITE__NAME", s.Sqlite.Name)
+		}
+	}
+	if p := c.Portable; p != nil {
+		env.str("PORTABLE__PYTHONBIN", p.PythonBin)
+		env.int("PORTABLE__INITTIMEOUT", p.InitTimeout)
+	}
+	if s := c.Sink; s != nil {
+		env.bool("SINK__ENABLECACHE", s.EnableCache)
+		env.int("SINK__MEMORYCACHETHRESHOLD", s.MemoryCacheThreshold)
+		env.int("SINK__MAXDISKCACHE", s.MaxDiskCache)
+		env.int("SINK__BUFFERPAGESIZE", s.BufferPageSize)
+		env.int("SINK__RESENDINTERVAL", s.ResendInterval)
+		env.bool("SINK__CLEANCACHEATSTOP", s.CleanCacheAtStop)
+	}
+	return *env
+}
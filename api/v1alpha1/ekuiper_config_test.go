@@ -0,0 +1,97 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
+)
+
+func TestEKuiperConfigEnv(t *testing.T) {
+	var config *EKuiperConfig
+	assert.Nil(t, config.Env())
+
+	secret := &corev1.SecretKeySelector{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "redis"},
+		Key:                  "password",
+	}
+	config = &EKuiperConfig{
+		Basic: &EKuiperBasicConfig{RestPort: pointer.Int32(9090), IgnoreCase: pointer.Bool(true)},
+		Rule: &EKuiperRuleConfig{
+			QOS:             pointer.Int32(1),
+			RestartStrategy: &EKuiperRestartStrategy{Attempts: pointer.Int32(3)},
+		},
+		Store: &EKuiperStoreConfig{
+			Type:  EKuiperStoreRedis,
+			Redis: &EKuiperRedisConfig{Host: "redis", PasswordSecretRef: secret},
+		},
+		Portable: &EKuiperPortableConfig{PythonBin: "python3"},
+		Sink:     &EKuiperSinkConfig{EnableCache: pointer.Bool(false)},
+	}
+	assert.Equal(t, []corev1.EnvVar{
+		{Name: "KUIPER__BASIC__IGNORECASE", Value: "true"},
+		{Name: "KUIPER__BASIC__RESTPORT", Value: "9090"},
+		{Name: "KUIPER__RULE__QOS", Value: "1"},
+		{Name: "KUIPER__RULE__RESTARTSTRATEGY__ATTEMPTS", Value: "3"},
+		{Name: "KUIPER__STORE__TYPE", Value: "redis"},
+		{Name: "KUIPER__STORE__REDIS__HOST", Value: "redis"},
+		{Name: "KUIPER__STORE__REDIS__PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: secret}},
+		{Name: "KUIPER__PORTABLE__PYTHONBIN", Value: "python3"},
+		{Name: "KUIPER__SINK__ENABLECACHE", Value: "false"},
+	}, config.Env())
+}
+
+func TestDefaultEKuiperConfig(t *testing.T) {
+	ins := &EKuiper{
+		ObjectMeta: metav1.ObjectMeta{Name: "ekuiper"},
+		Spec: EKuiperSpec{
+			EKuiper: corev1.Container{
+				Env: []corev1.EnvVar{
+					{Name: "KUIPER__BASIC__RESTPORT", Value: "9082"},
+					{Name: "KUIPER__BASIC__IGNORECASE", Value: "true"},
+				},
+			},
+			EKuiperConfig: &EKuiperConfig{
+				Basic: &EKuiperBasicConfig{RestPort: pointer.Int32(9090), IgnoreCase: pointer.Bool(false)},
+			},
+		},
+	}
+	ins.Default()
+
+	// the env vars of the config are set on the pod template, not in the spec
+	assert.Subset(t, ins.Spec.EKuiper.Env, []corev1.EnvVar{
+		{Name: "KUIPER__BASIC__RESTPORT", Value: "9082"},
+		{Name: "KUIPER__BASIC__IGNORECASE", Value: "true"},
+	})
+	assert.Equal(t, []corev1.ContainerPort{
+		{Name: "ekuiper", Protocol: corev1.ProtocolTCP, ContainerPort: 9090},
+	}, ins.Spec.EKuiper.Ports)
+	assert.Equal(t, intstr.FromInt(9090), ins.Spec.EKuiper.ReadinessProbe.HTTPGet.Port)
+}
+
+func TestDefaultEKuiperRestPortChange(t *testing.T) {
+	ins := &EKuiper{
+		ObjectMeta: metav1.ObjectMeta{Name: "ekuiper"},
+		Spec: EKuiperSpec{
+			ServiceTemplate: &corev1.Service{},
+		},
+	}
+	ins.Default()
+	assert.Equal(t, int32(9081), ins.Spec.EKuiper.Ports[0].ContainerPort)
+	assert.Equal(t, int32(9081), ins.Spec.ServiceTemplate.Spec.Ports[0].Port)
+
+	// the container port, the probes and the service port follow the REST port of the config
+	ins.Spec.EKuiperConfig = &EKuiperConfig{Basic: &EKuiperBasicConfig{RestPort: pointer.Int32(9090)}}
+	ins.Default()
+	assert.Equal(t, []corev1.ContainerPort{
+		{Name: "ekuiper", Protocol: corev1.ProtocolTCP, ContainerPort: 9090},
+	}, ins.Spec.EKuiper.Ports)
+	assert.Equal(t, intstr.FromInt(9090), ins.Spec.EKuiper.ReadinessProbe.HTTPGet.Port)
+	assert.Equal(t, intstr.FromInt(9090), ins.Spec.EKuiper.LivenessProbe.HTTPGet.Port)
+	assert.Len(t, ins.Spec.ServiceTemplate.Spec.Ports, 1)
+	assert.Equal(t, int32(9090), ins.Spec.ServiceTemplate.Spec.Ports[0].Port)
+	assert.Equal(t, intstr.FromInt(9090), ins.Spec.ServiceTemplate.Spec.Ports[0].TargetPort)
+}
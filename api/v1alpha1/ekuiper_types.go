@@ -35,6 +35,44 @@ type EKuiperSpec struct {
 	EKuiper             corev1.Container                      `json:"ekuiper,omitempty"`
 	VolumeClaimTemplate *corev1.PersistentVolumeClaimTemplate `json:"volumeClaimTemplate,omitempty"`
 	ServiceTemplate     *corev1.Service                       `json:"serviceTemplate,omitempty"`
+
+	// Paused stops the operator from changing the child resources of the instance,
+	// only the status is still updated.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+	// EKuiperConfig is the typed configuration of ekuiper, it is passed to the ekuiper container as
+	// KUIPER__ environment variables that take precedence over the ones set in spec.ekuiper.env.
+	// +optional
+	EKuiperConfig *EKuiperConfig `json:"ekuiperConfig,omitempty"`
+
+	// PinImageDigests resolves the tag of each container image to a digest through the registry API
+	// and pins the pod to it, so that a retagged image is not pulled. The digests are resolved again when
+	// an image changes or the edge.emqx.io/resolve-digests annotation changes. The controller resolves them
+	// before it applies the Deployment, not the admission webhooks, so that an unreachable registry does not
+	// block the admission of the instance. The spec keeps the tags, the digests are in status.imageDigests.
+	// +optional
+	PinImageDigests bool `json:"pinImageDigests,omitempty"`
+
+	// VolumeSnapshots takes snapshots of the persistent volumes with a CSI snapshotter, or creates them from
+	// a snapshot set
+	// +optional
+	VolumeSnapshots *VolumeSnapshots `json:"volumeSnapshots,omitempty"`
+
+	// CloneFrom copies the configuration of an existing instance once the instance is ready
+	// +optional
+	CloneFrom *CloneFrom `json:"cloneFrom,omitempty"`
+
+	// RevisionHistoryLimit is the number of ControllerRevisions of the spec that are kept to roll back to
+	//+kubebuilder:default:=10
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// MaintenanceWindows are the recurring windows in which the changes that restart the pods are applied,
+	// such as a new image, env or volumes. Outside of them the changes are held. They are applied at any
+	// time when no window is set.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
 }
 
 func (ek *EKuiper) GetComponentType() ComponentType {
@@ -45,6 +83,10 @@ func (ek *EKuiper) GetEdgePodSpec() EdgePodSpec {
 	return ek.Spec.EdgePodSpec
 }
 
+func (ek *EKuiper) SetEdgePodSpec(spec EdgePodSpec) {
+	ek.Spec.EdgePodSpec = spec
+}
+
 func (ek *EKuiper) GetNeuron() *corev1.Container {
 	return nil
 }
@@ -75,6 +117,54 @@ func (ek *EKuiper) SetReplicas(replicas int32) {
 	ek.Spec.Replicas = &replicas
 }
 
+func (ek *EKuiper) GetPaused() bool {
+	return ek.Spec.Paused
+}
+
+func (ek *EKuiper) GetPinImageDigests() bool {
+	return ek.Spec.PinImageDigests
+}
+
+func (ek *EKuiper) GetVolumeSnapshots() *VolumeSnapshots {
+	return ek.Spec.VolumeSnapshots
+}
+
+func (ek *EKuiper) GetCloneFrom() *CloneFrom {
+	return ek.Spec.CloneFrom
+}
+
+func (ek *EKuiper) GetRevisionHistoryLimit() int32 {
+	return getRevisionHistoryLimit(ek.Spec.RevisionHistoryLimit)
+}
+
+func (ek *EKuiper) GetMaintenanceWindows() []MaintenanceWindow {
+	return ek.Spec.MaintenanceWindows
+}
+
+func (ek *EKuiper) GetEKuiperConfig() *EKuiperConfig {
+	return ek.Spec.EKuiperConfig
+}
+
+func (ek *EKuiper) GetNeuronConfig() *NeuronConfig {
+	return nil
+}
+
+func (ek *EKuiper) GetHighAvailability() *HighAvailability {
+	return nil
+}
+
+func (ek *EKuiper) GetDevices() []Device {
+	return nil
+}
+
+func (ek *EKuiper) GetNeuronPort() int32 {
+	return 0
+}
+
+func (ek *EKuiper) GetEKuiperRestPort() int32 {
+	return getEKuiperRestPort(ek)
+}
+
 // EKuiperStatus defines the observed state of EKuiper
 type EKuiperStatus struct {
 	EdgeStatus `json:",inline"`
@@ -26,10 +26,9 @@ import (
 // log is for logging in this package.
 var ekuiperlog = logf.Log.WithName("EKuiper Webhook")
 
-func (r *EKuiper) SetupWebhookWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewWebhookManagedBy(mgr).
-		For(r).
-		Complete()
+// SetupWebhookWithManager registers the webhooks, serving only the objects in watchNamespaces if any is given
+func (r *EKuiper) SetupWebhookWithManager(mgr ctrl.Manager, watchNamespaces ...string) error {
+	return setupWebhookWithManager(mgr, r, watchNamespaces)
 }
 
 // TODO(user): EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -44,6 +43,7 @@ func (r *EKuiper) Default() {
 
 	setDefaultLabels(r)
 	setDefaultEKuiperContainer(r)
+	setImageRewrite(r)
 	setDefaultService(r)
 	setDefaultVolume(r)
 }
@@ -51,42 +51,31 @@ func (r *EKuiper) Default() {
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
 //+kubebuilder:webhook:path=/validate-edge-emqx-io-v1alpha1-ekuiper,mutating=false,failurePolicy=fail,sideEffects=None,groups=edge.emqx.io,resources=ekuipers,verbs=create;update,versions=v1alpha1,name=validate.ekuiper.edge.emqx.io,admissionReviewVersions=v1
 
-var _ webhook.Validator = &EKuiper{}
-
-// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+// ValidateCreate is called by the validating webhook through edgeValidator
 func (r *EKuiper) ValidateCreate() error {
 	ekuiperlog.Info("validate create", "name", r.Name)
 
-	for _, err := range []error{
-		validateVolumeTemplateCreate(r),
-	} {
-		if err != nil {
-			neuronexlog.Error(err, "validate neuron container failed")
-			return err
-		}
+	if err := toInvalidError(r, "EKuiper", validateCreate(r)); err != nil {
+		ekuiperlog.Error(err, "validate create failed", "name", r.Name)
+		return err
 	}
 
 	return nil
 }
 
-// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+// ValidateUpdate is called by the validating webhook through edgeValidator
 func (r *EKuiper) ValidateUpdate(old runtime.Object) error {
 	ekuiperlog.Info("validate update", "name", r.Name)
 
-	for _, err := range []error{
-		validateVolumeTemplateUpdate(r, old.(*EKuiper)),
-	} {
-		if err != nil {
-			neuronexlog.Error(err, "validate neuron container failed")
-			return err
-		}
+	if err := toInvalidError(r, "EKuiper", validateUpdate(r, old.(*EKuiper))); err != nil {
+		ekuiperlog.Error(err, "validate update failed", "name", r.Name)
+		return err
 	}
 
-	ekuiperlog.Info("validate update success", "name", r.Name)
 	return nil
 }
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+// ValidateDelete is called by the validating webhook through edgeValidator
 func (r *EKuiper) ValidateDelete() error {
 	ekuiperlog.Info("validate delete", "name", r.Name)
 
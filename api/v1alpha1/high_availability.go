@@ -0,0 +1,37 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// ActiveReadinessGate is the readiness gate of the neuron pods in high availability mode, the operator
+// sets it to true on the active pod only so that the service routes to it
+const ActiveReadinessGate corev1.PodConditionType = "edge.emqx.io/active"
+
+// ActiveAnnotation is set to "true" by the operator on the neuron pod elected active. The pods wait for it
+// in an init container, so that a standby never starts the southbound nodes persisted as running.
+const ActiveAnnotation = "edge.emqx.io/active"
+
+// HighAvailability runs neuron as an active and a standby pod on different nodes. The operator elects the
+// active pod with a Lease and lets it start neuron, the standby waits before starting neuron until it is
+// elected. The previous active pod must be stopped before the standby is elected: its southbound nodes are
+// stopped, or its node is deleted or tainted with node.kubernetes.io/out-of-service after a shutdown.
+type HighAvailability struct {
+	// LeaseDurationSeconds is how long the active pod can stay unready before the standby takes over
+	//+kubebuilder:default:=15
+	//+kubebuilder:validation:Minimum=5
+	// +optional
+	LeaseDurationSeconds int32 `json:"leaseDurationSeconds,omitempty"`
+	// TokenSecretRef selects the key of a secret holding a token of the neuron REST API, used by the
+	// operator to start and stop the southbound nodes. It is not needed when neuron runs without auth.
+	// +optional
+	TokenSecretRef *corev1.SecretKeySelector `json:"tokenSecretRef,omitempty"`
+}
+
+// GetLeaseDurationSeconds returns the lease duration, defaulting to 15 seconds
+func (ha *HighAvailability) GetLeaseDurationSeconds() int32 {
+	if ha.LeaseDurationSeconds == 0 {
+		return 15
+	}
+	return ha.LeaseDurationSeconds
+}
@@ -0,0 +1,77 @@
+package v1alpha1
+
+import (
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// MaintenanceWindow is a recurring window in which the pods of the instance may be restarted
+type MaintenanceWindow struct {
+	// Schedule is the cron expression of the opening of the window, such as "0 2 * * 6" for 2 AM on Saturdays
+	//+kubebuilder:validation:MinLength=1
+	Schedule string `json:"schedule"`
+	// Duration is how long the window stays open, such as "2h"
+	Duration metav1.Duration `json:"duration"`
+	// TimeZone is the IANA time zone of the schedule, such as "Europe/Berlin"
+	//+kubebuilder:default:=UTC
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+var cronParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// parse returns the schedule of the window in its time zone
+func (w *MaintenanceWindow) parse() (cron.Schedule, *time.Location, error) {
+	schedule, err := cronParser.Parse(w.Schedule)
+	if err != nil {
+		return nil, nil, err
+	}
+	location, err := time.LoadLocation(w.TimeZone)
+	if err != nil {
+		return nil, nil, err
+	}
+	return schedule, location, nil
+}
+
+// GetMaintenanceWindowState returns whether one of the windows is open at now, and otherwise when the next
+// one opens. Invalid windows, which the webhook rejects, never open.
+func GetMaintenanceWindowState(windows []MaintenanceWindow, now time.Time) (open bool, next time.Time) {
+	for i := range windows {
+		schedule, location, err := windows[i].parse()
+		if err != nil || windows[i].Duration.Duration <= 0 {
+			continue
+		}
+		// the first opening after now minus the duration is the one that may still be open
+		opening := schedule.Next(now.In(location).Add(-windows[i].Duration.Duration))
+		if opening.IsZero() {
+			continue
+		}
+		if !opening.After(now) {
+			return true, time.Time{}
+		}
+		if next.IsZero() || opening.Before(next) {
+			next = opening
+		}
+	}
+	return false, next
+}
+
+func validateMaintenanceWindows(ins EdgeInterface, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, window := range ins.GetMaintenanceWindows() {
+		if _, err := cronParser.Parse(window.Schedule); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Index(i).Child("schedule"), window.Schedule, err.Error()))
+		}
+		if _, err := time.LoadLocation(window.TimeZone); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Index(i).Child("timeZone"), window.TimeZone, err.Error()))
+		}
+		if window.Duration.Duration <= 0 {
+			allErrs = append(allErrs, field.Invalid(path.Index(i).Child("duration"), window.Duration.Duration.String(),
+				"must be positive"))
+		}
+	}
+	return allErrs
+}
@@ -0,0 +1,43 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetMaintenanceWindowState(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	assert.Nil(t, err)
+	windows := []MaintenanceWindow{
+		// Saturdays from 2 to 4 AM in Berlin
+		{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 2 * time.Hour}, TimeZone: "Europe/Berlin"},
+		// the first day of the month from midnight UTC for 30 minutes
+		{Schedule: "0 0 1 * *", Duration: metav1.Duration{Duration: 30 * time.Minute}},
+	}
+
+	// Friday, March 3rd 2023
+	open, next := GetMaintenanceWindowState(windows, time.Date(2023, 3, 3, 12, 0, 0, 0, berlin))
+	assert.False(t, open)
+	assert.True(t, next.Equal(time.Date(2023, 3, 4, 2, 0, 0, 0, berlin)), next)
+
+	open, _ = GetMaintenanceWindowState(windows, time.Date(2023, 3, 4, 3, 59, 0, 0, berlin))
+	assert.True(t, open)
+	open, next = GetMaintenanceWindowState(windows, time.Date(2023, 3, 4, 4, 0, 0, 0, berlin))
+	assert.False(t, open)
+	assert.True(t, next.Equal(time.Date(2023, 3, 11, 2, 0, 0, 0, berlin)), next)
+
+	// the earliest window opens next
+	open, next = GetMaintenanceWindowState(windows, time.Date(2023, 5, 30, 0, 0, 0, 0, time.UTC))
+	assert.False(t, open)
+	assert.True(t, next.Equal(time.Date(2023, 6, 1, 0, 0, 0, 0, time.UTC)), next)
+	open, _ = GetMaintenanceWindowState(windows, time.Date(2023, 6, 1, 0, 10, 0, 0, time.UTC))
+	assert.True(t, open)
+
+	// invalid windows never open
+	open, next = GetMaintenanceWindowState([]MaintenanceWindow{{Schedule: "never"}}, time.Now())
+	assert.False(t, open)
+	assert.True(t, next.IsZero())
+}
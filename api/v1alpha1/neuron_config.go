@@ -0,0 +1,51 @@
+package v1alpha1
+
+import (
+	corev1 "k8s.io/api/core/v1"
+)
+
+// NeuronPluginsFile is the file of the neuron config directory listing the plugins loaded at start
+const NeuronPluginsFile = "default_plugins.json"
+
+// NeuronLogLevel is the minimum level of the neuron logs
+// +kubebuilder:validation:Enum=debug;info;notice;warn;error;fatal
+type NeuronLogLevel string
+
+// NeuronConfig is the typed runtime configuration of neuron. It has no typed persistence setting: the
+// persistence settings of neuron are configured through Files, like any other file of the config directory,
+// and the persisted data is kept in the neuron-data volume, which is only persistent with a volume claim template.
+type NeuronConfig struct {
+	// LogLevel sets the LOG_LEVEL env var of the neuron container
+	// +optional
+	LogLevel NeuronLogLevel `json:"logLevel,omitempty"`
+	// DisableAuth sets the DISABLE_AUTH env var of the neuron container, the REST API then accepts
+	// requests without a token
+	// +optional
+	DisableAuth *bool `json:"disableAuth,omitempty"`
+	// Plugins are the plugin libraries loaded at start, written to default_plugins.json
+	// +optional
+	Plugins []string `json:"plugins,omitempty"`
+	// Files are extra files of the neuron config directory, keyed by file name, such as zlog.conf or the
+	// persistence settings
+	// +optional
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// Env returns the environment variables of the neuron container that apply the config
+func (c *NeuronConfig) Env() []corev1.EnvVar {
+	if c == nil {
+		return nil
+	}
+	var env []corev1.EnvVar
+	if c.LogLevel != "" {
+		env = append(env, corev1.EnvVar{Name: "LOG_LEVEL", Value: string(c.LogLevel)})
+	}
+	if c.DisableAuth != nil {
+		value := "0"
+		if *c.DisableAuth {
+			value = "1"
+		}
+		env = append(env, corev1.EnvVar{Name: "DISABLE_AUTH", Value: value})
+	}
+	return env
+}
@@ -35,6 +35,62 @@ type NeuronSpec struct {
 	Neuron              corev1.Container                      `json:"neuron,omitempty"`
 	ServiceTemplate     *corev1.Service                       `json:"serviceTemplate,omitempty"`
 	VolumeClaimTemplate *corev1.PersistentVolumeClaimTemplate `json:"volumeClaimTemplate,omitempty"`
+
+	// Paused stops the operator from changing the child resources of the instance,
+	// only the status is still updated.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// NeuronPort is the port of the neuron web server and REST API, it sets the container port, the
+	// probes and the service port of neuron. When it is not set, the port of the neuron container named
+	// "neuron" is used, or else ports.neuron of the operator config, 7000 by default.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	// +optional
+	NeuronPort int32 `json:"neuronPort,omitempty"`
+
+	// NeuronConfig is the typed runtime configuration of neuron, its files are mounted in the neuron
+	// config directory and a change restarts the pod.
+	// +optional
+	NeuronConfig *NeuronConfig `json:"neuronConfig,omitempty"`
+
+	// Devices are the serial and USB devices of the node passed through to the neuron container
+	// +optional
+	Devices []Device `json:"devices,omitempty"`
+
+	// HighAvailability runs an active and a standby neuron pod instead of a single one, the
+	// Deployment then has 2 replicas unless spec.replicas is 0.
+	// +optional
+	HighAvailability *HighAvailability `json:"highAvailability,omitempty"`
+
+	// PinImageDigests resolves the tag of each container image to a digest through the registry API
+	// and pins the pod to it, so that a retagged image is not pulled. The digests are resolved again when
+	// an image changes or the edge.emqx.io/resolve-digests annotation changes. The controller resolves them
+	// before it applies the Deployment, not the admission webhooks, so that an unreachable registry does not
+	// block the admission of the instance. The spec keeps the tags, the digests are in status.imageDigests.
+	// +optional
+	PinImageDigests bool `json:"pinImageDigests,omitempty"`
+
+	// VolumeSnapshots takes snapshots of the persistent volumes with a CSI snapshotter, or creates them from
+	// a snapshot set
+	// +optional
+	VolumeSnapshots *VolumeSnapshots `json:"volumeSnapshots,omitempty"`
+
+	// CloneFrom copies the configuration of an existing instance once the instance is ready
+	// +optional
+	CloneFrom *CloneFrom `json:"cloneFrom,omitempty"`
+
+	// RevisionHistoryLimit is the number of ControllerRevisions of the spec that are kept to roll back to
+	//+kubebuilder:default:=10
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// MaintenanceWindows are the recurring windows in which the changes that restart the pods are applied,
+	// such as a new image, env or volumes. Outside of them the changes are held. They are applied at any
+	// time when no window is set.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
 }
 
 func (n *Neuron) GetComponentType() ComponentType {
@@ -45,6 +101,10 @@ func (n *Neuron) GetEdgePodSpec() EdgePodSpec {
 	return n.Spec.EdgePodSpec
 }
 
+func (n *Neuron) SetEdgePodSpec(spec EdgePodSpec) {
+	n.Spec.EdgePodSpec = spec
+}
+
 func (n *Neuron) GetNeuron() *corev1.Container {
 	return &n.Spec.Neuron
 }
@@ -75,6 +135,54 @@ func (n *Neuron) SetReplicas(replicas int32) {
 	n.Spec.Replicas = &replicas
 }
 
+func (n *Neuron) GetPaused() bool {
+	return n.Spec.Paused
+}
+
+func (n *Neuron) GetPinImageDigests() bool {
+	return n.Spec.PinImageDigests
+}
+
+func (n *Neuron) GetVolumeSnapshots() *VolumeSnapshots {
+	return n.Spec.VolumeSnapshots
+}
+
+func (n *Neuron) GetCloneFrom() *CloneFrom {
+	return n.Spec.CloneFrom
+}
+
+func (n *Neuron) GetRevisionHistoryLimit() int32 {
+	return getRevisionHistoryLimit(n.Spec.RevisionHistoryLimit)
+}
+
+func (n *Neuron) GetMaintenanceWindows() []MaintenanceWindow {
+	return n.Spec.MaintenanceWindows
+}
+
+func (n *Neuron) GetEKuiperConfig() *EKuiperConfig {
+	return nil
+}
+
+func (n *Neuron) GetNeuronConfig() *NeuronConfig {
+	return n.Spec.NeuronConfig
+}
+
+func (n *Neuron) GetHighAvailability() *HighAvailability {
+	return n.Spec.HighAvailability
+}
+
+func (n *Neuron) GetDevices() []Device {
+	return n.Spec.Devices
+}
+
+func (n *Neuron) GetNeuronPort() int32 {
+	return getNeuronPort(n.Spec.NeuronPort, &n.Spec.Neuron)
+}
+
+func (n *Neuron) GetEKuiperRestPort() int32 {
+	return 0
+}
+
 // NeuronStatus defines the observed state of Neuron
 type NeuronStatus struct {
 	EdgeStatus `json:",inline"`
@@ -26,10 +26,9 @@ import (
 // log is for logging in this package.
 var neuronlog = logf.Log.WithName("neuron-resource")
 
-func (r *Neuron) SetupWebhookWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewWebhookManagedBy(mgr).
-		For(r).
-		Complete()
+// SetupWebhookWithManager registers the webhooks, serving only the objects in watchNamespaces if any is given
+func (r *Neuron) SetupWebhookWithManager(mgr ctrl.Manager, watchNamespaces ...string) error {
+	return setupWebhookWithManager(mgr, r, watchNamespaces)
 }
 
 // TODO(user): EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
@@ -44,6 +43,7 @@ func (r *Neuron) Default() {
 
 	setDefaultLabels(r)
 	setDefaultNeuronContainer(r)
+	setImageRewrite(r)
 	setDefaultService(r)
 	setDefaultVolume(r)
 }
@@ -51,44 +51,31 @@ func (r *Neuron) Default() {
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
 //+kubebuilder:webhook:path=/validate-edge-emqx-io-v1alpha1-neuron,mutating=false,failurePolicy=fail,sideEffects=None,groups=edge.emqx.io,resources=neurons,verbs=create;update,versions=v1alpha1,name=validate.neuron.edge.emqx.io,admissionReviewVersions=v1
 
-var _ webhook.Validator = &Neuron{}
-
-// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+// ValidateCreate is called by the validating webhook through edgeValidator
 func (r *Neuron) ValidateCreate() error {
 	neuronlog.Info("validate create", "name", r.Name)
 
-	for _, err := range []error{
-		validateNeuronContainer(r),
-		validateVolumeTemplateCreate(r),
-	} {
-		if err != nil {
-			neuronexlog.Error(err, "validate neuron container failed")
-			return err
-		}
+	if err := toInvalidError(r, "Neuron", validateCreate(r)); err != nil {
+		neuronlog.Error(err, "validate create failed", "name", r.Name)
+		return err
 	}
 
 	return nil
 }
 
-// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+// ValidateUpdate is called by the validating webhook through edgeValidator
 func (r *Neuron) ValidateUpdate(old runtime.Object) error {
 	neuronlog.Info("validate update", "name", r.Name)
 
-	for _, err := range []error{
-		validateNeuronContainer(r),
-		validateVolumeTemplateUpdate(r, old.(*Neuron)),
-	} {
-		if err != nil {
-			neuronexlog.Error(err, "validate neuron container failed")
-			return err
-		}
+	if err := toInvalidError(r, "Neuron", validateUpdate(r, old.(*Neuron))); err != nil {
+		neuronlog.Error(err, "validate update failed", "name", r.Name)
+		return err
 	}
 
-	neuronexlog.Info("validate create success", "name", r.Name)
 	return nil
 }
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+// ValidateDelete is called by the validating webhook through edgeValidator
 func (r *Neuron) ValidateDelete() error {
 	neuronlog.Info("validate delete", "name", r.Name)
 
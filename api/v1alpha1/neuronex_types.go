@@ -35,6 +35,61 @@ type NeuronEXSpec struct {
 	EKuiper             corev1.Container                      `json:"ekuiper,omitempty"`
 	VolumeClaimTemplate *corev1.PersistentVolumeClaimTemplate `json:"volumeClaimTemplate,omitempty"`
 	ServiceTemplate     *corev1.Service                       `json:"serviceTemplate,omitempty"`
+
+	// Paused stops the operator from changing the child resources of the instance,
+	// only the status is still updated.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// NeuronPort is the port of the neuron web server and REST API, it sets the container port, the
+	// probes and the service port of neuron. When it is not set, the port of the neuron container named
+	// "neuron" is used, or else ports.neuron of the operator config, 7000 by default.
+	//+kubebuilder:validation:Minimum=1
+	//+kubebuilder:validation:Maximum=65535
+	// +optional
+	NeuronPort int32 `json:"neuronPort,omitempty"`
+
+	// NeuronConfig is the typed runtime configuration of neuron, its files are mounted in the neuron
+	// config directory and a change restarts the pod.
+	// +optional
+	NeuronConfig *NeuronConfig `json:"neuronConfig,omitempty"`
+
+	// Devices are the serial and USB devices of the node passed through to the neuron container
+	// +optional
+	Devices []Device `json:"devices,omitempty"`
+	// EKuiperConfig is the typed configuration of ekuiper, it is passed to the ekuiper container as
+	// KUIPER__ environment variables that take precedence over the ones set in spec.ekuiper.env.
+	// +optional
+	EKuiperConfig *EKuiperConfig `json:"ekuiperConfig,omitempty"`
+
+	// PinImageDigests resolves the tag of each container image to a digest through the registry API
+	// and pins the pod to it, so that a retagged image is not pulled. The digests are resolved again when
+	// an image changes or the edge.emqx.io/resolve-digests annotation changes. The controller resolves them
+	// before it applies the Deployment, not the admission webhooks, so that an unreachable registry does not
+	// block the admission of the instance. The spec keeps the tags, the digests are in status.imageDigests.
+	// +optional
+	PinImageDigests bool `json:"pinImageDigests,omitempty"`
+
+	// VolumeSnapshots takes snapshots of the persistent volumes with a CSI snapshotter, or creates them from
+	// a snapshot set
+	// +optional
+	VolumeSnapshots *VolumeSnapshots `json:"volumeSnapshots,omitempty"`
+
+	// CloneFrom copies the configuration of an existing instance once the instance is ready
+	// +optional
+	CloneFrom *CloneFrom `json:"cloneFrom,omitempty"`
+
+	// RevisionHistoryLimit is the number of ControllerRevisions of the spec that are kept to roll back to
+	//+kubebuilder:default:=10
+	//+kubebuilder:validation:Minimum=0
+	// +optional
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// MaintenanceWindows are the recurring windows in which the changes that restart the pods are applied,
+	// such as a new image, env or volumes. Outside of them the changes are held. They are applied at any
+	// time when no window is set.
+	// +optional
+	MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows,omitempty"`
 }
 
 func (n *NeuronEX) GetComponentType() ComponentType {
@@ -45,6 +100,10 @@ func (n *NeuronEX) GetEdgePodSpec() EdgePodSpec {
 	return n.Spec.EdgePodSpec
 }
 
+func (n *NeuronEX) SetEdgePodSpec(spec EdgePodSpec) {
+	n.Spec.EdgePodSpec = spec
+}
+
 func (n *NeuronEX) GetNeuron() *corev1.Container {
 	return &n.Spec.Neuron
 }
@@ -75,6 +134,54 @@ func (n *NeuronEX) SetReplicas(replicas int32) {
 	n.Spec.Replicas = &replicas
 }
 
+func (n *NeuronEX) GetPaused() bool {
+	return n.Spec.Paused
+}
+
+func (n *NeuronEX) GetPinImageDigests() bool {
+	return n.Spec.PinImageDigests
+}
+
+func (n *NeuronEX) GetVolumeSnapshots() *VolumeSnapshots {
+	return n.Spec.VolumeSnapshots
+}
+
+func (n *NeuronEX) GetCloneFrom() *CloneFrom {
+	return n.Spec.CloneFrom
+}
+
+func (n *NeuronEX) GetRevisionHistoryLimit() int32 {
+	return getRevisionHistoryLimit(n.Spec.RevisionHistoryLimit)
+}
+
+func (n *NeuronEX) GetMaintenanceWindows() []MaintenanceWindow {
+	return n.Spec.MaintenanceWindows
+}
+
+func (n *NeuronEX) GetEKuiperConfig() *EKuiperConfig {
+	return n.Spec.EKuiperConfig
+}
+
+func (n *NeuronEX) GetNeuronConfig() *NeuronConfig {
+	return n.Spec.NeuronConfig
+}
+
+func (n *NeuronEX) GetHighAvailability() *HighAvailability {
+	return nil
+}
+
+func (n *NeuronEX) GetDevices() []Device {
+	return n.Spec.Devices
+}
+
+func (n *NeuronEX) GetNeuronPort() int32 {
+	return getNeuronPort(n.Spec.NeuronPort, &n.Spec.Neuron)
+}
+
+func (n *NeuronEX) GetEKuiperRestPort() int32 {
+	return getEKuiperRestPort(n)
+}
+
 // NeuronEXStatus defines the observed state of NeuronEX
 type NeuronEXStatus struct {
 	EdgeStatus `json:",inline"`
@@ -26,10 +26,9 @@ import (
 // log is for logging in this package.
 var neuronexlog = logf.Log.WithName("NeuronEX Webhook")
 
-func (r *NeuronEX) SetupWebhookWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewWebhookManagedBy(mgr).
-		For(r).
-		Complete()
+// SetupWebhookWithManager registers the webhooks, serving only the objects in watchNamespaces if any is given
+func (r *NeuronEX) SetupWebhookWithManager(mgr ctrl.Manager, watchNamespaces ...string) error {
+	return setupWebhookWithManager(mgr, r, watchNamespaces)
 }
 
 //+kubebuilder:webhook:path=/mutate-edge-emqx-io-v1alpha1-neuronex,mutating=true,failurePolicy=fail,sideEffects=None,groups=edge.emqx.io,resources=neuronexs,verbs=create;update,versions=v1alpha1,name=mutate.neuronex.edge.emqx.io,admissionReviewVersions=v1
@@ -43,6 +42,7 @@ func (r *NeuronEX) Default() {
 	setDefaultLabels(r)
 	setDefaultNeuronContainer(r)
 	setDefaultEKuiperContainer(r)
+	setImageRewrite(r)
 
 	setDefaultService(r)
 	setDefaultVolume(r)
@@ -51,43 +51,31 @@ func (r *NeuronEX) Default() {
 // TODO(user): change verbs to "verbs=create;update;delete" if you want to enable deletion validation.
 //+kubebuilder:webhook:path=/validate-edge-emqx-io-v1alpha1-neuronex,mutating=false,failurePolicy=fail,sideEffects=None,groups=edge.emqx.io,resources=neuronexs,verbs=create;update,versions=v1alpha1,name=validate.neuronex.edge.emqx.io,admissionReviewVersions=v1
 
-var _ webhook.Validator = &NeuronEX{}
-
-// ValidateCreate implements webhook.Validator so a webhook will be registered for the type
+// ValidateCreate is called by the validating webhook through edgeValidator
 func (r *NeuronEX) ValidateCreate() error {
 	neuronexlog.Info("validate create", "name", r.Name)
 
-	for _, err := range []error{
-		validateNeuronContainer(r),
-		validateVolumeTemplateCreate(r),
-	} {
-		if err != nil {
-			neuronexlog.Error(err, "validate neuron container failed")
-			return err
-		}
+	if err := toInvalidError(r, "NeuronEX", validateCreate(r)); err != nil {
+		neuronexlog.Error(err, "validate create failed", "name", r.Name)
+		return err
 	}
 
 	return nil
 }
 
-// ValidateUpdate implements webhook.Validator so a webhook will be registered for the type
+// ValidateUpdate is called by the validating webhook through edgeValidator
 func (r *NeuronEX) ValidateUpdate(old runtime.Object) error {
 	neuronexlog.Info("validate update", "name", r.Name)
 
-	for _, err := range []error{
-		validateNeuronContainer(r),
-		validateVolumeTemplateUpdate(r, old.(*NeuronEX)),
-	} {
-		if err != nil {
-			neuronexlog.Error(err, "validate neuron container failed")
-			return err
-		}
+	if err := toInvalidError(r, "NeuronEX", validateUpdate(r, old.(*NeuronEX))); err != nil {
+		neuronexlog.Error(err, "validate update failed", "name", r.Name)
+		return err
 	}
 
 	return nil
 }
 
-// ValidateDelete implements webhook.Validator so a webhook will be registered for the type
+// ValidateDelete is called by the validating webhook through edgeValidator
 func (r *NeuronEX) ValidateDelete() error {
 	neuronexlog.Info("validate delete", "name", r.Name)
 
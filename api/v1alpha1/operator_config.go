@@ -0,0 +1,60 @@
+package v1alpha1
+
+import (
+	configv1alpha1 "github.com/emqx/edge-operator/api/config/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// operatorConfig holds the defaults that the defaulting webhooks and the controllers read
+var operatorConfig = newOperatorConfig(nil)
+
+// SetOperatorConfig sets the configuration the defaulting webhooks and the controllers read from, it must
+// be called before the manager is started
+func SetOperatorConfig(config *configv1alpha1.OperatorConfig) {
+	operatorConfig = newOperatorConfig(config)
+}
+
+func newOperatorConfig(config *configv1alpha1.OperatorConfig) *configv1alpha1.OperatorConfig {
+	if config == nil {
+		config = &configv1alpha1.OperatorConfig{}
+	} else {
+		config = config.DeepCopy()
+	}
+	config.Default()
+	return config
+}
+
+// RewriteImage applies the image rewrite policy to the image of a container that the operator creates
+func RewriteImage(image string) string {
+	return operatorConfig.RewriteImage(image)
+}
+
+// ImagePullSecrets returns the image pull secrets of the image rewrite policy
+func ImagePullSecrets() []corev1.LocalObjectReference {
+	return operatorConfig.ImageRewrite.ImagePullSecrets
+}
+
+// MigrationImage returns the image of the Jobs that copy the persistent data, with the image rewrite policy
+// applied
+func MigrationImage() string {
+	return operatorConfig.RewriteImage(operatorConfig.Migration.Image)
+}
+
+// newHTTPProbe returns a probe on the web port of a component with the configured timings
+func newHTTPProbe(port intstr.IntOrString) *corev1.Probe {
+	return &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{
+				Path:   "/",
+				Port:   port,
+				Scheme: corev1.URISchemeHTTP,
+			},
+		},
+		InitialDelaySeconds: operatorConfig.Probes.InitialDelaySeconds,
+		TimeoutSeconds:      operatorConfig.Probes.TimeoutSeconds,
+		PeriodSeconds:       operatorConfig.Probes.PeriodSeconds,
+		SuccessThreshold:    operatorConfig.Probes.SuccessThreshold,
+		FailureThreshold:    operatorConfig.Probes.FailureThreshold,
+	}
+}
@@ -0,0 +1,90 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	configv1alpha1 "github.com/emqx/edge-operator/api/config/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestOperatorConfig(t *testing.T) {
+	SetOperatorConfig(&configv1alpha1.OperatorConfig{
+		Images: configv1alpha1.Images{
+			Neuron:  "emqx/neuron:2.3.0",
+			EKuiper: "lfedge/ekuiper:1.8.0-slim",
+		},
+		Ports: configv1alpha1.Ports{
+			Neuron:  7001,
+			EKuiper: 9082,
+		},
+		Probes: configv1alpha1.Probes{
+			PeriodSeconds: 30,
+		},
+	})
+	defer SetOperatorConfig(nil)
+
+	ins := &NeuronEX{ObjectMeta: metav1.ObjectMeta{Name: "neuronex"}}
+	ins.Default()
+
+	assert.Equal(t, "emqx/neuron:2.3.0", ins.GetNeuron().Image)
+	assert.Equal(t, "lfedge/ekuiper:1.8.0-slim", ins.GetEKuiper().Image)
+	assert.Equal(t, int32(7001), ins.GetNeuronPort())
+	assert.Contains(t, ins.GetNeuron().Env, corev1.EnvVar{Name: NeuronWebPortEnv, Value: "7001"})
+	assert.Equal(t, int32(9082), ins.GetEKuiperRestPort())
+	assert.Contains(t, ins.GetEKuiper().Env, corev1.EnvVar{Name: "KUIPER__BASIC__RESTPORT", Value: "9082"})
+
+	for _, probe := range []*corev1.Probe{ins.GetNeuron().ReadinessProbe, ins.GetEKuiper().LivenessProbe} {
+		assert.Equal(t, int32(30), probe.PeriodSeconds)
+		assert.Equal(t, int32(10), probe.InitialDelaySeconds)
+		assert.Equal(t, int32(12), probe.FailureThreshold)
+	}
+}
+
+func TestImageRewrite(t *testing.T) {
+	SetOperatorConfig(&configv1alpha1.OperatorConfig{
+		ImageRewrite: configv1alpha1.ImageRewrite{
+			Registries:       map[string]string{"docker.io": "registry.example.com/dockerhub"},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "mirror"}},
+		},
+	})
+	defer SetOperatorConfig(nil)
+
+	ins := &NeuronEX{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuronex"},
+		Spec: NeuronEXSpec{
+			EdgePodSpec: EdgePodSpec{
+				InitContainers:   []corev1.Container{{Name: "init", Image: "quay.io/prometheus/busybox"}},
+				ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}},
+			},
+			Neuron:  corev1.Container{Name: "neuron", Image: "emqx/neuron:2.3.0"},
+			EKuiper: corev1.Container{Name: "ekuiper", Image: "lfedge/ekuiper:1.8.0-slim"},
+		},
+	}
+	ins.Default()
+	assert.Equal(t, "registry.example.com/dockerhub/emqx/neuron:2.3.0", ins.Spec.Neuron.Image)
+	assert.Equal(t, "registry.example.com/dockerhub/lfedge/ekuiper:1.8.0-slim", ins.Spec.EKuiper.Image)
+	assert.Equal(t, "quay.io/prometheus/busybox", ins.Spec.InitContainers[0].Image)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry"}, {Name: "mirror"}}, ins.Spec.ImagePullSecrets)
+	assert.Equal(t, "neuron=emqx/neuron:2.3.0,ekuiper=lfedge/ekuiper:1.8.0-slim",
+		ins.Annotations[OriginalImagesAnnotation])
+
+	// the original images are kept on the next admissions
+	ins.Default()
+	assert.Equal(t, "registry.example.com/dockerhub/emqx/neuron:2.3.0", ins.Spec.Neuron.Image)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry"}, {Name: "mirror"}}, ins.Spec.ImagePullSecrets)
+	assert.Equal(t, "neuron=emqx/neuron:2.3.0,ekuiper=lfedge/ekuiper:1.8.0-slim",
+		ins.Annotations[OriginalImagesAnnotation])
+
+	// an image set to one that is not rewritten has no original image
+	ins.Spec.Neuron.Image = "quay.io/emqx/neuron:2.4.0"
+	ins.Spec.EKuiper.Image = "lfedge/ekuiper:1.9.0-slim"
+	ins.Default()
+	assert.Equal(t, "quay.io/emqx/neuron:2.4.0", ins.Spec.Neuron.Image)
+	assert.Equal(t, "registry.example.com/dockerhub/lfedge/ekuiper:1.9.0-slim", ins.Spec.EKuiper.Image)
+	assert.Equal(t, "ekuiper=lfedge/ekuiper:1.9.0-slim", ins.Annotations[OriginalImagesAnnotation])
+
+	// the annotation is not propagated to the components
+	assert.True(t, IsInstanceAnnotation(OriginalImagesAnnotation))
+}
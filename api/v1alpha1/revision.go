@@ -0,0 +1,44 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strconv"
+
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// DefaultRevisionHistoryLimit is the number of revisions of the spec that are kept by default
+const DefaultRevisionHistoryLimit = 10
+
+// RollbackToLastGood is the value of the rollback annotation that restores the last good revision
+const RollbackToLastGood = "last-good"
+
+func getRevisionHistoryLimit(limit *int32) int32 {
+	if limit == nil {
+		return DefaultRevisionHistoryLimit
+	}
+	return *limit
+}
+
+// ParseRollback returns the revision number of the rollback annotation, or 0 for the last good revision
+func ParseRollback(value string) (int64, error) {
+	if value == RollbackToLastGood {
+		return 0, nil
+	}
+	revision, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || revision <= 0 {
+		return 0, fmt.Errorf("must be a revision number or %s", RollbackToLastGood)
+	}
+	return revision, nil
+}
+
+func validateRollback(ins EdgeInterface, path *field.Path) field.ErrorList {
+	value, ok := ins.GetAnnotations()[RollbackAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := ParseRollback(value); err != nil {
+		return field.ErrorList{field.Invalid(path.Key(RollbackAnnotation), value, err.Error())}
+	}
+	return nil
+}
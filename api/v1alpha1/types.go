@@ -2,6 +2,7 @@ package v1alpha1
 
 import (
 	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -21,6 +22,41 @@ const (
 	CRReady    CRPhase = "Ready"
 )
 
+const (
+	// neuronBuiltinPort is the web port of the neuron builds that do not accept a port setting
+	neuronBuiltinPort int32 = 7000
+	// NeuronWebPortEnv sets the web port of the neuron builds that accept a port setting
+	NeuronWebPortEnv = "NEURON_WEB_PORT"
+)
+
+// getNeuronPort returns the port set in the spec, or else the port named "neuron" of the container, or else
+// the default port of the operator config
+func getNeuronPort(port int32, neuron *corev1.Container) int32 {
+	if port != 0 {
+		return port
+	}
+	for _, p := range neuron.Ports {
+		if p.Name == "neuron" && p.ContainerPort != 0 {
+			return p.ContainerPort
+		}
+	}
+	return operatorConfig.Ports.Neuron
+}
+
+const (
+	// ConditionPaused is true while the reconciliation of the instance is paused
+	ConditionPaused = "Paused"
+	// ConditionPodScheduled is false while a pod of the instance can not be scheduled on a node
+	ConditionPodScheduled = "PodScheduled"
+	// ConditionVolumesBound is false while a persistent volume claim of the instance is not bound
+	ConditionVolumesBound = "VolumesBound"
+	// ConditionPendingRestart is true while changes of the pod template are held until a maintenance window
+	ConditionPendingRestart = "PendingRestart"
+	// ConditionAdopted is false while the Deployment or Service named by the adoption annotations can not be
+	// adopted
+	ConditionAdopted = "Adopted"
+)
+
 // +kubebuilder:object:generate=false
 type EdgeInterface interface {
 	client.Object
@@ -33,6 +69,7 @@ type EdgeInterface interface {
 	GetComponentType() ComponentType
 
 	GetEdgePodSpec() EdgePodSpec
+	SetEdgePodSpec(EdgePodSpec)
 	GetNeuron() *corev1.Container
 	GetEKuiper() *corev1.Container
 
@@ -47,6 +84,41 @@ type EdgeInterface interface {
 
 	GetReplicas() *int32
 	SetReplicas(replicas int32)
+
+	GetPaused() bool
+
+	// GetNeuronPort returns the neuron web port, or 0 if the instance has no neuron container
+	GetNeuronPort() int32
+
+	// GetEKuiperRestPort returns the ekuiper REST port, or 0 if the instance has no ekuiper container
+	GetEKuiperRestPort() int32
+
+	// GetEKuiperConfig returns the typed ekuiper config, or nil if the instance has none
+	GetEKuiperConfig() *EKuiperConfig
+
+	// GetNeuronConfig returns the typed neuron config, or nil if the instance has none
+	GetNeuronConfig() *NeuronConfig
+
+	// GetHighAvailability returns the active/standby settings, or nil if the instance runs a single pod
+	GetHighAvailability() *HighAvailability
+
+	// GetDevices returns the devices passed through to the neuron container
+	GetDevices() []Device
+
+	// GetPinImageDigests returns whether the images are pinned to the digests of their tags
+	GetPinImageDigests() bool
+
+	// GetVolumeSnapshots returns the snapshot settings, or nil if the instance takes no snapshot
+	GetVolumeSnapshots() *VolumeSnapshots
+
+	// GetCloneFrom returns the instance the configuration is copied from, or nil
+	GetCloneFrom() *CloneFrom
+
+	// GetRevisionHistoryLimit returns the number of revisions of the spec that are kept
+	GetRevisionHistoryLimit() int32
+
+	// GetMaintenanceWindows returns the windows in which the pods may be restarted, any time when empty
+	GetMaintenanceWindows() []MaintenanceWindow
 }
 
 // +kubebuilder:object:generate=true
@@ -317,6 +389,89 @@ type EdgeStatus struct {
 	// Ready: The pod has been ready for serving
 	// +optional
 	Phase CRPhase `json:"phase"`
+	// Conditions represent the latest available observations of the instance's state.
+	// +optional
+	// +patchMergeKey=type
+	// +patchStrategy=merge
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty" patchStrategy:"merge" patchMergeKey:"type"`
+	// NeuronVersion is the tag, or else the digest, of the image that the ready neuron containers run
+	// +optional
+	NeuronVersion string `json:"neuronVersion,omitempty"`
+	// EKuiperVersion is the tag, or else the digest, of the image that the ready ekuiper containers run
+	// +optional
+	EKuiperVersion string `json:"ekuiperVersion,omitempty"`
+	// ActivePod is the pod running the southbound nodes in high availability mode
+	// +optional
+	ActivePod string `json:"activePod,omitempty"`
+	// Containers are the states of the init containers and containers of the instance pods
+	// +optional
+	Containers []EdgeContainerStatus `json:"containers,omitempty"`
+	// ImageDigests are the digests the images are pinned to when spec.pinImageDigests is set
+	// +optional
+	ImageDigests *ImageDigests `json:"imageDigests,omitempty"`
+	// DeploymentSelector is the selector of the adopted Deployment, it is immutable and is kept instead of
+	// the labels of the instance. It mirrors the edge.emqx.io/adopted-selector annotation.
+	// +optional
+	DeploymentSelector *metav1.LabelSelector `json:"deploymentSelector,omitempty"`
+	// VolumeMigration is the last copy of the persistent data to the claims of a new volume claim template
+	// +optional
+	VolumeMigration *VolumeMigration `json:"volumeMigration,omitempty"`
+	// Snapshot is the last snapshot set taken of the persistent volumes
+	// +optional
+	Snapshot *SnapshotStatus `json:"snapshot,omitempty"`
+	// Clone is the progress of the copy of the configuration of spec.cloneFrom
+	// +optional
+	Clone *CloneStatus `json:"clone,omitempty"`
+	// CurrentRevision is the ControllerRevision of the spec that was last reconciled
+	// +optional
+	CurrentRevision string `json:"currentRevision,omitempty"`
+	// LastGoodRevision is the ControllerRevision of the last spec whose pods were all updated and ready
+	// +optional
+	LastGoodRevision string `json:"lastGoodRevision,omitempty"`
+}
+
+// ImageDigests are the digests resolved from the tags of the container images
+type ImageDigests struct {
+	// ResolveRequest is the value of the edge.emqx.io/resolve-digests annotation when the digests were
+	// resolved, a new value resolves them again
+	// +optional
+	ResolveRequest string `json:"resolveRequest,omitempty"`
+	// Images are the resolved digests of the containers
+	// +optional
+	Images []ImageDigest `json:"images,omitempty"`
+}
+
+// ImageDigest is the digest of the image of a container
+type ImageDigest struct {
+	// Container is the name of the container
+	Container string `json:"container"`
+	// Image is the image of the container in the spec
+	Image string `json:"image"`
+	// Digest is the digest of the image when it was resolved, such as sha256:...
+	Digest string `json:"digest"`
+}
+
+// EdgeContainerStatus is the state of a container of an instance pod
+type EdgeContainerStatus struct {
+	// Name is the name of the container
+	Name string `json:"name"`
+	// Pod is the name of the pod running the container
+	Pod string `json:"pod"`
+	// Ready is true when the container passes its readiness probe
+	Ready bool `json:"ready"`
+	// RestartCount is the number of times the container has been restarted
+	RestartCount int32 `json:"restartCount"`
+	// WaitingReason is why the container is not running yet, such as ImagePullBackOff or CrashLoopBackOff
+	// +optional
+	WaitingReason string `json:"waitingReason,omitempty"`
+	// LastTerminationReason is why the container last terminated, such as OOMKilled or Error
+	// +optional
+	LastTerminationReason string `json:"lastTerminationReason,omitempty"`
+	// Message is a human readable message of the waiting or last termination reason
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 type PublicKey struct {
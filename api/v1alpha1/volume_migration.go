@@ -0,0 +1,59 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VolumeMigrationPhase is the progress of a volume migration
+type VolumeMigrationPhase string
+
+const (
+	// VolumeMigrationStopping waits for the pods to stop before the data of their claims is copied
+	VolumeMigrationStopping VolumeMigrationPhase = "Stopping"
+	// VolumeMigrationCopying runs the Job that copies the data to the new claims
+	VolumeMigrationCopying VolumeMigrationPhase = "Copying"
+	// VolumeMigrationCompleted is set once the data is copied, the pods then mount the new claims
+	VolumeMigrationCompleted VolumeMigrationPhase = "Completed"
+	// VolumeMigrationFailed is set when the copy Job fails, the pods keep the source volumes
+	VolumeMigrationFailed VolumeMigrationPhase = "Failed"
+	// VolumeMigrationRolledBack is set when the volume claim template is reverted before the end of the copy
+	VolumeMigrationRolledBack VolumeMigrationPhase = "RolledBack"
+	// VolumeMigrationAwaitingConfirmation holds the migration of emptyDir volumes, whose data can not be
+	// copied, until the DiscardDataAnnotation is set. The pods keep their emptyDir volumes meanwhile.
+	VolumeMigrationAwaitingConfirmation VolumeMigrationPhase = "AwaitingConfirmation"
+	// VolumeMigrationDiscarded is set once the data of the emptyDir volumes is discarded with the
+	// DiscardDataAnnotation, the pods then mount the new empty claims
+	VolumeMigrationDiscarded VolumeMigrationPhase = "Discarded"
+)
+
+// VolumeMigration is the copy of the persistent data to the claims of a new volume claim template
+type VolumeMigration struct {
+	// Phase is the progress of the migration
+	Phase VolumeMigrationPhase `json:"phase"`
+	// Job is the name of the Job copying the data
+	// +optional
+	Job string `json:"job,omitempty"`
+	// Volumes are the migrated volumes, their source claims are kept as rollback points
+	// +optional
+	Volumes []MigratedVolume `json:"volumes,omitempty"`
+	// StartTime is the time the migration started
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time the data was copied
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message tells why the migration failed or waits
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// MigratedVolume is a persistent volume of the pod whose data is copied to a new claim
+type MigratedVolume struct {
+	// Name is the name of the volume in the pod, such as neuron-data
+	Name string `json:"name"`
+	// SourceClaim is the claim the data is copied from, it is empty for an emptyDir volume
+	// +optional
+	SourceClaim string `json:"sourceClaim,omitempty"`
+	// TargetClaim is the claim the data is copied to
+	TargetClaim string `json:"targetClaim"`
+}
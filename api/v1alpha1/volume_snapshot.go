@@ -0,0 +1,145 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"reflect"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// VolumeSnapshots takes VolumeSnapshots of the persistent volumes of the instance with a CSI snapshotter. A
+// snapshot set is taken each time the edge.emqx.io/snapshot annotation gets a new value, its VolumeSnapshots
+// are named <instance>-<volume>-<value> and are kept when the instance is deleted.
+type VolumeSnapshots struct {
+	// VolumeSnapshotClassName is the class of the snapshots, the default class of the CSI driver is used
+	// when it is not set
+	// +optional
+	VolumeSnapshotClassName *string `json:"volumeSnapshotClassName,omitempty"`
+	// Quiesce scales the instance to zero until the snapshots are taken, so that no write is in flight
+	//+kubebuilder:default:=true
+	// +optional
+	Quiesce *bool `json:"quiesce,omitempty"`
+	// RestoreFrom creates the PersistentVolumeClaims of a new instance from a snapshot set
+	// +optional
+	RestoreFrom *SnapshotReference `json:"restoreFrom,omitempty"`
+}
+
+// SnapshotReference is a snapshot set taken of an instance in the same namespace
+type SnapshotReference struct {
+	// Instance is the name of the instance the snapshots were taken of
+	Instance string `json:"instance"`
+	// Name is the name of the snapshot set, the value of the edge.emqx.io/snapshot annotation
+	Name string `json:"name"`
+}
+
+// GetQuiesce returns whether the instance is scaled to zero while the snapshots are taken, defaulting to true
+func (s *VolumeSnapshots) GetQuiesce() bool {
+	return s.Quiesce == nil || *s.Quiesce
+}
+
+// GetVolumeSnapshotName returns the name of the VolumeSnapshot of a volume of an instance in a snapshot set
+func GetVolumeSnapshotName(instance, volume, snapshot string) string {
+	return instance + "-" + volume + "-" + snapshot
+}
+
+// SnapshotPhase is the progress of a snapshot set
+type SnapshotPhase string
+
+const (
+	// SnapshotQuiescing waits for the pods to stop
+	SnapshotQuiescing SnapshotPhase = "Quiescing"
+	// SnapshotCreating waits for the CSI driver to take the snapshots
+	SnapshotCreating SnapshotPhase = "Creating"
+	// SnapshotTaken is set once all the snapshots are taken, the pods are started again while the snapshots
+	// are uploaded
+	SnapshotTaken SnapshotPhase = "Taken"
+	// SnapshotCompleted is set once all the snapshots are ready to use
+	SnapshotCompleted SnapshotPhase = "Completed"
+	// SnapshotFailed is set when a snapshot fails
+	SnapshotFailed SnapshotPhase = "Failed"
+)
+
+// SnapshotStatus is the progress of the last snapshot set
+type SnapshotStatus struct {
+	// Name is the name of the snapshot set
+	Name string `json:"name"`
+	// Phase is the progress of the snapshot set
+	Phase SnapshotPhase `json:"phase"`
+	// VolumeSnapshots are the names of the VolumeSnapshots of the set
+	// +optional
+	VolumeSnapshots []string `json:"volumeSnapshots,omitempty"`
+	// StartTime is the time the snapshot set was requested
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+	// CompletionTime is the time all the snapshots were ready to use
+	// +optional
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+	// Message tells why a snapshot failed
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+func validateVolumeSnapshots(ins EdgeInterface, specPath *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	annotationPath := field.NewPath("metadata", "annotations").Key(SnapshotAnnotation)
+	snapshots := ins.GetVolumeSnapshots()
+	name, requested := ins.GetAnnotations()[SnapshotAnnotation]
+	if snapshots == nil {
+		if requested {
+			allErrs = append(allErrs, field.Invalid(annotationPath, name, "snapshots need spec.volumeSnapshots"))
+		}
+		return allErrs
+	}
+
+	if ins.GetVolumeClaimTemplate() == nil {
+		allErrs = append(allErrs, field.Required(specPath.Child("volumeClaimTemplate"),
+			"the volume snapshots need persistent volumes"))
+	}
+	if requested {
+		allErrs = append(allErrs, validateSnapshotName(ins.GetName(), name, ins, annotationPath)...)
+	}
+	if restore := snapshots.RestoreFrom; restore != nil {
+		restorePath := specPath.Child("volumeSnapshots", "restoreFrom")
+		for _, msg := range validation.IsDNS1123Subdomain(restore.Instance) {
+			allErrs = append(allErrs, field.Invalid(restorePath.Child("instance"), restore.Instance, msg))
+		}
+		allErrs = append(allErrs, validateSnapshotName(restore.Instance, restore.Name, ins, restorePath.Child("name"))...)
+	}
+	return allErrs
+}
+
+// validateSnapshotName checks that the VolumeSnapshots of the snapshot set have valid names
+func validateSnapshotName(instance, name string, ins EdgeInterface, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	for _, msg := range validation.IsDNS1123Label(name) {
+		allErrs = append(allErrs, field.Invalid(path, name, msg))
+	}
+	if len(allErrs) > 0 {
+		return allErrs
+	}
+	for _, volume := range getPersistentVolumeNames(ins) {
+		vsName := GetVolumeSnapshotName(instance, volume, name)
+		for _, msg := range validation.IsDNS1123Subdomain(vsName) {
+			allErrs = append(allErrs, field.Invalid(path, name, fmt.Sprintf("VolumeSnapshot %s: %s", vsName, msg)))
+		}
+	}
+	return allErrs
+}
+
+// validateVolumeSnapshotsUpdate forbids restoring the claims of an instance that has already created them
+func validateVolumeSnapshotsUpdate(new, old EdgeInterface, specPath *field.Path) field.ErrorList {
+	var newRestore, oldRestore *SnapshotReference
+	if new.GetVolumeSnapshots() != nil {
+		newRestore = new.GetVolumeSnapshots().RestoreFrom
+	}
+	if old.GetVolumeSnapshots() != nil {
+		oldRestore = old.GetVolumeSnapshots().RestoreFrom
+	}
+	if !reflect.DeepEqual(newRestore, oldRestore) {
+		return field.ErrorList{field.Forbidden(specPath.Child("volumeSnapshots", "restoreFrom"),
+			"can only be set when the instance is created")}
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/utils/pointer"
 )
 
 func TestDefault(t *testing.T) {
@@ -407,3 +408,92 @@ func deepCopyEdgeEdgeInterface(ins EdgeInterface) EdgeInterface {
 	}
 	return got
 }
+
+func TestDefaultNeuronPort(t *testing.T) {
+	ins := &Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron"},
+		Spec: NeuronSpec{
+			ServiceTemplate: &corev1.Service{},
+		},
+	}
+	ins.Default()
+	assert.Equal(t, int32(7000), ins.GetNeuronPort())
+	assert.NotContains(t, ins.Spec.Neuron.Env, corev1.EnvVar{Name: NeuronWebPortEnv, Value: "7000"})
+
+	ins.Spec.NeuronPort = 8000
+	ins.Default()
+	assert.Contains(t, ins.Spec.Neuron.Env, corev1.EnvVar{Name: NeuronWebPortEnv, Value: "8000"})
+	assert.Contains(t, ins.Spec.Neuron.Ports, corev1.ContainerPort{Name: "neuron", Protocol: corev1.ProtocolTCP, ContainerPort: 8000})
+	assert.Equal(t, intstr.FromInt(8000), ins.Spec.Neuron.ReadinessProbe.HTTPGet.Port)
+	assert.Equal(t, intstr.FromInt(8000), ins.Spec.Neuron.LivenessProbe.HTTPGet.Port)
+	assert.Contains(t, ins.Spec.ServiceTemplate.Spec.Ports, corev1.ServicePort{
+		Name: "neuron", Protocol: corev1.ProtocolTCP, Port: 8000, TargetPort: intstr.FromInt(8000),
+	})
+
+	// a customized service port is kept, only its target follows
+	ins.Spec.ServiceTemplate.Spec.Ports[0].Port = 80
+	ins.Spec.NeuronPort = 9000
+	ins.Default()
+	assert.Contains(t, ins.Spec.Neuron.Env, corev1.EnvVar{Name: NeuronWebPortEnv, Value: "9000"})
+	assert.Contains(t, ins.Spec.ServiceTemplate.Spec.Ports, corev1.ServicePort{
+		Name: "neuron", Protocol: corev1.ProtocolTCP, Port: 80, TargetPort: intstr.FromInt(9000),
+	})
+	assert.Len(t, ins.Spec.ServiceTemplate.Spec.Ports, 1)
+
+	// back to the default, the env var is kept in sync
+	ins.Spec.NeuronPort = 0
+	ins.Spec.Neuron.Ports = nil
+	ins.Default()
+	assert.Contains(t, ins.Spec.Neuron.Env, corev1.EnvVar{Name: NeuronWebPortEnv, Value: "7000"})
+}
+
+func TestDefaultDevices(t *testing.T) {
+	ins := &Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron"},
+		Spec: NeuronSpec{
+			Devices: []Device{
+				{HostPath: "/dev/ttyUSB0"},
+				{VendorID: "0403", ProductID: "6001"},
+			},
+		},
+	}
+	ins.Default()
+	assert.Equal(t, []Device{
+		{HostPath: "/dev/ttyUSB0", ContainerPath: "/dev/ttyUSB0"},
+		{
+			HostPath:      "/dev/edge/usb-0403-6001",
+			ContainerPath: "/dev/edge/usb-0403-6001",
+			VendorID:      "0403",
+			ProductID:     "6001",
+			NodeLabel:     "device.edge.emqx.io/usb-0403-6001",
+		},
+	}, ins.Spec.Devices)
+}
+
+func TestDefaultNeuronConfigEnv(t *testing.T) {
+	ins := &Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron"},
+		Spec: NeuronSpec{
+			NeuronConfig: &NeuronConfig{LogLevel: "debug", DisableAuth: pointer.Bool(true)},
+		},
+	}
+	ins.Default()
+	// the env vars of the config are set on the pod template, so that a removed field is unset
+	assert.False(t, hasEnv(ins.Spec.Neuron.Env, "LOG_LEVEL"))
+	assert.False(t, hasEnv(ins.Spec.Neuron.Env, "DISABLE_AUTH"))
+}
+
+func TestGetDefaultPullPolicy(t *testing.T) {
+	for image, policy := range map[string]corev1.PullPolicy{
+		"emqx/neuron":                           corev1.PullAlways,
+		"emqx/neuron:latest":                    corev1.PullAlways,
+		"emqx/neuron:2.3.0":                     corev1.PullIfNotPresent,
+		"registry.example.com:5000/emqx/neuron": corev1.PullAlways,
+		"reg:5000/emqx/neuron:2.3":              corev1.PullIfNotPresent,
+		"emqx/neuron:2.3.0@sha256:0123":         corev1.PullIfNotPresent,
+		"emqx/neuron:latest@sha256:0123":        corev1.PullIfNotPresent,
+		"emqx/neuron@sha256:0123":               corev1.PullIfNotPresent,
+	} {
+		assert.Equal(t, policy, getDefaultPullPolicy(image), image)
+	}
+}
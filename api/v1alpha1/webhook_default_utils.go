@@ -1,8 +1,10 @@
 package v1alpha1
 
 import (
+	"strconv"
 	"strings"
 
+	configv1alpha1 "github.com/emqx/edge-operator/api/config/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/util/intstr"
@@ -24,12 +26,11 @@ func setDefaultNeuronContainer(ins EdgeInterface) {
 	if neuron.Name == "" {
 		neuron.Name = "neuron"
 	}
+	if neuron.Image == "" {
+		neuron.Image = operatorConfig.Images.Neuron
+	}
 	if neuron.ImagePullPolicy == "" {
-		neuron.ImagePullPolicy = corev1.PullAlways
-		i := strings.Split(ins.GetNeuron().Image, ":")
-		if len(i) == 2 && !strings.Contains(i[1], "latest") {
-			neuron.ImagePullPolicy = corev1.PullIfNotPresent
-		}
+		neuron.ImagePullPolicy = getDefaultPullPolicy(neuron.Image)
 	}
 	if neuron.TerminationMessagePath == "" {
 		neuron.TerminationMessagePath = corev1.TerminationMessagePathDefault
@@ -43,45 +44,45 @@ func setDefaultNeuronContainer(ins EdgeInterface) {
 			Value: "1",
 		},
 	})
-	neuron.Ports = mergeContainerPorts(neuron.Ports, []corev1.ContainerPort{
-		{
-			Name:     "neuron",
-			Protocol: corev1.ProtocolTCP,
-			// neuron web port is hardcode in source code
-			ContainerPort: 7000,
-		},
+	devices := ins.GetDevices()
+	for i := range devices {
+		if devices[i].VendorID != "" && devices[i].ProductID != "" {
+			if devices[i].HostPath == "" {
+				devices[i].HostPath = USBDeviceLink(devices[i].VendorID, devices[i].ProductID)
+			}
+			if devices[i].NodeLabel == "" {
+				devices[i].NodeLabel = USBDeviceLabel(devices[i].VendorID, devices[i].ProductID)
+			}
+		}
+		if devices[i].ContainerPath == "" {
+			devices[i].ContainerPath = devices[i].HostPath
+		}
+	}
+
+	// spec.neuronPort drives the web port, the env var, container port, probes and service port
+	// follow it when it changes
+	port := ins.GetNeuronPort()
+	// the builds listening on the hardcoded 7000 don't need the env var
+	if port != neuronBuiltinPort || hasEnv(neuron.Env, NeuronWebPortEnv) {
+		neuron.Env = setEnv(neuron.Env, corev1.EnvVar{Name: NeuronWebPortEnv, Value: strconv.Itoa(int(port))})
+	}
+	previous := setContainerPort(neuron, corev1.ContainerPort{
+		Name:          "neuron",
+		Protocol:      corev1.ProtocolTCP,
+		ContainerPort: port,
 	})
 	if neuron.ReadinessProbe == nil {
-		neuron.ReadinessProbe = &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path:   "/",
-					Port:   intstr.FromInt(7000),
-					Scheme: corev1.URISchemeHTTP,
-				},
-			},
-			InitialDelaySeconds: 10,
-			TimeoutSeconds:      1,
-			PeriodSeconds:       5,
-			SuccessThreshold:    1,
-			FailureThreshold:    12,
-		}
+		neuron.ReadinessProbe = newHTTPProbe(intstr.FromInt(int(port)))
+	} else {
+		updateProbePort(neuron.ReadinessProbe, previous, port)
 	}
 	if neuron.LivenessProbe == nil {
-		neuron.LivenessProbe = &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path:   "/",
-					Port:   intstr.FromInt(7000),
-					Scheme: corev1.URISchemeHTTP,
-				},
-			},
-			InitialDelaySeconds: 10,
-			TimeoutSeconds:      1,
-			PeriodSeconds:       5,
-			SuccessThreshold:    1,
-			FailureThreshold:    12,
-		}
+		neuron.LivenessProbe = newHTTPProbe(intstr.FromInt(int(port)))
+	} else {
+		updateProbePort(neuron.LivenessProbe, previous, port)
+	}
+	if svc := ins.GetServiceTemplate(); svc != nil {
+		updateServicePort(svc, "neuron", previous, port)
 	}
 }
 
@@ -90,12 +91,11 @@ func setDefaultEKuiperContainer(ins EdgeInterface) {
 	if ekuiper.Name == "" {
 		ekuiper.Name = "ekuiper"
 	}
+	if ekuiper.Image == "" {
+		ekuiper.Image = operatorConfig.Images.EKuiper
+	}
 	if ekuiper.ImagePullPolicy == "" {
-		ekuiper.ImagePullPolicy = corev1.PullAlways
-		i := strings.Split(ins.GetEKuiper().Image, ":")
-		if len(i) == 2 && !strings.Contains(i[1], "latest") {
-			ekuiper.ImagePullPolicy = corev1.PullIfNotPresent
-		}
+		ekuiper.ImagePullPolicy = getDefaultPullPolicy(ekuiper.Image)
 	}
 	if ekuiper.TerminationMessagePath == "" {
 		ekuiper.TerminationMessagePath = corev1.TerminationMessagePathDefault
@@ -106,7 +106,7 @@ func setDefaultEKuiperContainer(ins EdgeInterface) {
 	ekuiper.Env = mergeEnv(ekuiper.Env, []corev1.EnvVar{
 		{
 			Name:  "KUIPER__BASIC__RESTPORT",
-			Value: "9081",
+			Value: strconv.Itoa(int(operatorConfig.Ports.EKuiper)),
 		},
 		{
 			Name:  "KUIPER__BASIC__IGNORECASE",
@@ -117,51 +117,110 @@ func setDefaultEKuiperContainer(ins EdgeInterface) {
 			Value: "true",
 		},
 	})
-	containerPort := intstr.Parse("9081")
-	for _, env := range ekuiper.Env {
+
+	// the REST port of spec.ekuiperConfig or of the env var drives the container port, the probes and
+	// the service port, they follow it when it changes
+	port := getEKuiperRestPort(ins)
+	previous := setContainerPort(ekuiper, corev1.ContainerPort{
+		Name:          "ekuiper",
+		Protocol:      corev1.ProtocolTCP,
+		ContainerPort: port,
+	})
+	if ekuiper.ReadinessProbe == nil {
+		ekuiper.ReadinessProbe = newHTTPProbe(intstr.FromInt(int(port)))
+	} else {
+		updateProbePort(ekuiper.ReadinessProbe, previous, port)
+	}
+	if ekuiper.LivenessProbe == nil {
+		ekuiper.LivenessProbe = newHTTPProbe(intstr.FromInt(int(port)))
+	} else {
+		updateProbePort(ekuiper.LivenessProbe, previous, port)
+	}
+	if svc := ins.GetServiceTemplate(); svc != nil {
+		updateServicePort(svc, "ekuiper", previous, port)
+	}
+}
+
+// getDefaultPullPolicy returns IfNotPresent for the images pinned to a digest or to a tag other than latest,
+// and Always otherwise
+func getDefaultPullPolicy(image string) corev1.PullPolicy {
+	version := configv1alpha1.ImageVersion(image)
+	if strings.Contains(image, "@") || version != "" && !strings.Contains(version, "latest") {
+		return corev1.PullIfNotPresent
+	}
+	return corev1.PullAlways
+}
+
+// getEKuiperRestPort returns the ekuiper REST port, set by spec.ekuiperConfig.basic.restPort or by the
+// KUIPER__BASIC__RESTPORT env var, or else the default port of the operator config
+func getEKuiperRestPort(ins EdgeInterface) int32 {
+	if config := ins.GetEKuiperConfig(); config != nil && config.Basic != nil && config.Basic.RestPort != nil {
+		return *config.Basic.RestPort
+	}
+	port := intstr.FromInt(int(operatorConfig.Ports.EKuiper))
+	for _, env := range ins.GetEKuiper().Env {
 		if env.Name == "KUIPER__BASIC__RESTPORT" {
-			containerPort = intstr.Parse(env.Value)
+			port = intstr.Parse(env.Value)
 		}
 	}
-	ekuiper.Ports = mergeContainerPorts(ekuiper.Ports, []corev1.ContainerPort{
-		{
-			Name:          "ekuiper",
-			Protocol:      corev1.ProtocolTCP,
-			ContainerPort: containerPort.IntVal,
-		},
-	})
-	if ekuiper.ReadinessProbe == nil {
-		ekuiper.ReadinessProbe = &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path:   "/",
-					Port:   containerPort,
-					Scheme: corev1.URISchemeHTTP,
-				},
-			},
-			InitialDelaySeconds: 10,
-			TimeoutSeconds:      1,
-			PeriodSeconds:       5,
-			SuccessThreshold:    1,
-			FailureThreshold:    12,
+	return port.IntVal
+}
+
+// setImageRewrite points the images of the neuron, ekuiper and init containers to the mirrors of the image
+// rewrite policy, and records the original images in the original images annotation. The pull secrets of
+// the policy are added to the spec.
+func setImageRewrite(ins EdgeInterface) {
+	originals := map[string]string{}
+	for _, pair := range strings.Split(ins.GetAnnotations()[OriginalImagesAnnotation], ",") {
+		if name, image, ok := strings.Cut(pair, "="); ok {
+			originals[name] = image
 		}
 	}
-	if ekuiper.LivenessProbe == nil {
-		ekuiper.LivenessProbe = &corev1.Probe{
-			ProbeHandler: corev1.ProbeHandler{
-				HTTPGet: &corev1.HTTPGetAction{
-					Path:   "/",
-					Port:   containerPort,
-					Scheme: corev1.URISchemeHTTP,
-				},
-			},
-			InitialDelaySeconds: 10,
-			TimeoutSeconds:      1,
-			PeriodSeconds:       5,
-			SuccessThreshold:    1,
-			FailureThreshold:    12,
+
+	spec := ins.GetEdgePodSpec()
+	containers := []*corev1.Container{ins.GetNeuron(), ins.GetEKuiper()}
+	for i := range spec.InitContainers {
+		containers = append(containers, &spec.InitContainers[i])
+	}
+	var images []string
+	for _, container := range containers {
+		if container == nil {
+			continue
+		}
+		original, ok := originals[container.Name]
+		if image := RewriteImage(container.Image); image != container.Image {
+			original, ok = container.Image, true
+			container.Image = image
+		} else if ok && RewriteImage(original) != container.Image {
+			// the image was changed to one that is not rewritten
+			ok = false
+		}
+		if ok {
+			images = append(images, container.Name+"="+original)
+		}
+	}
+
+	for _, secret := range ImagePullSecrets() {
+		found := false
+		for _, s := range spec.ImagePullSecrets {
+			found = found || s.Name == secret.Name
+		}
+		if !found {
+			spec.ImagePullSecrets = append(spec.ImagePullSecrets, secret)
+		}
+	}
+	ins.SetEdgePodSpec(spec)
+
+	annotations := ins.GetAnnotations()
+	if len(images) == 0 {
+		delete(annotations, OriginalImagesAnnotation)
+	} else {
+		if annotations == nil {
+			annotations = map[string]string{}
 		}
+		annotations[OriginalImagesAnnotation] = strings.Join(images, ",")
 	}
+	ins.SetAnnotations(annotations)
 }
 
 func setDefaultVolume(ins EdgeInterface) {
@@ -178,11 +237,18 @@ func setDefaultVolume(ins EdgeInterface) {
 }
 
 func setDefaultService(ins EdgeInterface) {
+	adopted := ins.GetAnnotations()[AdoptServiceAnnotation]
+	if ins.GetServiceTemplate() == nil && adopted != "" {
+		ins.SetServiceTemplate(&corev1.Service{})
+	}
 	svc := ins.GetServiceTemplate()
 	if svc == nil {
 		return
 	}
 
+	if adopted != "" {
+		svc.Name = adopted
+	}
 	if svc.Name == "" {
 		svc.Name = ins.GetName()
 	}
@@ -235,9 +301,27 @@ func mergeAnnotations(target, desired metav1.Object) {
 	if desiredAnnotations != nil {
 		delete(desiredAnnotations, corev1.LastAppliedConfigAnnotation)
 	}
+	// these annotations change during the life of the instance, they would make the volume template change too
+	desiredAnnotations = filterMap(desiredAnnotations, func(key string) bool {
+		return !IsInstanceAnnotation(key)
+	})
 	target.SetAnnotations(mergeMap(target.GetAnnotations(), desiredAnnotations))
 }
 
+// filterMap returns the entries of a map whose key is kept
+func filterMap(m map[string]string, keep func(string) bool) map[string]string {
+	var result map[string]string
+	for key, value := range m {
+		if keep(key) {
+			if result == nil {
+				result = make(map[string]string)
+			}
+			result[key] = value
+		}
+	}
+	return result
+}
+
 // mergeMap merges a map into another map.
 //
 // This will return whether the target's values have changed.
@@ -270,19 +354,66 @@ func mergeEnv(target, desired []corev1.EnvVar) []corev1.EnvVar {
 	return result
 }
 
-// mergeContainerPorts merge the same name and containerPort's port
-func mergeContainerPorts(target, desired []corev1.ContainerPort) []corev1.ContainerPort {
-	ports := append(target, desired...)
-	result := make([]corev1.ContainerPort, 0, len(ports))
-	temp := map[string]struct{}{}
+func hasEnv(envs []corev1.EnvVar, name string) bool {
+	for _, env := range envs {
+		if env.Name == name {
+			return true
+		}
+	}
+	return false
+}
 
-	for _, item := range ports {
-		if _, ok := temp[item.Name]; !ok {
-			temp[item.Name] = struct{}{}
-			result = append(result, item)
+// setEnv sets an environment variable, replacing the value of an existing one with the same name
+func setEnv(target []corev1.EnvVar, env corev1.EnvVar) []corev1.EnvVar {
+	for i := range target {
+		if target[i].Name == env.Name {
+			target[i] = env
+			return target
 		}
 	}
-	return result
+	return append(target, env)
+}
+
+// setContainerPort sets a port of a container by name, and returns the number the port had before
+// or 0 if it is new
+func setContainerPort(container *corev1.Container, port corev1.ContainerPort) int32 {
+	for i := range container.Ports {
+		if container.Ports[i].Name == port.Name {
+			previous := container.Ports[i].ContainerPort
+			container.Ports[i].ContainerPort = port.ContainerPort
+			return previous
+		}
+	}
+	container.Ports = append(container.Ports, port)
+	return 0
+}
+
+// updateProbePort moves an HTTP probe on the previous number of a port to its new number
+func updateProbePort(probe *corev1.Probe, previous, port int32) {
+	if probe.HTTPGet == nil || previous == 0 || previous == port {
+		return
+	}
+	if probe.HTTPGet.Port.Type == intstr.Int && probe.HTTPGet.Port.IntVal == previous {
+		probe.HTTPGet.Port = intstr.FromInt(int(port))
+	}
+}
+
+// updateServicePort moves a service port targeting the previous number of a container port to its
+// new number, the service port follows too unless it was customized
+func updateServicePort(svc *corev1.Service, name string, previous, port int32) {
+	if previous == 0 || previous == port {
+		return
+	}
+	for i := range svc.Spec.Ports {
+		p := &svc.Spec.Ports[i]
+		if p.Name != name || p.TargetPort.Type != intstr.Int || p.TargetPort.IntVal != previous {
+			continue
+		}
+		if p.Port == previous {
+			p.Port = port
+		}
+		p.TargetPort = intstr.FromInt(int(port))
+	}
 }
 
 func mergeServicePort(svc *corev1.Service, required []corev1.ServicePort) {
@@ -0,0 +1,78 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+// setupWebhookWithManager registers the defaulting and validating webhooks of an instance type, when
+// watchNamespaces is not empty the webhooks allow the objects in other namespaces untouched, as they
+// are reconciled by another operator or not at all
+func setupWebhookWithManager(mgr ctrl.Manager, ins EdgeInterface, watchNamespaces []string) error {
+	gvk, err := apiutil.GVKForObject(ins, mgr.GetScheme())
+	if err != nil {
+		return err
+	}
+	suffix := strings.ReplaceAll(gvk.Group, ".", "-") + "-" + gvk.Version + "-" + strings.ToLower(gvk.Kind)
+
+	mutating := admission.DefaultingWebhookFor(mgr.GetScheme(), ins)
+	validating := admission.WithCustomValidator(mgr.GetScheme(), ins, &edgeValidator{})
+
+	if len(watchNamespaces) > 0 {
+		namespaces := make(map[string]struct{}, len(watchNamespaces))
+		for _, namespace := range watchNamespaces {
+			namespaces[namespace] = struct{}{}
+		}
+		mutating.Handler = &namespaceFilter{Handler: mutating.Handler, namespaces: namespaces}
+		validating.Handler = &namespaceFilter{Handler: validating.Handler, namespaces: namespaces}
+	}
+
+	mgr.GetWebhookServer().Register("/mutate-"+suffix, mutating)
+	mgr.GetWebhookServer().Register("/validate-"+suffix, validating)
+	return nil
+}
+
+// edgeValidator validates the instances with their ValidateCreate, ValidateUpdate and ValidateDelete methods,
+// and returns the warnings of the valid ones
+type edgeValidator struct{}
+
+var _ webhook.CustomValidator = &edgeValidator{}
+
+func (v *edgeValidator) ValidateCreate(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	ins := obj.(EdgeInterface)
+	if err := ins.ValidateCreate(); err != nil {
+		return nil, err
+	}
+	return GetWarnings(ins), nil
+}
+
+func (v *edgeValidator) ValidateUpdate(_ context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	ins := newObj.(EdgeInterface)
+	if err := ins.ValidateUpdate(oldObj); err != nil {
+		return nil, err
+	}
+	return append(GetWarnings(ins), getUpdateWarnings(ins, oldObj.(EdgeInterface))...), nil
+}
+
+func (v *edgeValidator) ValidateDelete(_ context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, obj.(EdgeInterface).ValidateDelete()
+}
+
+// namespaceFilter only passes the requests for objects in the watched namespaces to the handler
+type namespaceFilter struct {
+	admission.Handler
+	namespaces map[string]struct{}
+}
+
+func (f *namespaceFilter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	if _, ok := f.namespaces[req.Namespace]; !ok {
+		return admission.Allowed("namespace " + req.Namespace + " is not watched by the operator")
+	}
+	return f.Handler.Handle(ctx, req)
+}
@@ -0,0 +1,84 @@
+package v1alpha1
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	admissionv1 "k8s.io/api/admission/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+)
+
+func TestNamespaceFilter(t *testing.T) {
+	filter := &namespaceFilter{
+		Handler: admission.HandlerFunc(func(context.Context, admission.Request) admission.Response {
+			return admission.Denied("handled")
+		}),
+		namespaces: map[string]struct{}{"foo": {}},
+	}
+
+	req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Namespace: "foo"}}
+	got := filter.Handle(context.Background(), req)
+	assert.False(t, got.Allowed)
+	assert.Equal(t, "handled", got.Result.Message)
+
+	req.Namespace = "bar"
+	got = filter.Handle(context.Background(), req)
+	assert.True(t, got.Allowed)
+	assert.Equal(t, "namespace bar is not watched by the operator", got.Result.Message)
+}
+
+func TestEdgeValidator(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, AddToScheme(scheme))
+	webhook := admission.WithCustomValidator(scheme, &NeuronEX{}, &edgeValidator{})
+
+	ins := &NeuronEX{
+		TypeMeta:   metav1.TypeMeta{APIVersion: GroupVersion.String(), Kind: "NeuronEX"},
+		ObjectMeta: metav1.ObjectMeta{Name: "neuronex", Namespace: "default"},
+		Spec: NeuronEXSpec{
+			Neuron:  corev1.Container{Name: "neuron", Image: "emqx/neuron:2.3.0"},
+			EKuiper: corev1.Container{Name: "ekuiper", Image: "lfedge/ekuiper:2.3.0-slim"},
+		},
+	}
+	request := func(operation admissionv1.Operation, obj, old *NeuronEX) admission.Request {
+		req := admission.Request{AdmissionRequest: admissionv1.AdmissionRequest{Operation: operation}}
+		req.Object.Raw, _ = json.Marshal(obj)
+		if old != nil {
+			req.OldObject.Raw, _ = json.Marshal(old)
+		}
+		return req
+	}
+
+	got := webhook.Handle(context.Background(), request(admissionv1.Create, ins, nil))
+	assert.True(t, got.Allowed)
+	assert.Contains(t, got.Warnings,
+		"spec.volumeClaimTemplate is not set, the neuron data is stored in an emptyDir and lost when the pod is deleted")
+
+	// the update warnings are returned with the warnings of the instance
+	updated := ins.DeepCopy()
+	updated.Spec.VolumeClaimTemplate = &corev1.PersistentVolumeClaimTemplate{Spec: corev1.PersistentVolumeClaimSpec{
+		AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce},
+		Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceStorage: resource.MustParse("1Gi")},
+		},
+	}}
+	got = webhook.Handle(context.Background(), request(admissionv1.Update, updated, ins))
+	assert.True(t, got.Allowed)
+	assert.Contains(t, got.Warnings, "spec.volumeClaimTemplate is added, the data of the emptyDir volumes can not "+
+		"be copied to the new PersistentVolumeClaims, the pods keep them until "+
+		"metadata.annotations[edge.emqx.io/discard-emptydir-data] is set to true")
+
+	// an invalid instance is denied without warnings
+	invalid := ins.DeepCopy()
+	invalid.Spec.EKuiper.Image = ""
+	got = webhook.Handle(context.Background(), request(admissionv1.Create, invalid, nil))
+	assert.False(t, got.Allowed)
+	assert.Contains(t, got.Result.Message, "ekuiper container image is empty")
+	assert.Empty(t, got.Warnings)
+}
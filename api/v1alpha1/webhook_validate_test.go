@@ -2,11 +2,15 @@ package v1alpha1
 
 import (
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/utils/pointer"
 )
 
 func TestValiedate(t *testing.T) {
@@ -82,11 +86,79 @@ func TestValiedate(t *testing.T) {
 			})
 			assert.Nil(t, got.ValidateCreate())
 		})
-		t.Run("check volume template can not be updated", func(t *testing.T) {
+		t.Run("check image is empty", func(t *testing.T) {
+			if ins.GetNeuron() != nil {
+				got := deepCopyEdgeEdgeInterface(ins)
+				got.GetNeuron().Image = ""
+				assert.ErrorContains(t, got.ValidateCreate(), "neuron container image is empty")
+			}
+			if ins.GetEKuiper() != nil {
+				got := deepCopyEdgeEdgeInterface(ins)
+				got.GetEKuiper().Image = ""
+				assert.ErrorContains(t, got.ValidateCreate(), "ekuiper container image is empty")
+			}
+		})
+		t.Run("check pod spec", func(t *testing.T) {
+			got := deepCopyEdgeEdgeInterface(ins)
+			spec := got.GetEdgePodSpec()
+			spec.Volumes = []corev1.Volume{{Name: "public-key"}, {Name: "foo"}}
+			spec.PublicKeys = []PublicKey{{Name: "admin.pem"}, {Name: "../admin.pem"}, {Name: "admin.pem"}}
+			spec.EphemeralContainers = []corev1.EphemeralContainer{{}}
+			spec.ActiveDeadlineSeconds = new(int64)
+			spec.RestartPolicy = corev1.RestartPolicyNever
+			setEdgePodSpec(got, spec)
+
+			err := got.ValidateCreate()
+			assert.True(t, k8sErrors.IsInvalid(err))
+			assert.ErrorContains(t, err, `spec.volumes[0].name: Invalid value: "public-key": volume name is reserved by the operator`)
+			assert.ErrorContains(t, err, `spec.publicKeys[1].name: Invalid value: "../admin.pem"`)
+			assert.ErrorContains(t, err, `spec.publicKeys[2].name: Duplicate value: "admin.pem"`)
+			assert.ErrorContains(t, err, "spec.ephemeralContainers: Forbidden")
+			assert.ErrorContains(t, err, "spec.activeDeadlineSeconds: Forbidden")
+			assert.ErrorContains(t, err, `spec.restartPolicy: Unsupported value: "Never"`)
+			assert.NotContains(t, err.Error(), "spec.volumes[1]")
+			assert.NotContains(t, err.Error(), "spec.publicKeys[0]")
+		})
+		t.Run("check container ports", func(t *testing.T) {
+			got := deepCopyEdgeEdgeInterface(ins)
+			container := got.GetNeuron()
+			path := "spec.neuron"
+			if container == nil {
+				container = got.GetEKuiper()
+				path = "spec.ekuiper"
+			}
+			container.Ports = []corev1.ContainerPort{
+				{Name: "foo", ContainerPort: 1234},
+				{Name: "foo", ContainerPort: 1235},
+				{Name: "bar", ContainerPort: 1234, Protocol: corev1.ProtocolTCP},
+				{Name: "baz", ContainerPort: 1234, Protocol: corev1.ProtocolUDP},
+			}
+			err := got.ValidateCreate()
+			assert.ErrorContains(t, err, path+`.ports[1].name: Duplicate value: "foo"`)
+			assert.ErrorContains(t, err, path+".ports[2].containerPort: Duplicate value: 1234")
+			assert.NotContains(t, err.Error(), path+".ports[3]")
+		})
+		t.Run("check ekuiper rest port", func(t *testing.T) {
+			if ins.GetEKuiper() == nil {
+				return
+			}
+			got := deepCopyEdgeEdgeInterface(ins)
+			got.GetEKuiper().Env = []corev1.EnvVar{{Name: "KUIPER__BASIC__RESTPORT", Value: "abc"}}
+			assert.ErrorContains(t, got.ValidateCreate(), `spec.ekuiper.env[0].value: Invalid value: "abc": ekuiper rest port must be numeric`)
+
+			got.GetEKuiper().Env = []corev1.EnvVar{{Name: "KUIPER__BASIC__RESTPORT", Value: "70000"}}
+			assert.ErrorContains(t, got.ValidateCreate(), `spec.ekuiper.env[0].value: Invalid value: "70000"`)
+
+			got.GetEKuiper().Env = []corev1.EnvVar{{Name: "KUIPER__BASIC__RESTPORT", Value: "9082"}}
+			assert.Nil(t, got.ValidateCreate())
+		})
+		t.Run("check volume template migration", func(t *testing.T) {
 			got := deepCopyEdgeEdgeInterface(ins)
 			assert.Nil(t, got.ValidateUpdate(ins))
 
+			// emptyDir volumes are migrated to claims
 			got.SetVolumeClaimTemplate(&corev1.PersistentVolumeClaimTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "data"},
 				Spec: corev1.PersistentVolumeClaimSpec{
 					AccessModes: []corev1.PersistentVolumeAccessMode{
 						corev1.ReadWriteOnce,
@@ -98,7 +170,253 @@ func TestValiedate(t *testing.T) {
 					},
 				},
 			})
-			assert.ErrorContains(t, got.ValidateUpdate(ins), "volume template can not be updated")
+			assert.Nil(t, got.ValidateUpdate(ins))
+			assert.ErrorContains(t, ins.ValidateUpdate(got), "can not be migrated to emptyDir volumes")
+
+			// claims are migrated to the claims of a template with a new name
+			migrated := deepCopyEdgeEdgeInterface(got)
+			migrated.GetVolumeClaimTemplate().Spec.StorageClassName = pointer.String("fast")
+			assert.ErrorContains(t, migrated.ValidateUpdate(got), "the volume template needs a new name")
+			migrated.GetVolumeClaimTemplate().Name = "fast-data"
+			assert.Nil(t, migrated.ValidateUpdate(got))
 		})
 	}
 }
+
+func setEdgePodSpec(ins EdgeInterface, spec EdgePodSpec) {
+	switch obj := ins.(type) {
+	case *Neuron:
+		obj.Spec.EdgePodSpec = spec
+	case *EKuiper:
+		obj.Spec.EdgePodSpec = spec
+	case *NeuronEX:
+		obj.Spec.EdgePodSpec = spec
+	}
+}
+
+func TestValidateEKuiperConfig(t *testing.T) {
+	path := field.NewPath("spec", "ekuiperConfig")
+	assert.Empty(t, validateEKuiperConfig(nil, path))
+	assert.Empty(t, validateEKuiperConfig(&EKuiperConfig{
+		Basic: &EKuiperBasicConfig{Timezone: "UTC"},
+		Store: &EKuiperStoreConfig{Type: EKuiperStoreRedis, Redis: &EKuiperRedisConfig{Host: "redis"}},
+	}, path))
+
+	errs := validateEKuiperConfig(&EKuiperConfig{
+		Basic: &EKuiperBasicConfig{Timezone: "Mars/Olympus"},
+		Rule: &EKuiperRuleConfig{
+			RestartStrategy: &EKuiperRestartStrategy{Delay: pointer.Int32(1000), MaxDelay: pointer.Int32(10)},
+		},
+		Store: &EKuiperStoreConfig{Type: EKuiperStoreRedis, Sqlite: &EKuiperSqliteConfig{}},
+	}, path)
+	assert.ElementsMatch(t, []string{
+		"spec.ekuiperConfig.basic.timezone",
+		"spec.ekuiperConfig.rule.restartStrategy.maxDelay",
+		"spec.ekuiperConfig.store.redis.host",
+		"spec.ekuiperConfig.store.sqlite",
+	}, errorFields(errs))
+
+	errs = validateEKuiperConfig(&EKuiperConfig{
+		Store: &EKuiperStoreConfig{Type: EKuiperStoreSqlite, Redis: &EKuiperRedisConfig{Host: "redis"}},
+	}, path)
+	assert.Equal(t, []string{"spec.ekuiperConfig.store.redis"}, errorFields(errs))
+}
+
+func errorFields(errs field.ErrorList) []string {
+	var fields []string
+	for _, err := range errs {
+		fields = append(fields, err.Field)
+	}
+	return fields
+}
+
+func TestValidateNeuronConfig(t *testing.T) {
+	path := field.NewPath("spec", "neuronConfig")
+	assert.Empty(t, validateNeuronConfig(nil, path))
+	assert.Empty(t, validateNeuronConfig(&NeuronConfig{
+		Plugins: []string{"libplugin-mqtt.so"},
+		Files:   map[string]string{"zlog.conf": ""},
+	}, path))
+
+	errs := validateNeuronConfig(&NeuronConfig{
+		Plugins: []string{"libplugin-mqtt.so"},
+		Files:   map[string]string{"zlog/conf": "", NeuronPluginsFile: "{}"},
+	}, path)
+	assert.ElementsMatch(t, []string{
+		"spec.neuronConfig.files[zlog/conf]",
+		"spec.neuronConfig.files[default_plugins.json]",
+	}, errorFields(errs))
+}
+
+func TestValidateHighAvailability(t *testing.T) {
+	ins := &Neuron{Spec: NeuronSpec{HighAvailability: &HighAvailability{}}}
+	path := field.NewPath("spec")
+	assert.Empty(t, validateHighAvailability(ins, path))
+
+	ins.Spec.VolumeClaimTemplate = &corev1.PersistentVolumeClaimTemplate{
+		Spec: corev1.PersistentVolumeClaimSpec{AccessModes: []corev1.PersistentVolumeAccessMode{corev1.ReadWriteOnce}},
+	}
+	assert.Equal(t, []string{"spec.volumeClaimTemplate.spec.accessModes"}, errorFields(validateHighAvailability(ins, path)))
+
+	ins.Spec.VolumeClaimTemplate.Spec.AccessModes = []corev1.PersistentVolumeAccessMode{corev1.ReadWriteMany}
+	assert.Empty(t, validateHighAvailability(ins, path))
+}
+
+func TestValidateDevices(t *testing.T) {
+	path := field.NewPath("spec", "devices")
+	assert.Empty(t, validateDevices([]Device{
+		{HostPath: "/dev/ttyUSB0", NodeLabel: "edge.emqx.io/ttyUSB0"},
+		{HostPath: "/dev/ttyUSB1", ContainerPath: "/dev/ttyS1", NodeLabel: "serial=rs485"},
+	}, true, path))
+
+	errs := validateDevices([]Device{
+		{HostPath: "/tmp/ttyUSB0"},
+		{HostPath: "/dev/ttyUSB1", ContainerPath: "/tmp/ttyUSB0", NodeLabel: "serial=rs 485"},
+		{VendorID: "0403"},
+	}, true, path)
+	assert.ElementsMatch(t, []string{
+		"spec.devices[0].hostPath",
+		"spec.devices[1].containerPath",
+		"spec.devices[1].nodeLabel",
+		"spec.devices[2]",
+		"spec.devices[2].hostPath",
+	}, errorFields(errs))
+
+	// an unprivileged container can only open the devices of a device plugin
+	errs = validateDevices([]Device{
+		{HostPath: "/dev/ttyUSB0"},
+		{HostPath: "/dev/ttyUSB1", Resource: "smarter-devices/ttyUSB1"},
+	}, false, path)
+	assert.Equal(t, []string{"spec.devices[0].resource"}, errorFields(errs))
+}
+
+func TestValidateAdoption(t *testing.T) {
+	path := field.NewPath("metadata", "annotations")
+	ins := &NeuronEX{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			AdoptDeploymentAnnotation: "neuronex",
+			AdoptServiceAnnotation:    "neuronex",
+			AdoptPVCsAnnotation:       "neuron-data=data-neuron, ekuiper-data=data-ekuiper",
+		}},
+		Spec: NeuronEXSpec{
+			Neuron:              corev1.Container{Name: "neuron"},
+			EKuiper:             corev1.Container{Name: "ekuiper"},
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{},
+		},
+	}
+	assert.Empty(t, validateAdoption(ins, path))
+	claims, err := GetAdoptedPVCs(ins)
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]string{"neuron-data": "data-neuron", "ekuiper-data": "data-ekuiper"}, claims)
+
+	ins.Annotations[AdoptPVCsAnnotation] = "neuron-data"
+	assert.Equal(t, []string{"metadata.annotations[edge.emqx.io/adopt-pvcs]"}, errorFields(validateAdoption(ins, path)))
+
+	neuron := &Neuron{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			AdoptDeploymentAnnotation: "Neuron",
+			AdoptPVCsAnnotation:       "ekuiper-data=data",
+		}},
+		Spec: NeuronSpec{Neuron: corev1.Container{Name: "neuron"}},
+	}
+	assert.ElementsMatch(t, []string{
+		"metadata.annotations[edge.emqx.io/adopt-deployment]",
+		"metadata.annotations[edge.emqx.io/adopt-pvcs]",
+		"metadata.annotations[edge.emqx.io/adopt-pvcs]",
+	}, errorFields(validateAdoption(neuron, path)))
+
+	old := neuron.DeepCopy()
+	neuron.Annotations[AdoptServiceAnnotation] = "neuron"
+	assert.Equal(t, []string{"metadata.annotations[edge.emqx.io/adopt-service]"},
+		errorFields(validateAdoptionUpdate(neuron, old, path)))
+}
+
+func TestValidateVolumeSnapshots(t *testing.T) {
+	path := field.NewPath("spec")
+	ins := &Neuron{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "neuron",
+			Annotations: map[string]string{SnapshotAnnotation: "daily-1"},
+		},
+		Spec: NeuronSpec{Neuron: corev1.Container{Name: "neuron"}},
+	}
+	assert.Equal(t, []string{"metadata.annotations[edge.emqx.io/snapshot]"},
+		errorFields(validateVolumeSnapshots(ins, path)))
+
+	ins.Spec.VolumeSnapshots = &VolumeSnapshots{}
+	assert.Equal(t, []string{"spec.volumeClaimTemplate"}, errorFields(validateVolumeSnapshots(ins, path)))
+
+	ins.Spec.VolumeClaimTemplate = &corev1.PersistentVolumeClaimTemplate{}
+	assert.Empty(t, validateVolumeSnapshots(ins, path))
+	assert.True(t, ins.Spec.VolumeSnapshots.GetQuiesce())
+
+	ins.Annotations[SnapshotAnnotation] = "Daily"
+	ins.Spec.VolumeSnapshots.RestoreFrom = &SnapshotReference{Instance: "old-neuron", Name: "daily_1"}
+	assert.Equal(t, []string{
+		"metadata.annotations[edge.emqx.io/snapshot]",
+		"spec.volumeSnapshots.restoreFrom.name",
+	}, errorFields(validateVolumeSnapshots(ins, path)))
+
+	old := ins.DeepCopy()
+	ins.Spec.VolumeSnapshots.RestoreFrom.Name = "daily-2"
+	assert.Equal(t, []string{"spec.volumeSnapshots.restoreFrom"},
+		errorFields(validateVolumeSnapshotsUpdate(ins, old, path)))
+}
+
+func TestValidateCloneFrom(t *testing.T) {
+	path := field.NewPath("spec", "cloneFrom")
+	ins := &Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "line-2"},
+		Spec: NeuronSpec{
+			Neuron:    corev1.Container{Name: "neuron"},
+			CloneFrom: &CloneFrom{Kind: "NeuronEX", Name: "line-1", Substitutions: []Substitution{{From: "10.0.0.1"}}},
+		},
+	}
+	assert.Empty(t, validateCloneFrom(ins, path))
+
+	ins.Spec.CloneFrom = &CloneFrom{Kind: "EKuiper", Name: "line-1", Substitutions: []Substitution{{To: "10.0.1.1"}}}
+	assert.Equal(t, []string{"spec.cloneFrom.kind", "spec.cloneFrom.substitutions[0].from"},
+		errorFields(validateCloneFrom(ins, path)))
+
+	ins.Spec.CloneFrom = &CloneFrom{Kind: "Neuron", Name: "line-2"}
+	assert.Equal(t, []string{"spec.cloneFrom.name"}, errorFields(validateCloneFrom(ins, path)))
+
+	old := ins.DeepCopy()
+	ins.Spec.CloneFrom = nil
+	assert.Equal(t, []string{"spec.cloneFrom"}, errorFields(validateCloneFromUpdate(ins, old, path)))
+}
+
+func TestValidateRollback(t *testing.T) {
+	path := field.NewPath("metadata", "annotations")
+	ins := &EKuiper{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{RollbackAnnotation: "3"}}}
+	assert.Empty(t, validateRollback(ins, path))
+	revision, err := ParseRollback("3")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(3), revision)
+
+	ins.Annotations[RollbackAnnotation] = RollbackToLastGood
+	assert.Empty(t, validateRollback(ins, path))
+
+	for _, value := range []string{"0", "-1", "previous"} {
+		ins.Annotations[RollbackAnnotation] = value
+		assert.Equal(t, []string{"metadata.annotations[edge.emqx.io/rollback-to]"},
+			errorFields(validateRollback(ins, path)))
+	}
+	assert.Equal(t, DefaultRevisionHistoryLimit, int(ins.GetRevisionHistoryLimit()))
+}
+
+func TestValidateMaintenanceWindows(t *testing.T) {
+	path := field.NewPath("spec", "maintenanceWindows")
+	ins := &Neuron{Spec: NeuronSpec{MaintenanceWindows: []MaintenanceWindow{
+		{Schedule: "0 2 * * 6", Duration: metav1.Duration{Duration: 2 * time.Hour}, TimeZone: "Europe/Berlin"},
+		{Schedule: "@daily", Duration: metav1.Duration{Duration: time.Hour}},
+	}}}
+	assert.Empty(t, validateMaintenanceWindows(ins, path))
+
+	ins.Spec.MaintenanceWindows = []MaintenanceWindow{{Schedule: "0 2 * *", TimeZone: "Mars/Olympus"}}
+	assert.Equal(t, []string{
+		"spec.maintenanceWindows[0].schedule",
+		"spec.maintenanceWindows[0].timeZone",
+		"spec.maintenanceWindows[0].duration",
+	}, errorFields(validateMaintenanceWindows(ins, path)))
+}
@@ -1,39 +1,366 @@
 package v1alpha1
 
 import (
-	"errors"
 	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/validation/field"
 )
 
-func validateNeuronContainer(ins EdgeInterface) error {
+// reservedVolumeNames are the volumes that the operator adds to the pod
+var reservedVolumeNames = map[string]struct{}{
+	"neuron-data":           {},
+	"ekuiper-data":          {},
+	"ekuiper-plugins":       {},
+	"ekuiper-init-rule-set": {},
+	"shared-tmp":            {},
+	"public-key":            {},
+	"neuron-config":         {},
+}
+
+// toInvalidError aggregates the field errors of an instance into an Invalid API error
+func toInvalidError(ins EdgeInterface, kind string, allErrs field.ErrorList) error {
+	if len(allErrs) == 0 {
+		return nil
+	}
+	return k8sErrors.NewInvalid(GroupVersion.WithKind(kind).GroupKind(), ins.GetName(), allErrs)
+}
+
+// validateCreate returns the errors of a new instance
+func validateCreate(ins EdgeInterface) field.ErrorList {
+	specPath := field.NewPath("spec")
+
+	var allErrs field.ErrorList
+	if ins.GetNeuron() != nil {
+		allErrs = append(allErrs, validateNeuronContainer(ins, specPath.Child("neuron"))...)
+		allErrs = append(allErrs, validateNeuronConfig(ins.GetNeuronConfig(), specPath.Child("neuronConfig"))...)
+		allErrs = append(allErrs, validateDevices(ins.GetDevices(), isPrivileged(ins.GetNeuron()), specPath.Child("devices"))...)
+	}
+	if ins.GetEKuiper() != nil {
+		allErrs = append(allErrs, validateEKuiperContainer(ins, specPath.Child("ekuiper"))...)
+		allErrs = append(allErrs, validateEKuiperConfig(ins.GetEKuiperConfig(), specPath.Child("ekuiperConfig"))...)
+	}
+	allErrs = append(allErrs, validateContainerPorts(ins, specPath)...)
+	allErrs = append(allErrs, validateHighAvailability(ins, specPath)...)
+	allErrs = append(allErrs, validatePodSpec(ins, specPath)...)
+	allErrs = append(allErrs, validateVolumeTemplateCreate(ins, specPath.Child("volumeClaimTemplate"))...)
+	allErrs = append(allErrs, validateAdoption(ins, field.NewPath("metadata", "annotations"))...)
+	allErrs = append(allErrs, validateVolumeSnapshots(ins, specPath)...)
+	allErrs = append(allErrs, validateCloneFrom(ins, specPath.Child("cloneFrom"))...)
+	allErrs = append(allErrs, validateRollback(ins, field.NewPath("metadata", "annotations"))...)
+	allErrs = append(allErrs, validateMaintenanceWindows(ins, specPath.Child("maintenanceWindows"))...)
+	return allErrs
+}
+
+// validateUpdate returns the errors of an updated instance
+func validateUpdate(new, old EdgeInterface) field.ErrorList {
+	specPath := field.NewPath("spec")
+
+	var allErrs field.ErrorList
+	if new.GetNeuron() != nil {
+		allErrs = append(allErrs, validateNeuronContainer(new, specPath.Child("neuron"))...)
+		allErrs = append(allErrs, validateNeuronConfig(new.GetNeuronConfig(), specPath.Child("neuronConfig"))...)
+		allErrs = append(allErrs, validateDevices(new.GetDevices(), isPrivileged(new.GetNeuron()), specPath.Child("devices"))...)
+	}
+	if new.GetEKuiper() != nil {
+		allErrs = append(allErrs, validateEKuiperContainer(new, specPath.Child("ekuiper"))...)
+		allErrs = append(allErrs, validateEKuiperConfig(new.GetEKuiperConfig(), specPath.Child("ekuiperConfig"))...)
+	}
+	allErrs = append(allErrs, validateContainerPorts(new, specPath)...)
+	allErrs = append(allErrs, validateHighAvailability(new, specPath)...)
+	allErrs = append(allErrs, validatePodSpec(new, specPath)...)
+	allErrs = append(allErrs, validateVolumeTemplateUpdate(new, old, specPath.Child("volumeClaimTemplate"))...)
+	allErrs = append(allErrs, validateAdoption(new, field.NewPath("metadata", "annotations"))...)
+	allErrs = append(allErrs, validateAdoptionUpdate(new, old, field.NewPath("metadata", "annotations"))...)
+	allErrs = append(allErrs, validateVolumeSnapshots(new, specPath)...)
+	allErrs = append(allErrs, validateVolumeSnapshotsUpdate(new, old, specPath)...)
+	allErrs = append(allErrs, validateCloneFrom(new, specPath.Child("cloneFrom"))...)
+	allErrs = append(allErrs, validateCloneFromUpdate(new, old, specPath.Child("cloneFrom"))...)
+	allErrs = append(allErrs, validateRollback(new, field.NewPath("metadata", "annotations"))...)
+	allErrs = append(allErrs, validateMaintenanceWindows(new, specPath.Child("maintenanceWindows"))...)
+	return allErrs
+}
+
+func validateNeuronContainer(ins EdgeInterface, path *field.Path) field.ErrorList {
 	neuron := ins.GetNeuron()
 
+	var allErrs field.ErrorList
 	if neuron.Image == "" {
-		return errors.New("neuron container image is empty")
+		allErrs = append(allErrs, field.Required(path.Child("image"),
+			"neuron container image is empty and no default neuron image is configured"))
 	}
+	return allErrs
+}
 
-	return nil
+func validateNeuronConfig(config *NeuronConfig, path *field.Path) field.ErrorList {
+	if config == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	for name := range config.Files {
+		filePath := path.Child("files").Key(name)
+		for _, msg := range validation.IsConfigMapKey(name) {
+			allErrs = append(allErrs, field.Invalid(filePath, name, msg))
+		}
+		if name == NeuronPluginsFile && len(config.Plugins) > 0 {
+			allErrs = append(allErrs, field.Forbidden(filePath, "the file is rendered from "+path.Child("plugins").String()))
+		}
+	}
+	return allErrs
+}
+
+// validateDevices checks that the neuron container can open the devices. Kubernetes has no API for device
+// cgroup rules, a device mounted from a hostPath can only be opened by a privileged container, so a device
+// needs the resource of a device plugin unless the user made the container privileged.
+func validateDevices(devices []Device, privileged bool, path *field.Path) field.ErrorList {
+	var allErrs field.ErrorList
+	containerPaths := map[string]struct{}{}
+	for i, device := range devices {
+		devicePath := path.Index(i)
+		if device.Resource == "" && !privileged {
+			allErrs = append(allErrs, field.Required(devicePath.Child("resource"), "the neuron container can not "+
+				"open a device mounted from a hostPath unless spec.neuron.securityContext.privileged is true, set "+
+				"the resource of a device plugin that exposes the device"))
+		}
+		if (device.VendorID == "") != (device.ProductID == "") {
+			allErrs = append(allErrs, field.Required(devicePath, "vendorID and productID must be set together"))
+		}
+		if device.HostPath == "" {
+			allErrs = append(allErrs, field.Required(devicePath.Child("hostPath"),
+				"hostPath must be set unless the device is requested by vendorID and productID"))
+		} else if !strings.HasPrefix(device.HostPath, "/dev/") {
+			allErrs = append(allErrs, field.Invalid(devicePath.Child("hostPath"), device.HostPath,
+				"device must be under /dev/"))
+		}
+		if _, ok := containerPaths[device.GetContainerPath()]; ok {
+			allErrs = append(allErrs, field.Duplicate(devicePath.Child("containerPath"), device.GetContainerPath()))
+		}
+		containerPaths[device.GetContainerPath()] = struct{}{}
+
+		if device.NodeLabel != "" {
+			key, value, _ := strings.Cut(device.NodeLabel, "=")
+			for _, msg := range validation.IsQualifiedName(key) {
+				allErrs = append(allErrs, field.Invalid(devicePath.Child("nodeLabel"), device.NodeLabel, msg))
+			}
+			for _, msg := range validation.IsValidLabelValue(value) {
+				allErrs = append(allErrs, field.Invalid(devicePath.Child("nodeLabel"), device.NodeLabel, msg))
+			}
+		}
+	}
+	return allErrs
 }
 
-func validateVolumeTemplateCreate(ins EdgeInterface) error {
+func validateEKuiperContainer(ins EdgeInterface, path *field.Path) field.ErrorList {
+	ekuiper := ins.GetEKuiper()
+
+	var allErrs field.ErrorList
+	if ekuiper.Image == "" {
+		allErrs = append(allErrs, field.Required(path.Child("image"),
+			"ekuiper container image is empty and no default ekuiper image is configured"))
+	}
+	for i, env := range ekuiper.Env {
+		if env.Name != "KUIPER__BASIC__RESTPORT" || env.ValueFrom != nil {
+			continue
+		}
+		port, err := strconv.Atoi(env.Value)
+		if err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("env").Index(i).Child("value"), env.Value,
+				"ekuiper rest port must be numeric"))
+			continue
+		}
+		for _, msg := range validation.IsValidPortNum(port) {
+			allErrs = append(allErrs, field.Invalid(path.Child("env").Index(i).Child("value"), env.Value, msg))
+		}
+	}
+	return allErrs
+}
+
+// validateEKuiperConfig checks the settings of the typed ekuiper config that depend on each other
+func validateEKuiperConfig(config *EKuiperConfig, path *field.Path) field.ErrorList {
+	if config == nil {
+		return nil
+	}
+
+	var allErrs field.ErrorList
+	if s := config.Rule; s != nil && s.RestartStrategy != nil {
+		strategy := s.RestartStrategy
+		if strategy.Delay != nil && strategy.MaxDelay != nil && *strategy.MaxDelay < *strategy.Delay {
+			allErrs = append(allErrs, field.Invalid(path.Child("rule", "restartStrategy", "maxDelay"), *strategy.MaxDelay,
+				"max delay must not be less than delay"))
+		}
+	}
+	if store := config.Store; store != nil {
+		storePath := path.Child("store")
+		switch store.Type {
+		case EKuiperStoreRedis:
+			if store.Redis == nil || store.Redis.Host == "" {
+				allErrs = append(allErrs, field.Required(storePath.Child("redis", "host"),
+					"redis host is required by the redis store"))
+			}
+			if store.Sqlite != nil {
+				allErrs = append(allErrs, field.Forbidden(storePath.Child("sqlite"), "store type is redis"))
+			}
+		default:
+			if store.Redis != nil {
+				allErrs = append(allErrs, field.Forbidden(storePath.Child("redis"),
+					"redis can only be set with the redis store type"))
+			}
+		}
+		if r := store.Redis; r != nil && r.PasswordSecretRef != nil {
+			for _, msg := range validation.IsConfigMapKey(r.PasswordSecretRef.Key) {
+				allErrs = append(allErrs, field.Invalid(storePath.Child("redis", "passwordSecretRef", "key"),
+					r.PasswordSecretRef.Key, msg))
+			}
+		}
+	}
+	if basic := config.Basic; basic != nil && basic.Timezone != "" {
+		if _, err := time.LoadLocation(basic.Timezone); err != nil {
+			allErrs = append(allErrs, field.Invalid(path.Child("basic", "timezone"), basic.Timezone, err.Error()))
+		}
+	}
+	return allErrs
+}
+
+// validateHighAvailability checks that the active and standby pods, which run on different nodes, can
+// share the neuron data
+func validateHighAvailability(ins EdgeInterface, specPath *field.Path) field.ErrorList {
+	if ins.GetHighAvailability() == nil || ins.GetVolumeClaimTemplate() == nil {
+		return nil
+	}
+	for _, mode := range ins.GetVolumeClaimTemplate().Spec.AccessModes {
+		if mode == corev1.ReadWriteMany {
+			return nil
+		}
+	}
+	return field.ErrorList{field.Invalid(specPath.Child("volumeClaimTemplate", "spec", "accessModes"),
+		ins.GetVolumeClaimTemplate().Spec.AccessModes,
+		"high availability needs the ReadWriteMany access mode to mount the volume on two nodes")}
+}
+
+// validateContainerPorts checks that the ports of the neuron and ekuiper containers, which share the pod
+// network, are unique by name and by number
+func validateContainerPorts(ins EdgeInterface, specPath *field.Path) field.ErrorList {
+	type portKey struct {
+		port     int32
+		protocol corev1.Protocol
+	}
+	names := map[string]struct{}{}
+	numbers := map[portKey]struct{}{}
+
+	var allErrs field.ErrorList
+	check := func(container *corev1.Container, path *field.Path) {
+		for i, port := range container.Ports {
+			portPath := path.Child("ports").Index(i)
+			if port.Name != "" {
+				if _, ok := names[port.Name]; ok {
+					allErrs = append(allErrs, field.Duplicate(portPath.Child("name"), port.Name))
+				}
+				names[port.Name] = struct{}{}
+			}
+
+			protocol := port.Protocol
+			if protocol == "" {
+				protocol = corev1.ProtocolTCP
+			}
+			key := portKey{port: port.ContainerPort, protocol: protocol}
+			if _, ok := numbers[key]; ok {
+				allErrs = append(allErrs, field.Duplicate(portPath.Child("containerPort"), port.ContainerPort))
+			}
+			numbers[key] = struct{}{}
+		}
+	}
+	if ins.GetNeuron() != nil {
+		check(ins.GetNeuron(), specPath.Child("neuron"))
+	}
+	if ins.GetEKuiper() != nil {
+		check(ins.GetEKuiper(), specPath.Child("ekuiper"))
+	}
+	return allErrs
+}
+
+// validatePodSpec checks the pod fields that conflict with the operator or with running as a Deployment
+func validatePodSpec(ins EdgeInterface, specPath *field.Path) field.ErrorList {
+	spec := ins.GetEdgePodSpec()
+
+	var allErrs field.ErrorList
+	for i, vol := range spec.Volumes {
+		if _, ok := reservedVolumeNames[vol.Name]; ok || strings.HasPrefix(vol.Name, DeviceVolumePrefix) {
+			allErrs = append(allErrs, field.Invalid(specPath.Child("volumes").Index(i).Child("name"), vol.Name,
+				"volume name is reserved by the operator"))
+		}
+	}
+
+	keyNames := map[string]struct{}{}
+	for i, key := range spec.PublicKeys {
+		namePath := specPath.Child("publicKeys").Index(i).Child("name")
+		for _, msg := range validation.IsConfigMapKey(key.Name) {
+			allErrs = append(allErrs, field.Invalid(namePath, key.Name, msg))
+		}
+		if _, ok := keyNames[key.Name]; ok {
+			allErrs = append(allErrs, field.Duplicate(namePath, key.Name))
+		}
+		keyNames[key.Name] = struct{}{}
+	}
+
+	if len(spec.EphemeralContainers) > 0 {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("ephemeralContainers"),
+			"ephemeral containers can only be added to a running pod"))
+	}
+	if spec.ActiveDeadlineSeconds != nil {
+		allErrs = append(allErrs, field.Forbidden(specPath.Child("activeDeadlineSeconds"),
+			"the pod of a Deployment can not have a deadline"))
+	}
+	if spec.RestartPolicy != "" && spec.RestartPolicy != corev1.RestartPolicyAlways {
+		allErrs = append(allErrs, field.NotSupported(specPath.Child("restartPolicy"), spec.RestartPolicy,
+			[]string{string(corev1.RestartPolicyAlways)}))
+	}
+	return allErrs
+}
+
+func validateVolumeTemplateCreate(ins EdgeInterface, path *field.Path) field.ErrorList {
 	vol := ins.GetVolumeClaimTemplate()
 	if vol == nil {
 		return nil
 	}
 
+	var allErrs field.ErrorList
 	if len(vol.Spec.AccessModes) == 0 {
-		return errors.New("volume template access modes is empty")
+		allErrs = append(allErrs, field.Required(path.Child("spec", "accessModes"),
+			"volume template access modes is empty"))
 	}
 	if vol.Spec.Resources.Limits.Storage().IsZero() && vol.Spec.Resources.Requests.Storage().IsZero() {
-		return errors.New("volume template resources storage is empty")
+		allErrs = append(allErrs, field.Required(path.Child("spec", "resources"),
+			"volume template resources storage is empty"))
 	}
-
-	return nil
+	return allErrs
 }
 
-func validateVolumeTemplateUpdate(new, old EdgeInterface) error {
-	if !reflect.DeepEqual(new.GetVolumeClaimTemplate(), old.GetVolumeClaimTemplate()) {
-		return errors.New("volume template can not be updated")
+// validateVolumeTemplateUpdate allows the volume template changes that the operator migrates the data for:
+// from emptyDir volumes to claims, or to the claims of a template with a new name
+func validateVolumeTemplateUpdate(new, old EdgeInterface, path *field.Path) field.ErrorList {
+	newVol, oldVol := new.GetVolumeClaimTemplate(), old.GetVolumeClaimTemplate()
+	if reflect.DeepEqual(newVol, oldVol) {
+		return nil
+	}
+	switch {
+	case newVol == nil:
+		return field.ErrorList{field.Forbidden(path, "the persistent data can not be migrated to emptyDir volumes")}
+	case new.GetAnnotations()[AdoptPVCsAnnotation] != "":
+		return field.ErrorList{field.Forbidden(path, "the adopted PersistentVolumeClaims can not be migrated")}
+	case oldVol != nil && newVol.Name == oldVol.Name:
+		return field.ErrorList{field.Invalid(path.Child("metadata", "name"), newVol.Name,
+			"the volume template needs a new name, the data is migrated to the claims named after it")}
 	}
-	return nil
+	return validateVolumeTemplateCreate(new, path)
+}
+
+func isPrivileged(container *corev1.Container) bool {
+	return container.SecurityContext != nil && container.SecurityContext.Privileged != nil &&
+		*container.SecurityContext.Privileged
 }
@@ -0,0 +1,63 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+
+	configv1alpha1 "github.com/emqx/edge-operator/api/config/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// GetWarnings returns the settings of a valid instance that are allowed but likely a mistake
+func GetWarnings(ins EdgeInterface) []string {
+	specPath := field.NewPath("spec")
+
+	var warnings []string
+	if ins.GetNeuron() != nil {
+		warnings = append(warnings, getContainerWarnings(ins.GetNeuron(), specPath.Child("neuron"))...)
+		if ins.GetVolumeClaimTemplate() == nil {
+			warnings = append(warnings, fmt.Sprintf("%s is not set, the neuron data is stored in an emptyDir "+
+				"and lost when the pod is deleted", specPath.Child("volumeClaimTemplate")))
+		}
+		if ins.GetHighAvailability() != nil && ins.GetVolumeClaimTemplate() == nil {
+			warnings = append(warnings, fmt.Sprintf("%s is set without %s, the standby pod does not share the "+
+				"nodes and tags configured on the active pod", specPath.Child("highAvailability"),
+				specPath.Child("volumeClaimTemplate")))
+		}
+	}
+	if ins.GetEKuiper() != nil {
+		warnings = append(warnings, getContainerWarnings(ins.GetEKuiper(), specPath.Child("ekuiper"))...)
+	}
+
+	spec := ins.GetEdgePodSpec()
+	if spec.HostNetwork && spec.DNSPolicy != corev1.DNSClusterFirstWithHostNet {
+		warnings = append(warnings, fmt.Sprintf("%s is set without %s %s, the pod can not resolve the cluster services",
+			specPath.Child("hostNetwork"), specPath.Child("dnsPolicy"), corev1.DNSClusterFirstWithHostNet))
+	}
+	return warnings
+}
+
+// getUpdateWarnings returns the allowed changes of an instance that lose data
+func getUpdateWarnings(new, old EdgeInterface) []string {
+	var warnings []string
+	if old.GetVolumeClaimTemplate() == nil && new.GetVolumeClaimTemplate() != nil {
+		warnings = append(warnings, fmt.Sprintf("%s is added, the data of the emptyDir volumes can not be copied to "+
+			"the new PersistentVolumeClaims, the pods keep them until %s is set to true",
+			field.NewPath("spec", "volumeClaimTemplate"), field.NewPath("metadata", "annotations").Key(DiscardDataAnnotation)))
+	}
+	return warnings
+}
+
+func getContainerWarnings(container *corev1.Container, path *field.Path) []string {
+	var warnings []string
+	if version := configv1alpha1.ImageVersion(container.Image); version == "" || strings.Contains(version, "latest") {
+		warnings = append(warnings, fmt.Sprintf("%s %s uses the latest tag, pin a version so that a restart "+
+			"does not upgrade the instance", path.Child("image"), container.Image))
+	}
+	if container.Resources.Limits.Cpu().IsZero() || container.Resources.Limits.Memory().IsZero() {
+		warnings = append(warnings, fmt.Sprintf("%s has no cpu or memory limit, the container can starve the edge node",
+			path.Child("resources", "limits")))
+	}
+	return warnings
+}
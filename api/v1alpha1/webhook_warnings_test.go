@@ -0,0 +1,79 @@
+package v1alpha1
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetWarnings(t *testing.T) {
+	limits := corev1.ResourceRequirements{
+		Limits: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("500m"),
+			corev1.ResourceMemory: resource.MustParse("512Mi"),
+		},
+	}
+	ins := &NeuronEX{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuronex"},
+		Spec: NeuronEXSpec{
+			Neuron:              corev1.Container{Image: "emqx/neuron:2.3.0", Resources: limits},
+			EKuiper:             corev1.Container{Image: "lfedge/ekuiper:1.8.0-slim", Resources: limits},
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{},
+		},
+	}
+	assert.Empty(t, GetWarnings(ins))
+
+	t.Run("should warn about the latest tag", func(t *testing.T) {
+		got := ins.DeepCopy()
+		got.Spec.Neuron.Image = "emqx/neuron"
+		got.Spec.EKuiper.Image = "lfedge/ekuiper:latest-slim"
+		assert.Equal(t, []string{
+			"spec.neuron.image emqx/neuron uses the latest tag, pin a version so that a restart does not upgrade the instance",
+			"spec.ekuiper.image lfedge/ekuiper:latest-slim uses the latest tag, pin a version so that a restart does not upgrade the instance",
+		}, GetWarnings(got))
+	})
+
+	t.Run("should warn about emptyDir persistence for neuron", func(t *testing.T) {
+		got := ins.DeepCopy()
+		got.Spec.VolumeClaimTemplate = nil
+		assert.Equal(t, []string{
+			"spec.volumeClaimTemplate is not set, the neuron data is stored in an emptyDir and lost when the pod is deleted",
+		}, GetWarnings(got))
+
+		ekuiper := &EKuiper{Spec: EKuiperSpec{EKuiper: got.Spec.EKuiper}}
+		assert.Empty(t, GetWarnings(ekuiper))
+	})
+
+	t.Run("should warn about hostNetwork without ClusterFirstWithHostNet", func(t *testing.T) {
+		got := ins.DeepCopy()
+		got.Spec.HostNetwork = true
+		assert.Equal(t, []string{
+			"spec.hostNetwork is set without spec.dnsPolicy ClusterFirstWithHostNet, the pod can not resolve the cluster services",
+		}, GetWarnings(got))
+
+		got.Spec.DNSPolicy = corev1.DNSClusterFirstWithHostNet
+		assert.Empty(t, GetWarnings(got))
+	})
+
+	t.Run("should warn about missing resource limits", func(t *testing.T) {
+		got := ins.DeepCopy()
+		delete(got.Spec.EKuiper.Resources.Limits, corev1.ResourceMemory)
+		assert.Equal(t, []string{
+			"spec.ekuiper.resources.limits has no cpu or memory limit, the container can starve the edge node",
+		}, GetWarnings(got))
+	})
+
+	t.Run("should warn about the emptyDir data that is not migrated", func(t *testing.T) {
+		old := ins.DeepCopy()
+		old.Spec.VolumeClaimTemplate = nil
+		assert.Equal(t, []string{
+			"spec.volumeClaimTemplate is added, the data of the emptyDir volumes can not be copied to the new " +
+				"PersistentVolumeClaims, the pods keep them until metadata.annotations[edge.emqx.io/discard-emptydir-data] " +
+				"is set to true",
+		}, getUpdateWarnings(ins, old))
+		assert.Empty(t, getUpdateWarnings(ins, ins))
+	})
+}
@@ -23,16 +23,76 @@ package v1alpha1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloneFrom) DeepCopyInto(out *CloneFrom) {
+	*out = *in
+	if in.NeuronTokenSecretRef != nil {
+		in, out := &in.NeuronTokenSecretRef, &out.NeuronTokenSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Substitutions != nil {
+		in, out := &in.Substitutions, &out.Substitutions
+		*out = make([]Substitution, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloneFrom.
+func (in *CloneFrom) DeepCopy() *CloneFrom {
+	if in == nil {
+		return nil
+	}
+	out := new(CloneFrom)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CloneStatus) DeepCopyInto(out *CloneStatus) {
+	*out = *in
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CloneStatus.
+func (in *CloneStatus) DeepCopy() *CloneStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CloneStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Device) DeepCopyInto(out *Device) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Device.
+func (in *Device) DeepCopy() *Device {
+	if in == nil {
+		return nil
+	}
+	out := new(Device)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EKuiper) DeepCopyInto(out *EKuiper) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiper.
@@ -53,6 +113,101 @@ func (in *EKuiper) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKuiperBasicConfig) DeepCopyInto(out *EKuiperBasicConfig) {
+	*out = *in
+	if in.Debug != nil {
+		in, out := &in.Debug, &out.Debug
+		*out = new(bool)
+		**out = **in
+	}
+	if in.ConsoleLog != nil {
+		in, out := &in.ConsoleLog, &out.ConsoleLog
+		*out = new(bool)
+		**out = **in
+	}
+	if in.FileLog != nil {
+		in, out := &in.FileLog, &out.FileLog
+		*out = new(bool)
+		**out = **in
+	}
+	if in.IgnoreCase != nil {
+		in, out := &in.IgnoreCase, &out.IgnoreCase
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RestPort != nil {
+		in, out := &in.RestPort, &out.RestPort
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Authentication != nil {
+		in, out := &in.Authentication, &out.Authentication
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Prometheus != nil {
+		in, out := &in.Prometheus, &out.Prometheus
+		*out = new(bool)
+		**out = **in
+	}
+	if in.PrometheusPort != nil {
+		in, out := &in.PrometheusPort, &out.PrometheusPort
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperBasicConfig.
+func (in *EKuiperBasicConfig) DeepCopy() *EKuiperBasicConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EKuiperBasicConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKuiperConfig) DeepCopyInto(out *EKuiperConfig) {
+	*out = *in
+	if in.Basic != nil {
+		in, out := &in.Basic, &out.Basic
+		*out = new(EKuiperBasicConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rule != nil {
+		in, out := &in.Rule, &out.Rule
+		*out = new(EKuiperRuleConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Store != nil {
+		in, out := &in.Store, &out.Store
+		*out = new(EKuiperStoreConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Portable != nil {
+		in, out := &in.Portable, &out.Portable
+		*out = new(EKuiperPortableConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sink != nil {
+		in, out := &in.Sink, &out.Sink
+		*out = new(EKuiperSinkConfig)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperConfig.
+func (in *EKuiperConfig) DeepCopy() *EKuiperConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EKuiperConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EKuiperList) DeepCopyInto(out *EKuiperList) {
 	*out = *in
@@ -85,6 +240,166 @@ func (in *EKuiperList) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKuiperPortableConfig) DeepCopyInto(out *EKuiperPortableConfig) {
+	*out = *in
+	if in.InitTimeout != nil {
+		in, out := &in.InitTimeout, &out.InitTimeout
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperPortableConfig.
+func (in *EKuiperPortableConfig) DeepCopy() *EKuiperPortableConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EKuiperPortableConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKuiperRedisConfig) DeepCopyInto(out *EKuiperRedisConfig) {
+	*out = *in
+	if in.Port != nil {
+		in, out := &in.Port, &out.Port
+		*out = new(int32)
+		**out = **in
+	}
+	if in.PasswordSecretRef != nil {
+		in, out := &in.PasswordSecretRef, &out.PasswordSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Timeout != nil {
+		in, out := &in.Timeout, &out.Timeout
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperRedisConfig.
+func (in *EKuiperRedisConfig) DeepCopy() *EKuiperRedisConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EKuiperRedisConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKuiperRestartStrategy) DeepCopyInto(out *EKuiperRestartStrategy) {
+	*out = *in
+	if in.Attempts != nil {
+		in, out := &in.Attempts, &out.Attempts
+		*out = new(int32)
+		**out = **in
+	}
+	if in.Delay != nil {
+		in, out := &in.Delay, &out.Delay
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxDelay != nil {
+		in, out := &in.MaxDelay, &out.MaxDelay
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperRestartStrategy.
+func (in *EKuiperRestartStrategy) DeepCopy() *EKuiperRestartStrategy {
+	if in == nil {
+		return nil
+	}
+	out := new(EKuiperRestartStrategy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKuiperRuleConfig) DeepCopyInto(out *EKuiperRuleConfig) {
+	*out = *in
+	if in.QOS != nil {
+		in, out := &in.QOS, &out.QOS
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CheckpointInterval != nil {
+		in, out := &in.CheckpointInterval, &out.CheckpointInterval
+		*out = new(int32)
+		**out = **in
+	}
+	if in.SendError != nil {
+		in, out := &in.SendError, &out.SendError
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RestartStrategy != nil {
+		in, out := &in.RestartStrategy, &out.RestartStrategy
+		*out = new(EKuiperRestartStrategy)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperRuleConfig.
+func (in *EKuiperRuleConfig) DeepCopy() *EKuiperRuleConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EKuiperRuleConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKuiperSinkConfig) DeepCopyInto(out *EKuiperSinkConfig) {
+	*out = *in
+	if in.EnableCache != nil {
+		in, out := &in.EnableCache, &out.EnableCache
+		*out = new(bool)
+		**out = **in
+	}
+	if in.MemoryCacheThreshold != nil {
+		in, out := &in.MemoryCacheThreshold, &out.MemoryCacheThreshold
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaxDiskCache != nil {
+		in, out := &in.MaxDiskCache, &out.MaxDiskCache
+		*out = new(int32)
+		**out = **in
+	}
+	if in.BufferPageSize != nil {
+		in, out := &in.BufferPageSize, &out.BufferPageSize
+		*out = new(int32)
+		**out = **in
+	}
+	if in.ResendInterval != nil {
+		in, out := &in.ResendInterval, &out.ResendInterval
+		*out = new(int32)
+		**out = **in
+	}
+	if in.CleanCacheAtStop != nil {
+		in, out := &in.CleanCacheAtStop, &out.CleanCacheAtStop
+		*out = new(bool)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperSinkConfig.
+func (in *EKuiperSinkConfig) DeepCopy() *EKuiperSinkConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EKuiperSinkConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EKuiperSpec) DeepCopyInto(out *EKuiperSpec) {
 	*out = *in
@@ -105,6 +420,31 @@ func (in *EKuiperSpec) DeepCopyInto(out *EKuiperSpec) {
 		*out = new(v1.Service)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.EKuiperConfig != nil {
+		in, out := &in.EKuiperConfig, &out.EKuiperConfig
+		*out = new(EKuiperConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VolumeSnapshots != nil {
+		in, out := &in.VolumeSnapshots, &out.VolumeSnapshots
+		*out = new(VolumeSnapshots)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CloneFrom != nil {
+		in, out := &in.CloneFrom, &out.CloneFrom
+		*out = new(CloneFrom)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperSpec.
@@ -117,10 +457,25 @@ func (in *EKuiperSpec) DeepCopy() *EKuiperSpec {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKuiperSqliteConfig) DeepCopyInto(out *EKuiperSqliteConfig) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperSqliteConfig.
+func (in *EKuiperSqliteConfig) DeepCopy() *EKuiperSqliteConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EKuiperSqliteConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EKuiperStatus) DeepCopyInto(out *EKuiperStatus) {
 	*out = *in
-	out.EdgeStatus = in.EdgeStatus
+	in.EdgeStatus.DeepCopyInto(&out.EdgeStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperStatus.
@@ -133,6 +488,46 @@ func (in *EKuiperStatus) DeepCopy() *EKuiperStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EKuiperStoreConfig) DeepCopyInto(out *EKuiperStoreConfig) {
+	*out = *in
+	if in.Redis != nil {
+		in, out := &in.Redis, &out.Redis
+		*out = new(EKuiperRedisConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Sqlite != nil {
+		in, out := &in.Sqlite, &out.Sqlite
+		*out = new(EKuiperSqliteConfig)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EKuiperStoreConfig.
+func (in *EKuiperStoreConfig) DeepCopy() *EKuiperStoreConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(EKuiperStoreConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *EdgeContainerStatus) DeepCopyInto(out *EdgeContainerStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EdgeContainerStatus.
+func (in *EdgeContainerStatus) DeepCopy() *EdgeContainerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(EdgeContainerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EdgePodSpec) DeepCopyInto(out *EdgePodSpec) {
 	*out = *in
@@ -294,6 +689,43 @@ func (in *EdgePodSpec) DeepCopy() *EdgePodSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *EdgeStatus) DeepCopyInto(out *EdgeStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Containers != nil {
+		in, out := &in.Containers, &out.Containers
+		*out = make([]EdgeContainerStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.ImageDigests != nil {
+		in, out := &in.ImageDigests, &out.ImageDigests
+		*out = new(ImageDigests)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.DeploymentSelector != nil {
+		in, out := &in.DeploymentSelector, &out.DeploymentSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VolumeMigration != nil {
+		in, out := &in.VolumeMigration, &out.VolumeMigration
+		*out = new(VolumeMigration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Snapshot != nil {
+		in, out := &in.Snapshot, &out.Snapshot
+		*out = new(SnapshotStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Clone != nil {
+		in, out := &in.Clone, &out.Clone
+		*out = new(CloneStatus)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new EdgeStatus.
@@ -306,13 +738,99 @@ func (in *EdgeStatus) DeepCopy() *EdgeStatus {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HighAvailability) DeepCopyInto(out *HighAvailability) {
+	*out = *in
+	if in.TokenSecretRef != nil {
+		in, out := &in.TokenSecretRef, &out.TokenSecretRef
+		*out = new(v1.SecretKeySelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HighAvailability.
+func (in *HighAvailability) DeepCopy() *HighAvailability {
+	if in == nil {
+		return nil
+	}
+	out := new(HighAvailability)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageDigest) DeepCopyInto(out *ImageDigest) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageDigest.
+func (in *ImageDigest) DeepCopy() *ImageDigest {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageDigest)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ImageDigests) DeepCopyInto(out *ImageDigests) {
+	*out = *in
+	if in.Images != nil {
+		in, out := &in.Images, &out.Images
+		*out = make([]ImageDigest, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ImageDigests.
+func (in *ImageDigests) DeepCopy() *ImageDigests {
+	if in == nil {
+		return nil
+	}
+	out := new(ImageDigests)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MigratedVolume) DeepCopyInto(out *MigratedVolume) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MigratedVolume.
+func (in *MigratedVolume) DeepCopy() *MigratedVolume {
+	if in == nil {
+		return nil
+	}
+	out := new(MigratedVolume)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Neuron) DeepCopyInto(out *Neuron) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Neuron.
@@ -333,13 +851,45 @@ func (in *Neuron) DeepCopyObject() runtime.Object {
 	return nil
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NeuronConfig) DeepCopyInto(out *NeuronConfig) {
+	*out = *in
+	if in.DisableAuth != nil {
+		in, out := &in.DisableAuth, &out.DisableAuth
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Plugins != nil {
+		in, out := &in.Plugins, &out.Plugins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Files != nil {
+		in, out := &in.Files, &out.Files
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeuronConfig.
+func (in *NeuronConfig) DeepCopy() *NeuronConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(NeuronConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NeuronEX) DeepCopyInto(out *NeuronEX) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	in.Spec.DeepCopyInto(&out.Spec)
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeuronEX.
@@ -413,6 +963,41 @@ func (in *NeuronEXSpec) DeepCopyInto(out *NeuronEXSpec) {
 		*out = new(v1.Service)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NeuronConfig != nil {
+		in, out := &in.NeuronConfig, &out.NeuronConfig
+		*out = new(NeuronConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]Device, len(*in))
+		copy(*out, *in)
+	}
+	if in.EKuiperConfig != nil {
+		in, out := &in.EKuiperConfig, &out.EKuiperConfig
+		*out = new(EKuiperConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VolumeSnapshots != nil {
+		in, out := &in.VolumeSnapshots, &out.VolumeSnapshots
+		*out = new(VolumeSnapshots)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CloneFrom != nil {
+		in, out := &in.CloneFrom, &out.CloneFrom
+		*out = new(CloneFrom)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeuronEXSpec.
@@ -428,7 +1013,7 @@ func (in *NeuronEXSpec) DeepCopy() *NeuronEXSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NeuronEXStatus) DeepCopyInto(out *NeuronEXStatus) {
 	*out = *in
-	out.EdgeStatus = in.EdgeStatus
+	in.EdgeStatus.DeepCopyInto(&out.EdgeStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeuronEXStatus.
@@ -493,6 +1078,41 @@ func (in *NeuronSpec) DeepCopyInto(out *NeuronSpec) {
 		*out = new(v1.PersistentVolumeClaimTemplate)
 		(*in).DeepCopyInto(*out)
 	}
+	if in.NeuronConfig != nil {
+		in, out := &in.NeuronConfig, &out.NeuronConfig
+		*out = new(NeuronConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Devices != nil {
+		in, out := &in.Devices, &out.Devices
+		*out = make([]Device, len(*in))
+		copy(*out, *in)
+	}
+	if in.HighAvailability != nil {
+		in, out := &in.HighAvailability, &out.HighAvailability
+		*out = new(HighAvailability)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.VolumeSnapshots != nil {
+		in, out := &in.VolumeSnapshots, &out.VolumeSnapshots
+		*out = new(VolumeSnapshots)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.CloneFrom != nil {
+		in, out := &in.CloneFrom, &out.CloneFrom
+		*out = new(CloneFrom)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.RevisionHistoryLimit != nil {
+		in, out := &in.RevisionHistoryLimit, &out.RevisionHistoryLimit
+		*out = new(int32)
+		**out = **in
+	}
+	if in.MaintenanceWindows != nil {
+		in, out := &in.MaintenanceWindows, &out.MaintenanceWindows
+		*out = make([]MaintenanceWindow, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeuronSpec.
@@ -508,7 +1128,7 @@ func (in *NeuronSpec) DeepCopy() *NeuronSpec {
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NeuronStatus) DeepCopyInto(out *NeuronStatus) {
 	*out = *in
-	out.EdgeStatus = in.EdgeStatus
+	in.EdgeStatus.DeepCopyInto(&out.EdgeStatus)
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NeuronStatus.
@@ -540,3 +1160,119 @@ func (in *PublicKey) DeepCopy() *PublicKey {
 	in.DeepCopyInto(out)
 	return out
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotReference) DeepCopyInto(out *SnapshotReference) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotReference.
+func (in *SnapshotReference) DeepCopy() *SnapshotReference {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotReference)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SnapshotStatus) DeepCopyInto(out *SnapshotStatus) {
+	*out = *in
+	if in.VolumeSnapshots != nil {
+		in, out := &in.VolumeSnapshots, &out.VolumeSnapshots
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SnapshotStatus.
+func (in *SnapshotStatus) DeepCopy() *SnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(SnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Substitution) DeepCopyInto(out *Substitution) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Substitution.
+func (in *Substitution) DeepCopy() *Substitution {
+	if in == nil {
+		return nil
+	}
+	out := new(Substitution)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeMigration) DeepCopyInto(out *VolumeMigration) {
+	*out = *in
+	if in.Volumes != nil {
+		in, out := &in.Volumes, &out.Volumes
+		*out = make([]MigratedVolume, len(*in))
+		copy(*out, *in)
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeMigration.
+func (in *VolumeMigration) DeepCopy() *VolumeMigration {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeMigration)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VolumeSnapshots) DeepCopyInto(out *VolumeSnapshots) {
+	*out = *in
+	if in.VolumeSnapshotClassName != nil {
+		in, out := &in.VolumeSnapshotClassName, &out.VolumeSnapshotClassName
+		*out = new(string)
+		**out = **in
+	}
+	if in.Quiesce != nil {
+		in, out := &in.Quiesce, &out.Quiesce
+		*out = new(bool)
+		**out = **in
+	}
+	if in.RestoreFrom != nil {
+		in, out := &in.RestoreFrom, &out.RestoreFrom
+		*out = new(SnapshotReference)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VolumeSnapshots.
+func (in *VolumeSnapshots) DeepCopy() *VolumeSnapshots {
+	if in == nil {
+		return nil
+	}
+	out := new(VolumeSnapshots)
+	in.DeepCopyInto(out)
+	return out
+}
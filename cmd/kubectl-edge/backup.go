@@ -0,0 +1,149 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/remotecommand"
+)
+
+// dataVolumes are the volumes that hold the persistent data of each component
+var dataVolumes = map[string]string{
+	string(edgev1alpha1.ComponentTypeNeuron):  "neuron-data",
+	string(edgev1alpha1.ComponentTypeEKuiper): "ekuiper-data",
+}
+
+func runBackup(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	kf := bindKubeFlags(fs)
+	component := fs.String("component", "", "The component to back up, neuron or ekuiper, defaults to neuron when the instance runs both.")
+	output := fs.String("o", "", "The file to write the gzipped tar archive to.")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *output == "" {
+		return errors.New("the archive file must be set with -o")
+	}
+	c, err := kf.newClient()
+	if err != nil {
+		return err
+	}
+	ins, err := c.getInstance(ctx, fs.Args())
+	if err != nil {
+		return err
+	}
+	pod, container, dir, err := c.getDataDir(ctx, ins, *component)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Create(*output)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := c.exec(pod, container, []string{"tar", "czf", "-", "-C", dir, "."}, nil, f); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "backed up %s of pod %s to %s\n", dir, pod.Name, *output)
+	return nil
+}
+
+func runRestore(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	kf := bindKubeFlags(fs)
+	component := fs.String("component", "", "The component to restore, neuron or ekuiper, defaults to neuron when the instance runs both.")
+	input := fs.String("i", "", "The gzipped tar archive created by the backup command.")
+	restart := fs.Bool("restart", true, "Delete the pod after the restore so that the component reloads its data.")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *input == "" {
+		return errors.New("the archive file must be set with -i")
+	}
+	c, err := kf.newClient()
+	if err != nil {
+		return err
+	}
+	ins, err := c.getInstance(ctx, fs.Args())
+	if err != nil {
+		return err
+	}
+	if ins.GetVolumeClaimTemplate() == nil && *restart {
+		return errors.New("the instance has no persistent volume, the restored data would be lost on restart, use -restart=false")
+	}
+	pod, container, dir, err := c.getDataDir(ctx, ins, *component)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.Open(*input)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := c.exec(pod, container, []string{"tar", "xzf", "-", "-C", dir}, f, io.Discard); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "restored %s of pod %s from %s\n", dir, pod.Name, *input)
+
+	if *restart {
+		if err := c.Delete(ctx, pod); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "deleted pod %s to reload the data\n", pod.Name)
+	}
+	return nil
+}
+
+// getDataDir returns the active or a ready pod, the container name and the mount path of the data volume of the component
+func (c *edgeClient) getDataDir(ctx context.Context, ins edgev1alpha1.EdgeInterface, component string) (*corev1.Pod,
+	string, string, error) {
+	container, err := getContainer(ins, component)
+	if err != nil {
+		return nil, "", "", err
+	}
+	volume := dataVolumes[string(edgev1alpha1.ComponentTypeNeuron)]
+	if container == ins.GetEKuiper() {
+		volume = dataVolumes[string(edgev1alpha1.ComponentTypeEKuiper)]
+	}
+
+	pod, err := c.getRunningPod(ctx, ins)
+	if err != nil {
+		return nil, "", "", err
+	}
+	for _, ctr := range pod.Spec.Containers {
+		if ctr.Name != container.Name {
+			continue
+		}
+		for _, mount := range ctr.VolumeMounts {
+			if mount.Name == volume {
+				return pod, ctr.Name, mount.MountPath, nil
+			}
+		}
+	}
+	return nil, "", "", fmt.Errorf("container %s of pod %s does not mount %s", container.Name, pod.Name, volume)
+}
+
+func (c *edgeClient) exec(pod *corev1.Pod, container string, command []string, stdin io.Reader, stdout io.Writer) error {
+	req := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("exec").
+		VersionedParams(&corev1.PodExecOptions{
+			Container: container,
+			Command:   command,
+			Stdin:     stdin != nil,
+			Stdout:    true,
+			Stderr:    true,
+		}, clientgoscheme.ParameterCodec)
+	executor, err := remotecommand.NewSPDYExecutor(c.config, http.MethodPost, req.URL())
+	if err != nil {
+		return err
+	}
+	return executor.Stream(remotecommand.StreamOptions{Stdin: stdin, Stdout: stdout, Stderr: os.Stderr})
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var scheme = runtime.NewScheme()
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(edgev1alpha1.AddToScheme(scheme))
+}
+
+// edgeClient bundles the clients needed to operate an instance
+type edgeClient struct {
+	client.Client
+	clientset *kubernetes.Clientset
+	config    *rest.Config
+	namespace string
+}
+
+// kubeFlags are the connection flags shared by all commands
+type kubeFlags struct {
+	kubeconfig string
+	context    string
+	namespace  string
+}
+
+func bindKubeFlags(fs *flag.FlagSet) *kubeFlags {
+	f := &kubeFlags{}
+	fs.StringVar(&f.kubeconfig, "kubeconfig", "", "Path to the kubeconfig file.")
+	fs.StringVar(&f.context, "context", "", "The name of the kubeconfig context to use.")
+	fs.StringVar(&f.namespace, "n", "", "The namespace of the instance, defaults to the namespace of the current context.")
+	return f
+}
+
+func (f *kubeFlags) newClient() (*edgeClient, error) {
+	rules := clientcmd.NewDefaultClientConfigLoadingRules()
+	rules.ExplicitPath = f.kubeconfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: f.context}
+	clientConfig := clientcmd.NewNonInteractiveDeferredLoadingClientConfig(rules, overrides)
+
+	config, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	namespace := f.namespace
+	if namespace == "" {
+		if namespace, _, err = clientConfig.Namespace(); err != nil {
+			return nil, err
+		}
+	}
+
+	c, err := client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		return nil, err
+	}
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return &edgeClient{Client: c, clientset: clientset, config: config, namespace: namespace}, nil
+}
+
+// newInstance returns an empty instance for a "<kind>/<name>" reference
+func newInstance(ref string) (edgev1alpha1.EdgeInterface, error) {
+	kind, name, ok := strings.Cut(ref, "/")
+	if !ok || name == "" {
+		return nil, fmt.Errorf("invalid instance %q, expected <kind>/<name>", ref)
+	}
+
+	var ins edgev1alpha1.EdgeInterface
+	switch strings.ToLower(kind) {
+	case "neuron", "neurons":
+		ins = &edgev1alpha1.Neuron{}
+	case "ekuiper", "ekuipers":
+		ins = &edgev1alpha1.EKuiper{}
+	case "neuronex", "neuronexs", "nex":
+		ins = &edgev1alpha1.NeuronEX{}
+	default:
+		return nil, fmt.Errorf("unknown kind %q, expected one of neuron, ekuiper, neuronex", kind)
+	}
+	ins.SetName(name)
+	return ins, nil
+}
+
+// getInstance fetches the instance referenced by the first positional argument
+func (c *edgeClient) getInstance(ctx context.Context, args []string) (edgev1alpha1.EdgeInterface, error) {
+	if len(args) == 0 {
+		return nil, errors.New("the instance must be given as <kind>/<name>")
+	}
+	ins, err := newInstance(args[0])
+	if err != nil {
+		return nil, err
+	}
+	ins.SetNamespace(c.namespace)
+	if err := c.Get(ctx, client.ObjectKeyFromObject(ins), ins); err != nil {
+		return nil, err
+	}
+	return ins, nil
+}
+
+// getRunningPod returns the active pod of the instance in high availability mode, otherwise a ready pod
+func (c *edgeClient) getRunningPod(ctx context.Context, ins edgev1alpha1.EdgeInterface) (*corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(ins.GetNamespace()), client.MatchingLabels{
+		edgev1alpha1.InstanceKey:  ins.GetName(),
+		edgev1alpha1.ComponentKey: string(ins.GetComponentType()),
+	}); err != nil {
+		return nil, err
+	}
+	return selectPod(ins, pods.Items)
+}
+
+// selectPod returns the running active pod, or the first running and ready pod when there is no active one
+func selectPod(ins edgev1alpha1.EdgeInterface, pods []corev1.Pod) (*corev1.Pod, error) {
+	running := func(pod *corev1.Pod) bool {
+		return pod.Status.Phase == corev1.PodRunning && pod.DeletionTimestamp == nil
+	}
+	if active := ins.GetStatus().ActivePod; active != "" {
+		for i := range pods {
+			if pods[i].Name == active && running(&pods[i]) {
+				return &pods[i], nil
+			}
+		}
+	}
+	for i := range pods {
+		if running(&pods[i]) && isPodReady(&pods[i]) {
+			return &pods[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no ready pod found for %s", ins.GetName())
+}
+
+func isPodReady(pod *corev1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodReady {
+			return condition.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// getContainer returns the neuron or ekuiper container of the instance, the component defaults
+// to neuron when the instance runs both
+func getContainer(ins edgev1alpha1.EdgeInterface, component string) (*corev1.Container, error) {
+	switch component {
+	case "":
+		if ins.GetNeuron() != nil {
+			return ins.GetNeuron(), nil
+		}
+		return ins.GetEKuiper(), nil
+	case string(edgev1alpha1.ComponentTypeNeuron):
+		if ins.GetNeuron() != nil {
+			return ins.GetNeuron(), nil
+		}
+	case string(edgev1alpha1.ComponentTypeEKuiper):
+		if ins.GetEKuiper() != nil {
+			return ins.GetEKuiper(), nil
+		}
+	}
+	return nil, fmt.Errorf("%s has no %s component", ins.GetName(), component)
+}
+
+// parseFlags parses the flags of a command, allowing the flags to follow the positional arguments
+// as in "kubectl edge logs neuron/foo -f"
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return err
+		}
+		if fs.NArg() == 0 {
+			break
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+	return fs.Parse(append([]string{"--"}, positional...))
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"k8s.io/client-go/tools/portforward"
+	"k8s.io/client-go/transport/spdy"
+)
+
+func runDashboard(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	kf := bindKubeFlags(fs)
+	address := fs.String("address", "localhost", "The comma separated addresses to listen on.")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	c, err := kf.newClient()
+	if err != nil {
+		return err
+	}
+	ins, err := c.getInstance(ctx, fs.Args())
+	if err != nil {
+		return err
+	}
+	ports := getDashboardPorts(ins)
+	if len(ports) == 0 {
+		return fmt.Errorf("%s exposes no dashboard port", ins.GetName())
+	}
+	pod, err := c.getRunningPod(ctx, ins)
+	if err != nil {
+		return err
+	}
+
+	transport, upgrader, err := spdy.RoundTripperFor(c.config)
+	if err != nil {
+		return err
+	}
+	url := c.clientset.CoreV1().RESTClient().Post().
+		Resource("pods").Namespace(pod.Namespace).Name(pod.Name).SubResource("portforward").URL()
+	dialer := spdy.NewDialer(upgrader, &http.Client{Transport: transport}, http.MethodPost, url)
+
+	stopChan := make(chan struct{})
+	go func() {
+		<-ctx.Done()
+		close(stopChan)
+	}()
+	fw, err := portforward.NewOnAddresses(dialer, strings.Split(*address, ","), ports, stopChan, nil, os.Stdout, os.Stderr)
+	if err != nil {
+		return err
+	}
+	return fw.ForwardPorts()
+}
+
+// getDashboardPorts returns the port forward specs of the neuron and ekuiper web ports
+func getDashboardPorts(ins edgev1alpha1.EdgeInterface) []string {
+	var ports []string
+	if ins.GetNeuron() != nil {
+		port := ins.GetNeuronPort()
+		ports = append(ports, fmt.Sprintf("%d:%d", port, port))
+	}
+	if ekuiper := ins.GetEKuiper(); ekuiper != nil {
+		for _, port := range ekuiper.Ports {
+			if port.Name == string(edgev1alpha1.ComponentTypeEKuiper) {
+				ports = append(ports, fmt.Sprintf("%d:%d", port.ContainerPort, port.ContainerPort))
+			}
+		}
+	}
+	return ports
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"io"
+	"os"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+func runLogs(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	kf := bindKubeFlags(fs)
+	component := fs.String("component", "", "The component to print the logs of, neuron or ekuiper, defaults to neuron when the instance runs both.")
+	follow := fs.Bool("f", false, "Stream the logs.")
+	tail := fs.Int64("tail", -1, "The number of lines from the end of the logs to show, -1 shows all lines.")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	c, err := kf.newClient()
+	if err != nil {
+		return err
+	}
+	ins, err := c.getInstance(ctx, fs.Args())
+	if err != nil {
+		return err
+	}
+	container, err := getContainer(ins, *component)
+	if err != nil {
+		return err
+	}
+	pod, err := c.getRunningPod(ctx, ins)
+	if err != nil {
+		return err
+	}
+
+	opts := &corev1.PodLogOptions{Container: container.Name, Follow: *follow}
+	if *tail >= 0 {
+		opts.TailLines = tail
+	}
+	stream, err := c.clientset.CoreV1().Pods(pod.Namespace).GetLogs(pod.Name, opts).Stream(ctx)
+	if err != nil {
+		return err
+	}
+	defer stream.Close()
+
+	_, err = io.Copy(os.Stdout, stream)
+	return err
+}
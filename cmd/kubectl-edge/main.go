@@ -0,0 +1,77 @@
+/*
+Copyright 2022.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// kubectl-edge is a kubectl plugin for the day-2 operations of Neuron, EKuiper and NeuronEX instances.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"syscall"
+)
+
+type command struct {
+	usage string
+	run   func(ctx context.Context, fs *flag.FlagSet, args []string) error
+}
+
+var commands = map[string]command{
+	"status":    {usage: "status <kind>/<name>\tShow the phase, conditions and owned resources of an instance", run: runStatus},
+	"logs":      {usage: "logs <kind>/<name>\tPrint the logs of the neuron or ekuiper container", run: runLogs},
+	"dashboard": {usage: "dashboard <kind>/<name>\tForward the neuron and ekuiper dashboards to localhost", run: runDashboard},
+	"token":     {usage: "token <kind>/<name>\tMint a JWT signed by a private key matching one of the instance public keys", run: runToken},
+	"backup":    {usage: "backup <kind>/<name>\tArchive the persistence directory of a component to a local file", run: runBackup},
+	"restore":   {usage: "restore <kind>/<name>\tRestore the persistence directory of a component from a local archive", run: runRestore},
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "Usage: kubectl edge <command> <kind>/<name> [flags]")
+	fmt.Fprintln(os.Stderr, "\nKinds: neuron, ekuiper, neuronex (nex)")
+	fmt.Fprintln(os.Stderr, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintln(os.Stderr, "  "+commands[name].usage)
+	}
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(1)
+	}
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		usage()
+		os.Exit(1)
+	}
+
+	ctx, cancel := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer cancel()
+
+	fs := flag.NewFlagSet("kubectl edge "+os.Args[1], flag.ExitOnError)
+	if err := cmd.run(ctx, fs, os.Args[2:]); err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func runStatus(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	kf := bindKubeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	c, err := kf.newClient()
+	if err != nil {
+		return err
+	}
+	ins, err := c.getInstance(ctx, fs.Args())
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	status := ins.GetStatus()
+	fmt.Fprintf(w, "Name:\t%s\n", ins.GetName())
+	fmt.Fprintf(w, "Namespace:\t%s\n", ins.GetNamespace())
+	fmt.Fprintf(w, "Component:\t%s\n", ins.GetComponentType())
+	fmt.Fprintf(w, "Phase:\t%s\n", status.Phase)
+
+	if len(status.Conditions) > 0 {
+		fmt.Fprintln(w, "\nCONDITION\tSTATUS\tREASON\tAGE\tMESSAGE")
+		for _, cond := range status.Conditions {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", cond.Type, cond.Status, cond.Reason, age(cond.LastTransitionTime.Time), cond.Message)
+		}
+	}
+
+	if len(status.Containers) > 0 {
+		fmt.Fprintln(w, "\nCONTAINER\tPOD\tREADY\tRESTARTS\tWAITING\tLAST TERMINATION\tMESSAGE")
+		for _, cs := range status.Containers {
+			fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%s\t%s\t%s\n", cs.Name, cs.Pod, cs.Ready, cs.RestartCount,
+				cs.WaitingReason, cs.LastTerminationReason, cs.Message)
+		}
+	}
+
+	resources, err := c.getOwnedResources(ctx, ins)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, "\nRESOURCE\tSTATUS")
+	for _, res := range resources {
+		fmt.Fprintf(w, "%s\t%s\n", res[0], res[1])
+	}
+	return w.Flush()
+}
+
+// getOwnedResources returns the "Kind/name" and a short status of every resource the operator created
+// for the instance
+func (c *edgeClient) getOwnedResources(ctx context.Context, ins edgev1alpha1.EdgeInterface) ([][2]string, error) {
+	var resources [][2]string
+	ownedBy := func(obj client.Object) bool {
+		for _, ref := range obj.GetOwnerReferences() {
+			if ref.UID == ins.GetUID() {
+				return true
+			}
+		}
+		return false
+	}
+	inNamespace := client.InNamespace(ins.GetNamespace())
+
+	deploys := &appsv1.DeploymentList{}
+	if err := c.List(ctx, deploys, inNamespace); err != nil {
+		return nil, err
+	}
+	for i := range deploys.Items {
+		if deploy := &deploys.Items[i]; ownedBy(deploy) {
+			resources = append(resources, [2]string{"Deployment/" + deploy.Name,
+				fmt.Sprintf("%d/%d ready", deploy.Status.ReadyReplicas, deploy.Status.Replicas)})
+		}
+	}
+
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, inNamespace, client.MatchingLabels{
+		edgev1alpha1.InstanceKey:  ins.GetName(),
+		edgev1alpha1.ComponentKey: string(ins.GetComponentType()),
+	}); err != nil {
+		return nil, err
+	}
+	for _, pod := range pods.Items {
+		resources = append(resources, [2]string{"Pod/" + pod.Name, string(pod.Status.Phase)})
+	}
+
+	services := &corev1.ServiceList{}
+	if err := c.List(ctx, services, inNamespace); err != nil {
+		return nil, err
+	}
+	for i := range services.Items {
+		if svc := &services.Items[i]; ownedBy(svc) {
+			resources = append(resources, [2]string{"Service/" + svc.Name, string(svc.Spec.Type)})
+		}
+	}
+
+	secrets := &corev1.SecretList{}
+	if err := c.List(ctx, secrets, inNamespace); err != nil {
+		return nil, err
+	}
+	for i := range secrets.Items {
+		if secret := &secrets.Items[i]; ownedBy(secret) {
+			resources = append(resources, [2]string{"Secret/" + secret.Name, fmt.Sprintf("%d keys", len(secret.Data))})
+		}
+	}
+
+	configMaps := &corev1.ConfigMapList{}
+	if err := c.List(ctx, configMaps, inNamespace); err != nil {
+		return nil, err
+	}
+	for i := range configMaps.Items {
+		if cm := &configMaps.Items[i]; ownedBy(cm) {
+			resources = append(resources, [2]string{"ConfigMap/" + cm.Name, fmt.Sprintf("%d keys", len(cm.Data))})
+		}
+	}
+
+	// PVCs are not owned by the instance so that data survives its deletion
+	pvcs := &corev1.PersistentVolumeClaimList{}
+	if err := c.List(ctx, pvcs, inNamespace, client.MatchingLabels{edgev1alpha1.InstanceKey: ins.GetName()}); err != nil {
+		return nil, err
+	}
+	for _, pvc := range pvcs.Items {
+		resources = append(resources, [2]string{"PersistentVolumeClaim/" + pvc.Name, string(pvc.Status.Phase)})
+	}
+	return resources, nil
+}
+
+func age(t time.Time) string {
+	if t.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(t))
+}
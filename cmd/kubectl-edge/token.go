@@ -0,0 +1,123 @@
+package main
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/golang-jwt/jwt/v4"
+)
+
+func runToken(ctx context.Context, fs *flag.FlagSet, args []string) error {
+	kf := bindKubeFlags(fs)
+	keyFile := fs.String("key", "", "The PEM encoded RSA private key to sign the token with.")
+	issuer := fs.String("issuer", "", "The name of the public key in spec.publicKeys that verifies the token, defaults to the first one.")
+	audience := fs.String("audience", "", "The audience of the token, defaults to neuron or eKuiper depending on the component.")
+	component := fs.String("component", "", "The component the token is minted for, neuron or ekuiper, defaults to neuron when the instance runs both.")
+	expire := fs.Duration("expire", time.Hour, "The lifetime of the token.")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *keyFile == "" {
+		return errors.New("the private key must be set with -key")
+	}
+	keyPEM, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	c, err := kf.newClient()
+	if err != nil {
+		return err
+	}
+	ins, err := c.getInstance(ctx, fs.Args())
+	if err != nil {
+		return err
+	}
+	container, err := getContainer(ins, *component)
+	if err != nil {
+		return err
+	}
+	if *audience == "" {
+		*audience = "neuron"
+		if container == ins.GetEKuiper() {
+			*audience = "eKuiper"
+		}
+	}
+
+	publicKey, err := findPublicKey(ins, *issuer)
+	if err != nil {
+		return err
+	}
+	token, err := mintToken(keyPEM, publicKey, *audience, *expire, time.Now())
+	if err != nil {
+		return err
+	}
+	fmt.Println(token)
+	return nil
+}
+
+// findPublicKey returns the public key with the given name, or the first one if the name is empty
+func findPublicKey(ins edgev1alpha1.EdgeInterface, name string) (edgev1alpha1.PublicKey, error) {
+	keys := ins.GetEdgePodSpec().PublicKeys
+	if len(keys) == 0 {
+		return edgev1alpha1.PublicKey{}, fmt.Errorf("%s has no public keys", ins.GetName())
+	}
+	if name == "" {
+		return keys[0], nil
+	}
+	for _, key := range keys {
+		if key.Name == name {
+			return key, nil
+		}
+	}
+	return edgev1alpha1.PublicKey{}, fmt.Errorf("%s has no public key named %s", ins.GetName(), name)
+}
+
+// mintToken signs a RS256 token with the private key, after checking that the key pairs with the
+// public key mounted into the instance, the issuer is the name of the public key as it is the file
+// name that neuron and ekuiper look up to verify the token
+func mintToken(privateKeyPEM []byte, publicKey edgev1alpha1.PublicKey, audience string, expire time.Duration,
+	now time.Time) (string, error) {
+	privateKey, err := jwt.ParseRSAPrivateKeyFromPEM(privateKeyPEM)
+	if err != nil {
+		return "", err
+	}
+	if err := checkKeyPair(privateKey, publicKey.Data); err != nil {
+		return "", fmt.Errorf("private key does not match public key %s: %w", publicKey.Name, err)
+	}
+
+	claims := jwt.RegisteredClaims{
+		Issuer:    publicKey.Name,
+		Audience:  jwt.ClaimStrings{audience},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(expire)),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(privateKey)
+}
+
+func checkKeyPair(privateKey *rsa.PrivateKey, publicKeyPEM []byte) error {
+	block, _ := pem.Decode(publicKeyPEM)
+	if block == nil {
+		return errors.New("public key is not PEM encoded")
+	}
+	want, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	if err != nil {
+		return err
+	}
+	if string(block.Bytes) != string(want) {
+		// keys generated by "openssl rsa -RSAPublicKey_out" are PKCS1 encoded
+		pkcs1 := x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)
+		if string(block.Bytes) != string(pkcs1) {
+			return errors.New("keys are not a pair")
+		}
+	}
+	return nil
+}
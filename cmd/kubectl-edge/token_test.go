@@ -0,0 +1,115 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"flag"
+	"testing"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNewInstance(t *testing.T) {
+	ins, err := newInstance("nex/foo")
+	assert.Nil(t, err)
+	assert.IsType(t, &edgev1alpha1.NeuronEX{}, ins)
+	assert.Equal(t, "foo", ins.GetName())
+
+	ins, err = newInstance("EKuiper/bar")
+	assert.Nil(t, err)
+	assert.IsType(t, &edgev1alpha1.EKuiper{}, ins)
+
+	_, err = newInstance("neuron")
+	assert.NotNil(t, err)
+	_, err = newInstance("deployment/foo")
+	assert.NotNil(t, err)
+}
+
+func TestSelectPod(t *testing.T) {
+	pod := func(name string, ready corev1.ConditionStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				Phase:      corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: ready}},
+			},
+		}
+	}
+	ins := &edgev1alpha1.Neuron{ObjectMeta: metav1.ObjectMeta{Name: "neuron"}}
+	pods := []corev1.Pod{pod("neuron-a", corev1.ConditionFalse), pod("neuron-b", corev1.ConditionTrue)}
+
+	got, err := selectPod(ins, pods)
+	assert.Nil(t, err)
+	assert.Equal(t, "neuron-b", got.Name)
+
+	// the active pod of high availability mode runs the southbound nodes, though the standby is ready as well
+	pods[0].Status.Conditions[0].Status = corev1.ConditionTrue
+	ins.Status.ActivePod = "neuron-b"
+	got, err = selectPod(ins, pods)
+	assert.Nil(t, err)
+	assert.Equal(t, "neuron-b", got.Name)
+
+	_, err = selectPod(ins, []corev1.Pod{pod("neuron-a", corev1.ConditionFalse)})
+	assert.EqualError(t, err, "no ready pod found for neuron")
+}
+
+func TestParseFlags(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	follow := fs.Bool("f", false, "")
+	tail := fs.Int64("tail", -1, "")
+	assert.Nil(t, parseFlags(fs, []string{"neuron/foo", "-f", "-tail", "10"}))
+	assert.True(t, *follow)
+	assert.Equal(t, int64(10), *tail)
+	assert.Equal(t, []string{"neuron/foo"}, fs.Args())
+}
+
+func TestMintToken(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	privateKeyPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(privateKey)})
+	pkix, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	assert.Nil(t, err)
+	publicKey := edgev1alpha1.PublicKey{
+		Name: "admin.pem",
+		Data: pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pkix}),
+	}
+	now := time.Now()
+
+	t.Run("should sign a token verified by the public key", func(t *testing.T) {
+		token, err := mintToken(privateKeyPEM, publicKey, "neuron", time.Hour, now)
+		assert.Nil(t, err)
+
+		claims := &jwt.RegisteredClaims{}
+		_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) {
+			return jwt.ParseRSAPublicKeyFromPEM(publicKey.Data)
+		})
+		assert.Nil(t, err)
+		assert.Equal(t, "admin.pem", claims.Issuer)
+		assert.True(t, claims.VerifyAudience("neuron", true))
+		assert.Equal(t, now.Add(time.Hour).Unix(), claims.ExpiresAt.Unix())
+	})
+
+	t.Run("should accept a PKCS1 public key", func(t *testing.T) {
+		pkcs1 := edgev1alpha1.PublicKey{
+			Name: "admin.pem",
+			Data: pem.EncodeToMemory(&pem.Block{Type: "RSA PUBLIC KEY", Bytes: x509.MarshalPKCS1PublicKey(&privateKey.PublicKey)}),
+		}
+		_, err := mintToken(privateKeyPEM, pkcs1, "neuron", time.Hour, now)
+		assert.Nil(t, err)
+	})
+
+	t.Run("should reject a private key not matching the public key", func(t *testing.T) {
+		otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+		assert.Nil(t, err)
+		otherPEM := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(otherKey)})
+		_, err = mintToken(otherPEM, publicKey, "neuron", time.Hour, now)
+		assert.ErrorContains(t, err, "does not match public key admin.pem")
+	})
+}
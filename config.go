@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	configv1alpha1 "github.com/emqx/edge-operator/api/config/v1alpha1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+// loadOperatorConfig reads the operator configuration file, an empty path returns the default configuration
+func loadOperatorConfig(path string) (*configv1alpha1.OperatorConfig, error) {
+	config := &configv1alpha1.OperatorConfig{}
+	if path == "" {
+		return config, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := runtime.DecodeInto(serializer.NewCodecFactory(scheme).UniversalDecoder(), data, config); err != nil {
+		return nil, fmt.Errorf("could not decode operator config %s: %w", path, err)
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid operator config %s: %w", path, err)
+	}
+	return config, nil
+}
+
+// managerFlags are the command-line flags of the manager options that the config file sets too, set holds
+// the names of the flags given on the command line
+type managerFlags struct {
+	metricsAddr          string
+	probeAddr            string
+	enableLeaderElection bool
+	set                  map[string]bool
+}
+
+// getManagerOptions applies the manager options of the config file, then the flags given on the command
+// line, which override them. The defaults of the flags and of the scaffold fill the options that neither sets.
+func getManagerOptions(options ctrl.Options, config *configv1alpha1.OperatorConfig, flags managerFlags) (ctrl.Options, error) {
+	options, err := options.AndFrom(config)
+	if err != nil {
+		return options, err
+	}
+	if flags.set["metrics-bind-address"] || options.MetricsBindAddress == "" {
+		options.MetricsBindAddress = flags.metricsAddr
+	}
+	if flags.set["health-probe-bind-address"] || options.HealthProbeBindAddress == "" {
+		options.HealthProbeBindAddress = flags.probeAddr
+	}
+	if flags.set["leader-elect"] {
+		options.LeaderElection = flags.enableLeaderElection
+	}
+	if options.LeaderElectionID == "" {
+		options.LeaderElectionID = "e9df4402.edge.emqx.io"
+	}
+	if options.Port == 0 {
+		options.Port = 9443
+	}
+	return options, nil
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	ctrl "sigs.k8s.io/controller-runtime"
+)
+
+func TestGetManagerOptions(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	assert.Nil(t, os.WriteFile(path, []byte(`apiVersion: config.edge.emqx.io/v1alpha1
+kind: OperatorConfig
+metrics:
+  bindAddress: :9090
+health:
+  healthProbeBindAddress: :9091
+leaderElection:
+  leaderElect: true
+  resourceName: edge-operator
+webhook:
+  port: 9444
+`), 0600))
+	config, err := loadOperatorConfig(path)
+	assert.Nil(t, err)
+	flags := managerFlags{metricsAddr: ":8080", probeAddr: ":8081", set: map[string]bool{}}
+
+	// the settings of the file are not replaced by the defaults of the flags
+	options, err := getManagerOptions(ctrl.Options{Scheme: scheme}, config, flags)
+	assert.Nil(t, err)
+	assert.Equal(t, ":9090", options.MetricsBindAddress)
+	assert.Equal(t, ":9091", options.HealthProbeBindAddress)
+	assert.True(t, options.LeaderElection)
+	assert.Equal(t, "edge-operator", options.LeaderElectionID)
+	assert.Equal(t, 9444, options.Port)
+
+	// the flags given on the command line override the file
+	flags.metricsAddr = ":7070"
+	flags.set = map[string]bool{"metrics-bind-address": true, "leader-elect": true}
+	options, err = getManagerOptions(ctrl.Options{Scheme: scheme}, config, flags)
+	assert.Nil(t, err)
+	assert.Equal(t, ":7070", options.MetricsBindAddress)
+	assert.Equal(t, ":9091", options.HealthProbeBindAddress)
+	assert.False(t, options.LeaderElection)
+
+	// without a file the defaults of the flags apply
+	config, err = loadOperatorConfig("")
+	assert.Nil(t, err)
+	options, err = getManagerOptions(ctrl.Options{Scheme: scheme}, config, managerFlags{
+		metricsAddr: ":8080", probeAddr: ":8081", set: map[string]bool{},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, ":8080", options.MetricsBindAddress)
+	assert.Equal(t, ":8081", options.HealthProbeBindAddress)
+	assert.False(t, options.LeaderElection)
+	assert.Equal(t, "e9df4402.edge.emqx.io", options.LeaderElectionID)
+	assert.Equal(t, 9443, options.Port)
+}
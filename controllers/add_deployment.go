@@ -2,8 +2,10 @@ package controllers
 
 import (
 	"context"
+
 	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
 	"github.com/emqx/edge-operator/internal"
+	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
@@ -15,11 +17,7 @@ func (a addEkuiperDeployment) reconcile(ctx context.Context, r *EdgeController,
 	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
 		"add ekuiper Deployment")
 
-	deploy := getDeployment(instance)
-	if err := r.createOrUpdate(ctx, instance, &deploy, logger); err != nil {
-		return &requeue{curError: err}
-	}
-	return nil
+	return addDeployment(ctx, r, instance, logger)
 }
 
 type addNeuronDeployment struct{}
@@ -27,11 +25,7 @@ type addNeuronDeployment struct{}
 func (a addNeuronDeployment) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
 	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler", "add Neuron Deployment")
 
-	deploy := getDeployment(instance)
-	if err := r.createOrUpdate(ctx, instance, &deploy, logger); err != nil {
-		return &requeue{curError: err}
-	}
-	return nil
+	return addDeployment(ctx, r, instance, logger)
 }
 
 type addNeuronExDeploy struct{}
@@ -40,26 +34,49 @@ func (a addNeuronExDeploy) reconcile(ctx context.Context, r *EdgeController, ins
 	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
 		"add NeuronEx Deploy")
 
-	deploy := getDeployment(instance)
-	if err := r.createOrUpdate(ctx, instance, &deploy, logger); err != nil {
+	return addDeployment(ctx, r, instance, logger)
+}
+
+// addDeployment creates or updates the Deployment of the instance. Outside of the maintenance windows the
+// pod template of the running pods is kept.
+func addDeployment(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	referenced, err := getReferencedHashes(ctx, r.Client, ins)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+	deploy := getDeployment(ins, referenced)
+	held, next, err := holdPodTemplate(ctx, r, ins, &deploy, logger)
+	if err != nil {
 		return &requeue{curError: err}
 	}
-	return nil
+	if err := r.createOrUpdate(ctx, ins, &deploy, logger); err != nil {
+		return &requeue{curError: err}
+	}
+	return updatePendingRestart(ctx, r, ins, held, next, logger)
 }
 
-func getDeployment(instance edgev1alpha1.EdgeInterface) appsv1.Deployment {
-	podTemp := getPodTemplate(instance)
+func getDeployment(instance edgev1alpha1.EdgeInterface, referenced map[string]string) appsv1.Deployment {
+	podTemp := getPodTemplate(instance, referenced)
+
+	// in high availability mode the standby pod runs next to the active one
+	replicas := instance.GetReplicas()
+	if instance.GetHighAvailability() != nil && (replicas == nil || *replicas > 0) {
+		replicas = &[]int32{2}[0]
+	}
+
+	selector := &metav1.LabelSelector{MatchLabels: podTemp.GetLabels()}
+	if adopted := getAdoptedSelector(instance); adopted != nil {
+		selector = adopted
+	}
 
 	deploy := appsv1.Deployment{
-		ObjectMeta: internal.GetObjectMetadata(instance, instance.GetName()),
+		ObjectMeta: internal.GetObjectMetadata(instance, getDeploymentName(instance)),
 		Spec: appsv1.DeploymentSpec{
-			Replicas: instance.GetReplicas(),
+			Replicas: replicas,
 			Strategy: appsv1.DeploymentStrategy{
 				Type: appsv1.RecreateDeploymentStrategyType,
 			},
-			Selector: &metav1.LabelSelector{
-				MatchLabels: podTemp.GetLabels(),
-			},
+			Selector: selector,
 			Template: podTemp,
 		},
 	}
@@ -68,12 +85,19 @@ func getDeployment(instance edgev1alpha1.EdgeInterface) appsv1.Deployment {
 	return deploy
 }
 
-func getPodTemplate(instance edgev1alpha1.EdgeInterface) corev1.PodTemplateSpec {
+// getPodTemplate returns the pod template of the instance, referenced are the content hashes of the
+// Secrets and ConfigMaps that the user mounts in the pod
+func getPodTemplate(instance edgev1alpha1.EdgeInterface, referenced map[string]string) corev1.PodTemplateSpec {
 	pod := corev1.PodTemplateSpec{
 		ObjectMeta: internal.GetObjectMetadata(instance, ""),
 		Spec:       getPodSpec(instance),
 	}
 
+	// neuron and ekuiper don't reload the mounted keys and config files, a new hash rolls the pod
+	if pod.Annotations == nil {
+		pod.Annotations = map[string]string{}
+	}
+	pod.Annotations[edgev1alpha1.ConfigHashAnnotation] = getConfigHash(instance, referenced)
 	return pod
 }
 
@@ -82,6 +106,11 @@ func getPodSpec(instance edgev1alpha1.EdgeInterface) corev1.PodSpec {
 	edgePodSpec := instance.GetEdgePodSpec()
 	structAssign(podSpec, &edgePodSpec)
 
+	podSpec.InitContainers = append([]corev1.Container(nil), podSpec.InitContainers...)
+	for i := range podSpec.InitContainers {
+		pinImage(instance, &podSpec.InitContainers[i])
+	}
+
 	vols := getVolumeList(instance)
 	for i := range vols {
 		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
@@ -90,6 +119,10 @@ func getPodSpec(instance edgev1alpha1.EdgeInterface) corev1.PodSpec {
 		})
 	}
 
+	if len(instance.GetDevices()) > 0 {
+		setDevicesNodeAffinity(instance.GetDevices(), podSpec)
+	}
+
 	switch instance.GetComponentType() {
 	case edgev1alpha1.ComponentTypeNeuronEx:
 		podSpec.Containers = []corev1.Container{
@@ -107,24 +140,132 @@ func getPodSpec(instance edgev1alpha1.EdgeInterface) corev1.PodSpec {
 	default:
 		panic("Unknown component " + instance.GetComponentType())
 	}
+
+	if instance.GetHighAvailability() != nil {
+		setHighAvailabilityPodSpec(instance, podSpec)
+	}
 	return *podSpec
 }
 
+// setHighAvailabilityPodSpec spreads the active and standby pods on different nodes, holds them in an init
+// container until they are elected active, and only lets the active pod become ready
+func setHighAvailabilityPodSpec(instance edgev1alpha1.EdgeInterface, podSpec *corev1.PodSpec) {
+	// the downward API volume follows the annotation of the running pod, the init container uses the image of
+	// the neuron container that is pulled anyway
+	podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+		Name: activeVolume,
+		VolumeSource: corev1.VolumeSource{
+			DownwardAPI: &corev1.DownwardAPIVolumeSource{
+				Items: []corev1.DownwardAPIVolumeFile{{
+					Path:     "active",
+					FieldRef: &corev1.ObjectFieldSelector{FieldPath: "metadata.annotations['" + edgev1alpha1.ActiveAnnotation + "']"},
+				}},
+			},
+		},
+	})
+	neuron := podSpec.Containers[0]
+	podSpec.InitContainers = append(podSpec.InitContainers, corev1.Container{
+		Name:            waitActiveContainer,
+		Image:           neuron.Image,
+		ImagePullPolicy: neuron.ImagePullPolicy,
+		Command:         []string{"sh", "-c", "until grep -qx true /etc/edge/active; do sleep 1; done"},
+		VolumeMounts:    []corev1.VolumeMount{{Name: activeVolume, MountPath: "/etc/edge", ReadOnly: true}},
+	})
+
+	podSpec.ReadinessGates = append(podSpec.ReadinessGates, corev1.PodReadinessGate{
+		ConditionType: edgev1alpha1.ActiveReadinessGate,
+	})
+
+	// the pods of a node that stops reporting are evicted after the lease instead of five minutes, unless
+	// the user tolerates the taints
+	leaseDuration := int64(instance.GetHighAvailability().GetLeaseDurationSeconds())
+	for _, taint := range []string{corev1.TaintNodeNotReady, corev1.TaintNodeUnreachable} {
+		tolerated := false
+		for _, toleration := range podSpec.Tolerations {
+			if toleration.Key == taint {
+				tolerated = true
+			}
+		}
+		if !tolerated {
+			podSpec.Tolerations = append(append([]corev1.Toleration(nil), podSpec.Tolerations...), corev1.Toleration{
+				Key:               taint,
+				Operator:          corev1.TolerationOpExists,
+				Effect:            corev1.TaintEffectNoExecute,
+				TolerationSeconds: &leaseDuration,
+			})
+		}
+	}
+
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	} else {
+		podSpec.Affinity = podSpec.Affinity.DeepCopy()
+	}
+	if podSpec.Affinity.PodAntiAffinity == nil {
+		podSpec.Affinity.PodAntiAffinity = &corev1.PodAntiAffinity{}
+	}
+	antiAffinity := podSpec.Affinity.PodAntiAffinity
+	antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution = append(
+		antiAffinity.RequiredDuringSchedulingIgnoredDuringExecution, corev1.PodAffinityTerm{
+			LabelSelector: &metav1.LabelSelector{
+				MatchLabels: map[string]string{
+					edgev1alpha1.InstanceKey:  instance.GetName(),
+					edgev1alpha1.ComponentKey: string(instance.GetComponentType()),
+				},
+			},
+			TopologyKey: corev1.LabelHostname,
+		})
+}
+
 func getNeuronContainer(ins edgev1alpha1.EdgeInterface, vols []volumeInfo) corev1.Container {
 	container := ins.GetNeuron().DeepCopy()
+	// the env vars of spec.neuronConfig are not persisted in the spec, so that a removed field is unset
+	setEnvVars(container, ins.GetNeuronConfig().Env())
 	appendVolumeMount(container, mountToNeuron, vols)
+	setDevicesAccess(ins.GetDevices(), container)
+	pinImage(ins, container)
 	return *container
 }
 
 func getEkuiperContainer(ins edgev1alpha1.EdgeInterface, vols []volumeInfo) corev1.Container {
 	container := ins.GetEKuiper().DeepCopy()
+	// the env vars of spec.ekuiperConfig are not persisted in the spec, so that a removed field is unset
+	setEnvVars(container, ins.GetEKuiperConfig().Env())
 	appendVolumeMount(container, mountToEkuiper, vols)
+	pinImage(ins, container)
 	return *container
 }
 
+// getImagePullSecrets returns the image pull secrets of the instance and of the image rewrite policy for the
+// Jobs and the registry requests of the operator, the defaulting webhook adds the latter to the spec
+func getImagePullSecrets(ins edgev1alpha1.EdgeInterface) []corev1.LocalObjectReference {
+	secrets := append([]corev1.LocalObjectReference(nil), ins.GetEdgePodSpec().ImagePullSecrets...)
+	for _, secret := range edgev1alpha1.ImagePullSecrets() {
+		found := false
+		for _, s := range secrets {
+			found = found || s.Name == secret.Name
+		}
+		if !found {
+			secrets = append(secrets, secret)
+		}
+	}
+	return secrets
+}
+
 func appendVolumeMount(container *corev1.Container, mount mountTo, vols []volumeInfo) {
 	for i := range vols {
 		if attr, ok := vols[i].mounts[mount]; ok {
+			for _, subPath := range attr.subPaths {
+				container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
+					Name:      vols[i].name,
+					MountPath: attr.path + "/" + subPath,
+					SubPath:   subPath,
+					ReadOnly:  attr.readOnly,
+				})
+			}
+			if len(attr.subPaths) > 0 || attr.filesOnly {
+				continue
+			}
 			container.VolumeMounts = append(container.VolumeMounts, corev1.VolumeMount{
 				Name:      vols[i].name,
 				MountPath: attr.path,
@@ -133,3 +274,19 @@ func appendVolumeMount(container *corev1.Container, mount mountTo, vols []volume
 		}
 	}
 }
+
+// setEnvVars sets environment variables of a container, replacing the values of existing ones with the same name
+func setEnvVars(container *corev1.Container, env []corev1.EnvVar) {
+	for _, e := range env {
+		found := false
+		for i := range container.Env {
+			if container.Env[i].Name == e.Name {
+				container.Env[i] = e
+				found = true
+			}
+		}
+		if !found {
+			container.Env = append(container.Env, e)
+		}
+	}
+}
@@ -0,0 +1,64 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/emqx/edge-operator/internal"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type addNeuronConfig struct{}
+
+func (a addNeuronConfig) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"add Neuron ConfigMap")
+	return addNeuronConfigMap(ctx, r, instance, logger)
+}
+
+type addNeuronExConfig struct{}
+
+func (a addNeuronExConfig) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.NeuronEX) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"add NeuronEx ConfigMap")
+	return addNeuronConfigMap(ctx, r, instance, logger)
+}
+
+func addNeuronConfigMap(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	if !hasNeuronConfigFiles(ins) {
+		return nil
+	}
+	configMap := getNeuronConfigMap(ins)
+	if err := r.createOrUpdate(ctx, ins, configMap, logger); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
+
+// getNeuronConfigMap returns the files of the neuron config directory rendered from spec.neuronConfig
+func getNeuronConfigMap(ins edgev1alpha1.EdgeInterface) *corev1.ConfigMap {
+	config := ins.GetNeuronConfig()
+	configMap := &corev1.ConfigMap{
+		ObjectMeta: internal.GetObjectMetadata(ins, internal.GetResNameOnPanic(ins, neuronConfig)),
+		Data:       map[string]string{},
+	}
+	configMap.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+
+	for name, content := range config.Files {
+		configMap.Data[name] = content
+	}
+	if len(config.Plugins) > 0 {
+		plugins, _ := json.Marshal(map[string][]string{"plugins": config.Plugins})
+		configMap.Data[edgev1alpha1.NeuronPluginsFile] = string(plugins)
+	}
+	return configMap
+}
+
+// hasNeuronConfigFiles returns whether spec.neuronConfig writes files of the neuron config directory, the
+// log level and the auth setting are env vars only
+func hasNeuronConfigFiles(ins edgev1alpha1.EdgeInterface) bool {
+	config := ins.GetNeuronConfig()
+	return config != nil && (len(config.Files) > 0 || len(config.Plugins) > 0)
+}
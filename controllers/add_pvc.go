@@ -48,16 +48,9 @@ func (a addNeuronExPVC) reconcile(ctx context.Context, r *EdgeController, instan
 }
 
 func addPVC(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
-	vols := getVolumeList(ins)
-	for i := range vols {
-		if vols[i].volumeSource.PersistentVolumeClaim == nil {
-			continue
-		}
-		template := ins.GetVolumeClaimTemplate()
-		pvc := &corev1.PersistentVolumeClaim{
-			ObjectMeta: internal.GetObjectMetadata(template, internal.GetResNameOnPanic(template, vols[i].name)),
-			Spec:       template.Spec,
-		}
+	pvcs := getPVCs(ins)
+	for i := range pvcs {
+		pvc := &pvcs[i]
 
 		existingPVC := &corev1.PersistentVolumeClaim{}
 		err := r.Get(ctx, client.ObjectKeyFromObject(pvc), existingPVC)
@@ -78,3 +71,27 @@ func addPVC(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterfa
 	}
 	return nil
 }
+
+// getPVCs returns the PVCs generated from the volume claim template, one for each persistent volume
+func getPVCs(ins edgev1alpha1.EdgeInterface) []corev1.PersistentVolumeClaim {
+	template := ins.GetVolumeClaimTemplate()
+	if template == nil {
+		return nil
+	}
+
+	var pvcs []corev1.PersistentVolumeClaim
+	vols := getVolumeList(ins)
+	for i := range vols {
+		if vols[i].volumeSource.PersistentVolumeClaim == nil {
+			continue
+		}
+		pvc := corev1.PersistentVolumeClaim{
+			ObjectMeta: internal.GetObjectMetadata(template, vols[i].volumeSource.PersistentVolumeClaim.ClaimName),
+			Spec:       *template.Spec.DeepCopy(),
+		}
+		pvc.Spec.DataSource = getRestoreDataSource(ins, vols[i].name)
+		pvc.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("PersistentVolumeClaim"))
+		pvcs = append(pvcs, pvc)
+	}
+	return pvcs
+}
@@ -16,12 +16,7 @@ func (a addRuleSet) reconcile(ctx context.Context, r *EdgeController, ins *edgev
 	logger := log.WithValues("namespace", ins.Namespace, "instance", ins.Name, "reconciler",
 		"add eKuiper rule set")
 
-	ruleSet := &corev1.ConfigMap{
-		ObjectMeta: internal.GetObjectMetadata(ins, internal.GetResNameOnPanic(ins, ekuiperRuleSet)),
-		Data: map[string]string{
-			"init.json": `{"streams": {"neuronStream": "CREATE STREAM neuronStream () WITH (DATASOURCE=\"users\", FORMAT=\"JSON\")"}}`,
-		},
-	}
+	ruleSet := getRuleSet(ins)
 
 	existingRuleSet := &corev1.ConfigMap{}
 	if err := r.Get(ctx, client.ObjectKeyFromObject(ruleSet), existingRuleSet); err != nil {
@@ -36,3 +31,14 @@ func (a addRuleSet) reconcile(ctx context.Context, r *EdgeController, ins *edgev
 	}
 	return nil
 }
+
+func getRuleSet(ins edgev1alpha1.EdgeInterface) *corev1.ConfigMap {
+	ruleSet := &corev1.ConfigMap{
+		ObjectMeta: internal.GetObjectMetadata(ins, internal.GetResNameOnPanic(ins, ekuiperRuleSet)),
+		Data: map[string]string{
+			"init.json": `{"streams": {"neuronStream": "CREATE STREAM neuronStream () WITH (DATASOURCE=\"users\", FORMAT=\"JSON\")"}}`,
+		},
+	}
+	ruleSet.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
+	return ruleSet
+}
@@ -33,6 +33,14 @@ func (a addNeuronExSecret) reconcile(ctx context.Context, r *EdgeController, ins
 }
 
 func addSecret(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	secret := getSecret(ins)
+	if err := r.createOrUpdate(ctx, ins, &secret, logger); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
+
+func getSecret(ins edgev1alpha1.EdgeInterface) corev1.Secret {
 	secret := corev1.Secret{
 		Type:       corev1.SecretTypeOpaque,
 		ObjectMeta: internal.GetObjectMetadata(ins, internal.GetResNameOnPanic(ins, publicKey)),
@@ -45,8 +53,5 @@ func addSecret(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInte
 		pk := &publicKeys[i]
 		secret.Data[pk.Name] = pk.Data
 	}
-	if err := r.createOrUpdate(ctx, ins, &secret, logger); err != nil {
-		return &requeue{curError: err}
-	}
-	return nil
+	return secret
 }
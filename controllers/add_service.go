@@ -35,14 +35,22 @@ func (a addNeuronExService) reconcile(ctx context.Context, r *EdgeController, in
 }
 
 func addService(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
-	if ins.GetServiceTemplate() == nil {
+	svc := getService(ins)
+	if svc == nil {
 		return nil
 	}
-	svc := ins.GetServiceTemplate().DeepCopy()
-	svc.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
-
 	if err := r.createOrUpdate(ctx, ins, svc, logger); err != nil {
 		return &requeue{curError: err}
 	}
 	return nil
 }
+
+// getService returns the Service of the service template, or nil if the instance has none
+func getService(ins edgev1alpha1.EdgeInterface) *corev1.Service {
+	if ins.GetServiceTemplate() == nil {
+		return nil
+	}
+	svc := ins.GetServiceTemplate().DeepCopy()
+	svc.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
+	return svc
+}
@@ -0,0 +1,357 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	emperror "emperror.dev/errors"
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/emqx/edge-operator/internal"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type adoptEKuiperResources struct{}
+
+func (a adoptEKuiperResources) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.EKuiper) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"adopt eKuiper resources")
+	return adoptResources(ctx, r, instance, logger)
+}
+
+type adoptNeuronResources struct{}
+
+func (a adoptNeuronResources) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"adopt Neuron resources")
+	return adoptResources(ctx, r, instance, logger)
+}
+
+type adoptNeuronEXResources struct{}
+
+func (a adoptNeuronEXResources) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.NeuronEX) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"adopt NeuronEX resources")
+	return adoptResources(ctx, r, instance, logger)
+}
+
+// getDeploymentName returns the name of the adopted Deployment, or the name of the instance
+func getDeploymentName(ins edgev1alpha1.EdgeInterface) string {
+	if name := ins.GetAnnotations()[edgev1alpha1.AdoptDeploymentAnnotation]; name != "" {
+		return name
+	}
+	return ins.GetName()
+}
+
+// getClaimName returns the name of the adopted PersistentVolumeClaim of a persistent volume, or the name
+// generated from the volume claim template
+func getClaimName(ins edgev1alpha1.EdgeInterface, volume string) string {
+	if claims, err := edgev1alpha1.GetAdoptedPVCs(ins); err == nil && claims[volume] != "" {
+		return claims[volume]
+	}
+	return internal.GetResNameOnPanic(ins.GetVolumeClaimTemplate(), volume)
+}
+
+// adoptResources takes over the existing Deployment and Service named by the adoption annotations, once
+// they are checked to be compatible with the generated ones. The adopted PersistentVolumeClaims are only
+// mounted, like the generated ones they are not owned by the instance so that their data outlives it.
+func adoptResources(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	annotations := ins.GetAnnotations()
+	if annotations[edgev1alpha1.AdoptDeploymentAnnotation] == "" && annotations[edgev1alpha1.AdoptServiceAnnotation] == "" {
+		return nil
+	}
+
+	if name := annotations[edgev1alpha1.AdoptDeploymentAnnotation]; name != "" {
+		deploy := &appsv1.Deployment{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: name}, deploy); err != nil {
+			if !k8sErrors.IsNotFound(err) {
+				return &requeue{curError: err}
+			}
+		} else {
+			if !metav1.IsControlledBy(deploy, ins) {
+				if err := checkAdoptedDeployment(ins, deploy); err != nil {
+					return adoptionFailed(ctx, r, ins, "Deployment", name, err)
+				}
+			}
+			// the selector of a Deployment is immutable, the adopted one is kept
+			if req := setAdoptedSelector(ctx, r, ins, deploy.Spec.Selector); req != nil {
+				return req
+			}
+			if !metav1.IsControlledBy(deploy, ins) {
+				deploy.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
+				if err := adopt(ctx, r, ins, deploy, logger); err != nil {
+					return &requeue{curError: err}
+				}
+			}
+		}
+	}
+
+	if svcTemplate := ins.GetServiceTemplate(); svcTemplate != nil && annotations[edgev1alpha1.AdoptServiceAnnotation] != "" {
+		svc := &corev1.Service{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: svcTemplate.Name}, svc); err != nil {
+			if !k8sErrors.IsNotFound(err) {
+				return &requeue{curError: err}
+			}
+		} else if !metav1.IsControlledBy(svc, ins) {
+			if err := checkAdoptedService(ins, svc); err != nil {
+				return adoptionFailed(ctx, r, ins, "Service", svc.Name, err)
+			}
+			svc.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Service"))
+			if err := adopt(ctx, r, ins, svc, logger); err != nil {
+				return &requeue{curError: err}
+			}
+		}
+	}
+	return setAdoptedCondition(ctx, r, ins, metav1.ConditionTrue, "Adopted", "The existing resources are adopted")
+}
+
+// getAdoptedSelector returns the selector of the adopted Deployment, or nil
+func getAdoptedSelector(ins edgev1alpha1.EdgeInterface) *metav1.LabelSelector {
+	if value := ins.GetAnnotations()[edgev1alpha1.AdoptedSelectorAnnotation]; value != "" {
+		selector := &metav1.LabelSelector{}
+		if err := json.Unmarshal([]byte(value), selector); err == nil {
+			return selector
+		}
+	}
+	return ins.GetStatus().DeploymentSelector.DeepCopy()
+}
+
+// setAdoptedSelector records the selector of the adopted Deployment in an annotation of the instance, which
+// is kept when the status is lost, and in its status
+func setAdoptedSelector(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface,
+	selector *metav1.LabelSelector) *requeue {
+	data, err := json.Marshal(selector)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+	if ins.GetAnnotations()[edgev1alpha1.AdoptedSelectorAnnotation] != string(data) {
+		patch := client.MergeFrom(ins.DeepCopyObject().(client.Object))
+		annotations := ins.GetAnnotations()
+		annotations[edgev1alpha1.AdoptedSelectorAnnotation] = string(data)
+		ins.SetAnnotations(annotations)
+		if err := r.Patch(ctx, ins, patch); err != nil {
+			return &requeue{curError: err}
+		}
+	}
+
+	status := ins.GetStatus()
+	if !reflect.DeepEqual(status.DeploymentSelector, selector) {
+		status.DeploymentSelector = selector
+		ins.SetStatus(&status)
+		if err := r.Status().Update(ctx, ins); err != nil {
+			return &requeue{curError: err}
+		}
+	}
+	return nil
+}
+
+func adoptionFailed(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, kind, name string,
+	err error) *requeue {
+	message := fmt.Sprintf("Cannot adopt %s %s: %s", kind, name, err)
+	if req := setAdoptedCondition(ctx, r, ins, metav1.ConditionFalse, "AdoptionFailed", message); req != nil {
+		return req
+	}
+	return &requeue{curError: fmt.Errorf("cannot adopt %s %s: %w", kind, name, err)}
+}
+
+// setAdoptedCondition keeps the Adopted condition in sync, with an event when it changes
+func setAdoptedCondition(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface,
+	conditionStatus metav1.ConditionStatus, reason, message string) *requeue {
+	status := ins.GetStatus()
+	if existing := meta.FindStatusCondition(status.Conditions, edgev1alpha1.ConditionAdopted); existing != nil &&
+		existing.Status == conditionStatus && existing.Message == message {
+		return nil
+	}
+	eventType := corev1.EventTypeNormal
+	if conditionStatus == metav1.ConditionFalse {
+		eventType = corev1.EventTypeWarning
+	}
+	r.Recorder.Event(ins, eventType, reason, message)
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               edgev1alpha1.ConditionAdopted,
+		Status:             conditionStatus,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: ins.GetGeneration(),
+	})
+	ins.SetStatus(&status)
+	if err := r.Status().Update(ctx, ins); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
+
+// checkAdoptedDeployment returns why an existing Deployment cannot be replaced by the generated one: its
+// selector must select the pods of the instance, its containers must be the ones of the instance and the
+// instance must mount all of its claims, so that no data is left behind
+func checkAdoptedDeployment(ins edgev1alpha1.EdgeInterface, deploy *appsv1.Deployment) error {
+	if err := checkController(deploy); err != nil {
+		return err
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(deploy.Spec.Selector)
+	if err != nil {
+		return err
+	}
+	if !selector.Matches(labels.Set(ins.GetLabels())) {
+		return fmt.Errorf("its selector %s does not match the labels of the instance", selector)
+	}
+
+	// a container of another name would run next to the generated ones
+	var names []string
+	containers := map[string]struct{}{}
+	for _, container := range getPodTemplate(ins, nil).Spec.Containers {
+		names = append(names, container.Name)
+		containers[container.Name] = struct{}{}
+	}
+	for _, container := range deploy.Spec.Template.Spec.Containers {
+		if _, ok := containers[container.Name]; !ok {
+			return fmt.Errorf("its container %s is not one of the containers %s of the instance", container.Name,
+				strings.Join(names, ", "))
+		}
+	}
+
+	claims := map[string]struct{}{}
+	for _, vol := range getVolumeList(ins) {
+		if vol.volumeSource.PersistentVolumeClaim != nil {
+			claims[vol.volumeSource.PersistentVolumeClaim.ClaimName] = struct{}{}
+		}
+	}
+	for _, vol := range deploy.Spec.Template.Spec.Volumes {
+		if vol.PersistentVolumeClaim == nil {
+			continue
+		}
+		if _, ok := claims[vol.PersistentVolumeClaim.ClaimName]; !ok {
+			return fmt.Errorf("PersistentVolumeClaim %s is not adopted, add it to the %s annotation",
+				vol.PersistentVolumeClaim.ClaimName, edgev1alpha1.AdoptPVCsAnnotation)
+		}
+	}
+	return nil
+}
+
+// checkAdoptedService returns why an existing Service cannot be replaced by the service template: each of its
+// ports must be a port of the template that targets the same port of the pods, so that its clients keep working
+func checkAdoptedService(ins edgev1alpha1.EdgeInterface, svc *corev1.Service) error {
+	if err := checkController(svc); err != nil {
+		return err
+	}
+
+	podSpec := getPodTemplate(ins, nil).Spec
+	template := ins.GetServiceTemplate()
+	for _, port := range svc.Spec.Ports {
+		var generated *corev1.ServicePort
+		for i := range template.Spec.Ports {
+			if template.Spec.Ports[i].Port == port.Port && getProtocol(template.Spec.Ports[i]) == getProtocol(port) {
+				generated = &template.Spec.Ports[i]
+			}
+		}
+		if generated == nil {
+			return fmt.Errorf("its port %d/%s is not a port of the service template", port.Port, getProtocol(port))
+		}
+		target, generatedTarget := getTargetPort(&podSpec, port), getTargetPort(&podSpec, *generated)
+		if target != generatedTarget {
+			return fmt.Errorf("its port %d targets %s, the service template targets port %d of the pods",
+				port.Port, port.TargetPort.String(), generatedTarget)
+		}
+	}
+	return nil
+}
+
+func getProtocol(port corev1.ServicePort) corev1.Protocol {
+	if port.Protocol == "" {
+		return corev1.ProtocolTCP
+	}
+	return port.Protocol
+}
+
+// getTargetPort returns the container port targeted by a Service port, or 0 if no container has the named port
+func getTargetPort(podSpec *corev1.PodSpec, port corev1.ServicePort) int32 {
+	switch {
+	case port.TargetPort.Type == intstr.String && port.TargetPort.StrVal != "":
+		for _, container := range podSpec.Containers {
+			for _, containerPort := range container.Ports {
+				if containerPort.Name == port.TargetPort.StrVal {
+					return containerPort.ContainerPort
+				}
+			}
+		}
+		return 0
+	case port.TargetPort.Type == intstr.Int && port.TargetPort.IntVal != 0:
+		return port.TargetPort.IntVal
+	}
+	return port.Port
+}
+
+func checkController(obj client.Object) error {
+	if owner := metav1.GetControllerOf(obj); owner != nil {
+		return fmt.Errorf("it is controlled by %s %s", owner.Kind, owner.Name)
+	}
+	return nil
+}
+
+// adopt sets the instance as the controller of an existing object and hands the object over to the operator,
+// so that the next update removes the fields that the operator does not generate. Without server-side apply
+// the object is recorded as the last applied configuration.
+func adopt(ctx context.Context, r *EdgeController, owner, obj client.Object, logger logr.Logger) error {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	patch := client.MergeFrom(obj.DeepCopyObject().(client.Object))
+
+	if r.options.ServerSideApply {
+		if err := takeOverManagedFields(obj); err != nil {
+			return emperror.Wrapf(err, "failed to take over the managed fields of %s %s", kind, obj.GetName())
+		}
+	} else {
+		applied, err := getAppliedConfiguration(obj)
+		if err != nil {
+			return emperror.Wrapf(err, "failed to get the configuration of %s %s", kind, obj.GetName())
+		}
+		if err := r.patcher.SetLastAppliedAnnotation(applied); err != nil {
+			return emperror.Wrapf(err, "failed to set last applied annotation for %s %s", kind, obj.GetName())
+		}
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		annotations[lastAppliedAnnotation] = applied.GetAnnotations()[lastAppliedAnnotation]
+		obj.SetAnnotations(annotations)
+	}
+	if err := ctrl.SetControllerReference(owner, obj, r.Scheme()); err != nil {
+		return emperror.Wrapf(err, "failed to set controller reference for %s %s", kind, obj.GetName())
+	}
+
+	logger.Info("Adopt "+obj.GetName(), "kind", kind)
+	if err := r.Patch(ctx, obj, patch); err != nil {
+		return emperror.Wrapf(err, "failed to adopt %s %s", kind, obj.GetName())
+	}
+	r.Recorder.Eventf(owner, corev1.EventTypeNormal, "Adopted", "Adopted the existing %s %s", kind, obj.GetName())
+	return nil
+}
+
+// getAppliedConfiguration returns the object without its status and the metadata set by the server
+func getAppliedConfiguration(obj client.Object) (*unstructured.Unstructured, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	applied := &unstructured.Unstructured{Object: content}
+	unstructured.RemoveNestedField(applied.Object, "status")
+	for _, field := range []string{"resourceVersion", "uid", "generation", "creationTimestamp", "managedFields",
+		"ownerReferences"} {
+		unstructured.RemoveNestedField(applied.Object, "metadata", field)
+	}
+	return applied, nil
+}
@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestAdoptResources(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "neuron",
+			Namespace: "default",
+			UID:       "uid",
+			Labels:    map[string]string{"app": "neuron", edgev1alpha1.InstanceKey: "neuron"},
+			Annotations: map[string]string{
+				edgev1alpha1.AdoptDeploymentAnnotation: "legacy-neuron",
+				edgev1alpha1.AdoptServiceAnnotation:    "legacy-neuron",
+				edgev1alpha1.AdoptPVCsAnnotation:       "neuron-data=legacy-neuron-data",
+			},
+		},
+		Spec: edgev1alpha1.NeuronSpec{
+			Neuron:              corev1.Container{Name: "neuron", Image: "emqx/neuron:2.3.0"},
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{},
+		},
+	}
+	ins.Default()
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "neuron"}}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy-neuron", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: selector,
+			Template: corev1.PodTemplateSpec{
+				ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"app": "neuron"}},
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "neuron", Image: "emqx/neuron:2.2.0"}},
+					Volumes: []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "legacy-neuron-data"},
+					}}},
+				},
+			},
+		},
+	}
+	svc := &corev1.Service{ObjectMeta: metav1.ObjectMeta{Name: "legacy-neuron", Namespace: "default"}}
+
+	r := newFakeController(t, ins, deploy, svc)
+	assert.Nil(t, adoptResources(context.Background(), r, ins, log))
+
+	assert.Nil(t, r.Get(context.Background(), client.ObjectKeyFromObject(deploy), deploy))
+	assert.True(t, metav1.IsControlledBy(deploy, ins))
+	assert.Contains(t, deploy.Annotations, lastAppliedAnnotation)
+	assert.Nil(t, r.Get(context.Background(), client.ObjectKeyFromObject(svc), svc))
+	assert.True(t, metav1.IsControlledBy(svc, ins))
+	assert.Nil(t, r.Get(context.Background(), client.ObjectKeyFromObject(ins), ins))
+	assert.Equal(t, selector, ins.Status.DeploymentSelector)
+	assert.JSONEq(t, `{"matchLabels": {"app": "neuron"}}`, ins.Annotations[edgev1alpha1.AdoptedSelectorAnnotation])
+	assert.True(t, meta.IsStatusConditionTrue(ins.Status.Conditions, edgev1alpha1.ConditionAdopted))
+
+	// the selector is kept when the status is lost
+	ins.Status = edgev1alpha1.NeuronStatus{}
+	assert.Equal(t, selector, getDeployment(ins, nil).Spec.Selector)
+	assert.Nil(t, adoptResources(context.Background(), r, ins, log))
+	assert.Equal(t, selector, ins.Status.DeploymentSelector)
+
+	// the generated resources replace the adopted ones
+	desired := getDeployment(ins, nil)
+	assert.Equal(t, "legacy-neuron", desired.Name)
+	assert.Equal(t, selector, desired.Spec.Selector)
+	assert.Equal(t, "legacy-neuron-data", desired.Spec.Template.Spec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, "legacy-neuron", ins.GetServiceTemplate().Name)
+	pvcs := getPVCs(ins)
+	assert.Len(t, pvcs, 1)
+	assert.Equal(t, "legacy-neuron-data", pvcs[0].Name)
+}
+
+func TestAdoptResourcesFailed(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "neuron",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "neuron"},
+			Annotations: map[string]string{edgev1alpha1.AdoptDeploymentAnnotation: "legacy-neuron"},
+		},
+		Spec: edgev1alpha1.NeuronSpec{Neuron: corev1.Container{Name: "neuron", Image: "emqx/neuron:2.3.0"}},
+	}
+	ins.Default()
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "legacy-neuron", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "neuron"}},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{Name: "gateway", Image: "emqx/neuron:2.2.0"}},
+			}},
+		},
+	}
+	r := newFakeController(t, ins, deploy)
+
+	req := adoptResources(context.Background(), r, ins, log)
+	assert.NotNil(t, req)
+	assert.NotNil(t, req.curError)
+	condition := meta.FindStatusCondition(ins.Status.Conditions, edgev1alpha1.ConditionAdopted)
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, "AdoptionFailed", condition.Reason)
+	assert.Equal(t, "Cannot adopt Deployment legacy-neuron: its container gateway is not one of the containers "+
+		"neuron of the instance", condition.Message)
+	assert.Nil(t, r.Get(context.Background(), client.ObjectKeyFromObject(deploy), deploy))
+	assert.Empty(t, deploy.OwnerReferences)
+	assert.NotContains(t, ins.Annotations, edgev1alpha1.AdoptedSelectorAnnotation)
+}
+
+func TestCheckAdoptedService(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "neuron",
+			Namespace:   "default",
+			Annotations: map[string]string{edgev1alpha1.AdoptServiceAnnotation: "legacy-neuron"},
+		},
+		Spec: edgev1alpha1.NeuronSpec{Neuron: corev1.Container{Name: "neuron", Image: "emqx/neuron:2.3.0"}},
+	}
+	ins.Default()
+	svc := func(port int32, target intstr.IntOrString) *corev1.Service {
+		return &corev1.Service{Spec: corev1.ServiceSpec{Ports: []corev1.ServicePort{{Port: port, TargetPort: target}}}}
+	}
+
+	assert.Nil(t, checkAdoptedService(ins, svc(7000, intstr.FromString("neuron"))))
+	assert.Nil(t, checkAdoptedService(ins, svc(7000, intstr.IntOrString{})))
+	assert.EqualError(t, checkAdoptedService(ins, svc(80, intstr.FromInt(7000))),
+		"its port 80/TCP is not a port of the service template")
+	assert.EqualError(t, checkAdoptedService(ins, svc(7000, intstr.FromInt(8000))),
+		"its port 7000 targets 8000, the service template targets port 7000 of the pods")
+	assert.EqualError(t, checkAdoptedService(ins, svc(7000, intstr.FromString("web"))),
+		"its port 7000 targets web, the service template targets port 7000 of the pods")
+}
+
+func TestCheckAdoptedDeployment(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "neuron",
+			Namespace:   "default",
+			Labels:      map[string]string{"app": "neuron"},
+			Annotations: map[string]string{edgev1alpha1.AdoptPVCsAnnotation: "neuron-data=data"},
+		},
+		Spec: edgev1alpha1.NeuronSpec{
+			Neuron:              corev1.Container{Name: "neuron"},
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{},
+		},
+	}
+	deploy := func(selector map[string]string, claim string) *appsv1.Deployment {
+		return &appsv1.Deployment{Spec: appsv1.DeploymentSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: selector},
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{Name: "data", VolumeSource: corev1.VolumeSource{
+					PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: claim},
+				}}},
+			}},
+		}}
+	}
+
+	assert.Nil(t, checkAdoptedDeployment(ins, deploy(map[string]string{"app": "neuron"}, "data")))
+	assert.EqualError(t, checkAdoptedDeployment(ins, deploy(map[string]string{"app": "legacy"}, "data")),
+		"its selector app=legacy does not match the labels of the instance")
+	assert.EqualError(t, checkAdoptedDeployment(ins, deploy(map[string]string{"app": "neuron"}, "logs")),
+		"PersistentVolumeClaim logs is not adopted, add it to the edge.emqx.io/adopt-pvcs annotation")
+
+	renamed := deploy(map[string]string{"app": "neuron"}, "data")
+	renamed.Spec.Template.Spec.Containers = []corev1.Container{{Name: "neuron"}, {Name: "exporter"}}
+	assert.EqualError(t, checkAdoptedDeployment(ins, renamed),
+		"its container exporter is not one of the containers neuron of the instance")
+
+	controlled := deploy(map[string]string{"app": "neuron"}, "data")
+	controlled.OwnerReferences = []metav1.OwnerReference{{Kind: "ReplicaSet", Name: "other", Controller: &[]bool{true}[0]}}
+	assert.EqualError(t, checkAdoptedDeployment(ins, controlled), "it is controlled by ReplicaSet other")
+}
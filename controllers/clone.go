@@ -0,0 +1,227 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+type cloneEKuiper struct{}
+
+func (c cloneEKuiper) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.EKuiper) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"clone eKuiper")
+	return cloneInstance(ctx, r, instance, logger)
+}
+
+type cloneNeuron struct{}
+
+func (c cloneNeuron) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"clone Neuron")
+	return cloneInstance(ctx, r, instance, logger)
+}
+
+type cloneNeuronEX struct{}
+
+func (c cloneNeuronEX) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.NeuronEX) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"clone NeuronEX")
+	return cloneInstance(ctx, r, instance, logger)
+}
+
+// cloneInstance copies the configuration of the source instance through the REST APIs once both instances
+// are ready. It runs once, a failed copy is retried every minute.
+func cloneInstance(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	clone := ins.GetCloneFrom()
+	status := ins.GetStatus()
+	if clone == nil || (status.Clone != nil && status.Clone.Phase == edgev1alpha1.CloneCompleted) {
+		return nil
+	}
+
+	source, err := getCloneSource(ctx, r.Client, ins.GetNamespace(), clone)
+	if err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return cloneFailed(ctx, r, ins, fmt.Errorf("%s %s is not found", clone.Kind, clone.Name))
+		}
+		return &requeue{curError: err}
+	}
+	if status.Phase != edgev1alpha1.CRReady || source.GetStatus().Phase != edgev1alpha1.CRReady {
+		if status.Clone == nil {
+			if req := setCloneStatus(ctx, r, ins, &edgev1alpha1.CloneStatus{Phase: edgev1alpha1.ClonePending}); req != nil {
+				return req
+			}
+		}
+		return &requeue{delay: 10 * time.Second, message: fmt.Sprintf("waiting for the instance and %s %s to be ready",
+			clone.Kind, clone.Name)}
+	}
+
+	logger.Info("Clone configuration", "kind", clone.Kind, "source", clone.Name)
+	if ins.GetNeuron() != nil && source.GetNeuron() != nil {
+		if err := cloneNeuronNodes(ctx, r.Client, source, ins, clone); err != nil {
+			return cloneFailed(ctx, r, ins, fmt.Errorf("failed to copy the neuron nodes: %w", err))
+		}
+	}
+	if ins.GetEKuiper() != nil && source.GetEKuiper() != nil {
+		if err := cloneEKuiperConfig(ctx, r.Client, source, ins, clone); err != nil {
+			return cloneFailed(ctx, r, ins, fmt.Errorf("failed to copy the ekuiper configuration: %w", err))
+		}
+	}
+
+	r.Recorder.Eventf(ins, corev1.EventTypeNormal, "CloneCompleted", "Copied the configuration of %s %s",
+		clone.Kind, clone.Name)
+	return setCloneStatus(ctx, r, ins, &edgev1alpha1.CloneStatus{
+		Phase:          edgev1alpha1.CloneCompleted,
+		CompletionTime: &metav1.Time{Time: time.Now()},
+	})
+}
+
+func cloneNeuronNodes(ctx context.Context, c client.Reader, source, target edgev1alpha1.EdgeInterface,
+	clone *edgev1alpha1.CloneFrom) error {
+	token, err := getSecretKey(ctx, c, target.GetNamespace(), clone.NeuronTokenSecretRef)
+	if err != nil {
+		return err
+	}
+
+	sourcePod, err := getAPIPod(ctx, c, source)
+	if err != nil {
+		return err
+	}
+	nodes, err := newNeuronAPI(fmt.Sprintf("http://%s:%d", sourcePod.Status.PodIP, source.GetNeuronPort()), token).
+		exportNodes(ctx)
+	if err != nil {
+		return err
+	}
+	if err := substitute(&nodes, clone.Substitutions); err != nil {
+		return err
+	}
+
+	targetPod, err := getAPIPod(ctx, c, target)
+	if err != nil {
+		return err
+	}
+	return newNeuronAPI(fmt.Sprintf("http://%s:%d", targetPod.Status.PodIP, target.GetNeuronPort()), token).
+		importNodes(ctx, nodes)
+}
+
+func cloneEKuiperConfig(ctx context.Context, c client.Reader, source, target edgev1alpha1.EdgeInterface,
+	clone *edgev1alpha1.CloneFrom) error {
+	sourcePod, err := getAPIPod(ctx, c, source)
+	if err != nil {
+		return err
+	}
+	config, err := newEKuiperAPI(fmt.Sprintf("http://%s:%d", sourcePod.Status.PodIP, source.GetEKuiperRestPort())).
+		exportConfig(ctx)
+	if err != nil {
+		return err
+	}
+	if err := substitute(&config, clone.Substitutions); err != nil {
+		return err
+	}
+
+	targetPod, err := getAPIPod(ctx, c, target)
+	if err != nil {
+		return err
+	}
+	return newEKuiperAPI(fmt.Sprintf("http://%s:%d", targetPod.Status.PodIP, target.GetEKuiperRestPort())).
+		importConfig(ctx, config)
+}
+
+// getCloneSource returns the instance the configuration is copied from
+func getCloneSource(ctx context.Context, c client.Reader, namespace string, clone *edgev1alpha1.CloneFrom) (
+	edgev1alpha1.EdgeInterface, error) {
+	var source edgev1alpha1.EdgeInterface
+	switch clone.GetComponentType() {
+	case edgev1alpha1.ComponentTypeNeuron:
+		source = &edgev1alpha1.Neuron{}
+	case edgev1alpha1.ComponentTypeEKuiper:
+		source = &edgev1alpha1.EKuiper{}
+	default:
+		source = &edgev1alpha1.NeuronEX{}
+	}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: clone.Name}, source); err != nil {
+		return nil, err
+	}
+	return source, nil
+}
+
+// getAPIPod returns the pod serving the REST APIs of an instance, the active pod in high availability mode
+func getAPIPod(ctx context.Context, c client.Reader, ins edgev1alpha1.EdgeInterface) (*corev1.Pod, error) {
+	pods, err := getInstancePods(ctx, c, ins)
+	if err != nil {
+		return nil, err
+	}
+	var healthy *corev1.Pod
+	for i := range pods {
+		if !isPodHealthy(&pods[i]) || pods[i].Status.PodIP == "" {
+			continue
+		}
+		if pods[i].Name == ins.GetStatus().ActivePod {
+			return &pods[i], nil
+		}
+		if healthy == nil {
+			healthy = &pods[i]
+		}
+	}
+	if healthy == nil {
+		return nil, fmt.Errorf("%s has no ready pod", ins.GetName())
+	}
+	return healthy, nil
+}
+
+// substitute replaces the strings of the substitutions in the JSON encoding of the configuration
+func substitute(config interface{}, substitutions []edgev1alpha1.Substitution) error {
+	if len(substitutions) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	content := string(data)
+	for _, sub := range substitutions {
+		content = strings.ReplaceAll(content, jsonString(sub.From), jsonString(sub.To))
+	}
+	return json.Unmarshal([]byte(content), config)
+}
+
+// jsonString returns a string escaped as in a JSON string, without the quotes
+func jsonString(s string) string {
+	data, _ := json.Marshal(s)
+	return string(data[1 : len(data)-1])
+}
+
+func cloneFailed(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, err error) *requeue {
+	if clone := ins.GetStatus().Clone; clone == nil || clone.Phase != edgev1alpha1.CloneFailed || clone.Message != err.Error() {
+		r.Recorder.Eventf(ins, corev1.EventTypeWarning, "CloneFailed", "Cannot copy the configuration of %s %s: %s",
+			ins.GetCloneFrom().Kind, ins.GetCloneFrom().Name, err)
+		if req := setCloneStatus(ctx, r, ins, &edgev1alpha1.CloneStatus{
+			Phase:   edgev1alpha1.CloneFailed,
+			Message: err.Error(),
+		}); req != nil {
+			return req
+		}
+	}
+	return &requeue{delay: time.Minute, message: err.Error()}
+}
+
+func setCloneStatus(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface,
+	clone *edgev1alpha1.CloneStatus) *requeue {
+	status := ins.GetStatus()
+	status.Clone = clone
+	ins.SetStatus(&status)
+	if err := r.Status().Update(ctx, ins); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
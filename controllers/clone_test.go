@@ -0,0 +1,179 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestCloneNeuronNodes(t *testing.T) {
+	source := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path + "?" + r.URL.RawQuery {
+		case "/api/v2/node?type=1":
+			_, _ = w.Write([]byte(`{"nodes": [{"name": "modbus", "plugin": "Modbus TCP"}]}`))
+		case "/api/v2/node?type=2":
+			_, _ = w.Write([]byte(`{"nodes": [{"name": "mqtt", "plugin": "MQTT"}]}`))
+		case "/api/v2/node/setting?node=modbus":
+			_, _ = w.Write([]byte(`{"node": "modbus", "params": {"host": "10.0.0.1", "port": 502}}`))
+		case "/api/v2/node/setting?node=mqtt":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"error": 2005}`))
+		case "/api/v2/group?node=modbus":
+			_, _ = w.Write([]byte(`{"groups": [{"name": "line-1", "interval": 1000, "tag_count": 1}]}`))
+		case "/api/v2/tags?node=modbus&group=line-1":
+			_, _ = w.Write([]byte(`{"tags": [{"name": "temperature", "address": "1!40001", "type": 3}]}`))
+		case "/api/v2/subscribe?app=mqtt":
+			_, _ = w.Write([]byte(`{"groups": [{"driver": "modbus", "group": "line-1"}]}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer source.Close()
+
+	nodes, err := newNeuronAPI(source.URL, "").exportNodes(context.Background())
+	assert.Nil(t, err)
+	assert.Len(t, nodes, 2)
+	assert.Nil(t, substitute(&nodes, []edgev1alpha1.Substitution{{From: "10.0.0.1", To: "10.0.1.1"}}))
+	assert.JSONEq(t, `{"host": "10.0.1.1", "port": 502}`, string(nodes[0].Params))
+	assert.Nil(t, nodes[1].Params)
+
+	var requests []string
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodGet {
+			_, _ = w.Write([]byte(`{"nodes": [{"name": "monitor", "plugin": "Monitor"}]}`))
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		requests = append(requests, r.URL.Path+" "+string(body))
+		_, _ = w.Write([]byte(`{"error": 0}`))
+	}))
+	defer target.Close()
+
+	assert.Nil(t, newNeuronAPI(target.URL, "").importNodes(context.Background(), nodes))
+	assert.Equal(t, []string{
+		`/api/v2/node {"name":"modbus","plugin":"Modbus TCP"}`,
+		`/api/v2/node/setting {"node":"modbus","params":{"host":"10.0.1.1","port":502}}`,
+		`/api/v2/group {"group":"line-1","interval":1000,"node":"modbus"}`,
+		`/api/v2/tags {"group":"line-1","node":"modbus","tags":[{"name":"temperature","address":"1!40001","type":3}]}`,
+		`/api/v2/node {"name":"mqtt","plugin":"MQTT"}`,
+		`/api/v2/subscribe {"app":"mqtt","driver":"modbus","group":"line-1"}`,
+	}, requests)
+}
+
+func TestImportNeuronNodesRetry(t *testing.T) {
+	// a fake neuron that keeps its nodes and fails to create the tags once
+	created := map[string]bool{}
+	var requests []string
+	failTags := true
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body := map[string]interface{}{}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		requests = append(requests, r.Method+" "+r.URL.Path)
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/api/v2/node":
+			var nodes []string
+			for name := range created {
+				nodes = append(nodes, `{"name": "`+name+`"}`)
+			}
+			_, _ = w.Write([]byte(`{"nodes": [` + strings.Join(nodes, ",") + `]}`))
+			return
+		case r.Method == http.MethodPost && r.URL.Path == "/api/v2/node":
+			created[body["name"].(string)] = true
+		case r.Method == http.MethodDelete && r.URL.Path == "/api/v2/node":
+			delete(created, body["name"].(string))
+		case r.URL.Path == "/api/v2/tags" && failTags:
+			failTags = false
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"error": 1}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"error": 0}`))
+	}))
+	defer target.Close()
+
+	nodes := []neuronNode{{
+		Name:   "modbus",
+		Plugin: "Modbus TCP",
+		Type:   neuronNodeTypeDriver,
+		Groups: []neuronGroup{{Name: "line-1", Interval: 1000, Tags: []json.RawMessage{[]byte(`{"name": "temperature"}`)}}},
+	}}
+	api := newNeuronAPI(target.URL, "")
+	assert.NotNil(t, api.importNodes(context.Background(), nodes))
+	assert.Empty(t, created)
+
+	// the retry creates the node again with its groups and tags
+	requests = nil
+	assert.Nil(t, api.importNodes(context.Background(), nodes))
+	assert.True(t, created["modbus"])
+	assert.Equal(t, []string{
+		"GET /api/v2/node",
+		"GET /api/v2/node",
+		"POST /api/v2/node",
+		"POST /api/v2/group",
+		"POST /api/v2/tags",
+	}, requests)
+}
+
+func TestCloneEKuiperConfig(t *testing.T) {
+	var imported string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/data/export":
+			_, _ = w.Write([]byte(`{"streams": {"demo": "CREATE STREAM demo () WITH (DATASOURCE=\"tcp://10.0.0.1:1883\")"}}`))
+		case "/data/import":
+			body := map[string]string{}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+			imported = body["content"]
+			_, _ = w.Write([]byte("imported"))
+		}
+	}))
+	defer server.Close()
+
+	api := newEKuiperAPI(server.URL)
+	config, err := api.exportConfig(context.Background())
+	assert.Nil(t, err)
+	assert.Nil(t, substitute(&config, []edgev1alpha1.Substitution{{From: `"tcp://10.0.0.1`, To: `"tcp://10.0.1.1`}}))
+	assert.Nil(t, api.importConfig(context.Background(), config))
+	assert.JSONEq(t, `{"streams": {"demo": "CREATE STREAM demo () WITH (DATASOURCE=\"tcp://10.0.1.1:1883\")"}}`, imported)
+}
+
+func TestCloneInstance(t *testing.T) {
+	ins := &edgev1alpha1.NeuronEX{
+		ObjectMeta: metav1.ObjectMeta{Name: "line-2", Namespace: "default"},
+		Spec: edgev1alpha1.NeuronEXSpec{
+			Neuron:    corev1.Container{Name: "neuron"},
+			EKuiper:   corev1.Container{Name: "ekuiper"},
+			CloneFrom: &edgev1alpha1.CloneFrom{Kind: "NeuronEX", Name: "line-1"},
+		},
+	}
+	r := newFakeController(t, ins)
+	ctx := context.Background()
+
+	req := cloneInstance(ctx, r, ins, log)
+	assert.NotNil(t, req)
+	assert.Equal(t, edgev1alpha1.CloneFailed, ins.Status.Clone.Phase)
+	assert.Equal(t, "NeuronEX line-1 is not found", ins.Status.Clone.Message)
+
+	// the copy waits for both instances to be ready
+	source := &edgev1alpha1.NeuronEX{ObjectMeta: metav1.ObjectMeta{Name: "line-1", Namespace: "default"}}
+	assert.Nil(t, r.Create(ctx, source))
+	req = cloneInstance(ctx, r, ins, log)
+	assert.NotNil(t, req)
+	assert.Equal(t, "waiting for the instance and NeuronEX line-1 to be ready", req.message)
+
+	// the configuration is only copied once
+	ins.Status.Clone = &edgev1alpha1.CloneStatus{Phase: edgev1alpha1.CloneCompleted}
+	assert.Nil(t, cloneInstance(ctx, r, ins, log))
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(ins), ins))
+	assert.Equal(t, edgev1alpha1.CloneFailed, ins.Status.Clone.Phase)
+}
@@ -0,0 +1,182 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// referencedObjects are the names of the Secrets and ConfigMaps that the user mounts or references in the pod
+type referencedObjects struct {
+	secrets    []string
+	configMaps []string
+}
+
+// getReferencedObjects returns the Secrets and ConfigMaps used by the volumes, env and envFrom of the pod
+func getReferencedObjects(ins edgev1alpha1.EdgeInterface) referencedObjects {
+	secrets := map[string]struct{}{}
+	configMaps := map[string]struct{}{}
+
+	spec := ins.GetEdgePodSpec()
+	for _, vol := range spec.Volumes {
+		if vol.Secret != nil {
+			secrets[vol.Secret.SecretName] = struct{}{}
+		}
+		if vol.ConfigMap != nil {
+			configMaps[vol.ConfigMap.Name] = struct{}{}
+		}
+		if vol.Projected != nil {
+			for _, source := range vol.Projected.Sources {
+				if source.Secret != nil {
+					secrets[source.Secret.Name] = struct{}{}
+				}
+				if source.ConfigMap != nil {
+					configMaps[source.ConfigMap.Name] = struct{}{}
+				}
+			}
+		}
+	}
+
+	containers := append([]corev1.Container{}, spec.InitContainers...)
+	if ins.GetNeuron() != nil {
+		containers = append(containers, *ins.GetNeuron())
+	}
+	if ins.GetEKuiper() != nil {
+		containers = append(containers, *ins.GetEKuiper())
+	}
+	for _, container := range containers {
+		for _, env := range container.Env {
+			if env.ValueFrom == nil {
+				continue
+			}
+			if env.ValueFrom.SecretKeyRef != nil {
+				secrets[env.ValueFrom.SecretKeyRef.Name] = struct{}{}
+			}
+			if env.ValueFrom.ConfigMapKeyRef != nil {
+				configMaps[env.ValueFrom.ConfigMapKeyRef.Name] = struct{}{}
+			}
+		}
+		for _, envFrom := range container.EnvFrom {
+			if envFrom.SecretRef != nil {
+				secrets[envFrom.SecretRef.Name] = struct{}{}
+			}
+			if envFrom.ConfigMapRef != nil {
+				configMaps[envFrom.ConfigMapRef.Name] = struct{}{}
+			}
+		}
+	}
+	return referencedObjects{secrets: sortedKeys(secrets), configMaps: sortedKeys(configMaps)}
+}
+
+func (refs referencedObjects) contains(obj client.Object) bool {
+	names := refs.configMaps
+	if _, ok := obj.(*corev1.Secret); ok {
+		names = refs.secrets
+	}
+	i := sort.SearchStrings(names, obj.GetName())
+	return i < len(names) && names[i] == obj.GetName()
+}
+
+// getReferencedHashes returns the content hashes of the Secrets and ConfigMaps referenced by the pod, keyed
+// by "Kind/name". The missing ones are left out, they are hashed once created.
+func getReferencedHashes(ctx context.Context, c client.Reader, ins edgev1alpha1.EdgeInterface) (map[string]string, error) {
+	refs := getReferencedObjects(ins)
+	hashes := map[string]string{}
+	for _, name := range refs.secrets {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: name}, secret); err != nil {
+			if k8sErrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		hashes["Secret/"+name] = hashData(secret.Data)
+	}
+	for _, name := range refs.configMaps {
+		configMap := &corev1.ConfigMap{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: name}, configMap); err != nil {
+			if k8sErrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		hashes["ConfigMap/"+name] = hashData(configMap.Data) + hashData(configMap.BinaryData)
+	}
+	return hashes, nil
+}
+
+// getConfigHash returns a hash of all the Secrets and ConfigMaps mounted in the pod, the ones managed by
+// the operator are rendered from the instance and the referenced ones come from getReferencedHashes
+func getConfigHash(ins edgev1alpha1.EdgeInterface, referenced map[string]string) string {
+	hashes := map[string]string{}
+	for key, hash := range referenced {
+		hashes[key] = hash
+	}
+
+	secret := getSecret(ins)
+	hashes["Secret/"+secret.Name] = hashData(secret.Data)
+	if ins.GetComponentType() == edgev1alpha1.ComponentTypeNeuronEx {
+		ruleSet := getRuleSet(ins)
+		hashes["ConfigMap/"+ruleSet.Name] = hashData(ruleSet.Data)
+	}
+	if hasNeuronConfigFiles(ins) {
+		configMap := getNeuronConfigMap(ins)
+		hashes["ConfigMap/"+configMap.Name] = hashData(configMap.Data)
+	}
+	return hashData(hashes)
+}
+
+// hashData returns a stable hash of the data of a ConfigMap or Secret
+func hashData[T string | []byte](data map[string]T) string {
+	h := sha256.New()
+	for _, key := range sortedKeys(data) {
+		h.Write([]byte(key))
+		h.Write([]byte{0})
+		h.Write([]byte(data[key]))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// enqueueReferencing returns a handler that reconciles the instances of the list type that reference
+// the changed Secret or ConfigMap
+func enqueueReferencing(c client.Reader, list client.ObjectList) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(ctx context.Context, obj client.Object) []reconcile.Request {
+		instances := list.DeepCopyObject().(client.ObjectList)
+		if err := c.List(ctx, instances, client.InNamespace(obj.GetNamespace())); err != nil {
+			log.Error(err, "failed to list the instances referencing an object", "namespace", obj.GetNamespace(),
+				"name", obj.GetName())
+			return nil
+		}
+
+		var requests []reconcile.Request
+		_ = meta.EachListItem(instances, func(item runtime.Object) error {
+			ins, ok := item.(edgev1alpha1.EdgeInterface)
+			if ok && getReferencedObjects(ins).contains(obj) {
+				requests = append(requests, reconcile.Request{NamespacedName: client.ObjectKeyFromObject(ins)})
+			}
+			return nil
+		})
+		return requests
+	})
+}
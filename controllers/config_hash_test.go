@@ -0,0 +1,69 @@
+package controllers
+
+import (
+	"testing"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestConfigHash(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default"},
+	}
+	ins.Default()
+	hash := func(referenced map[string]string) string {
+		return getPodTemplate(ins, referenced).Annotations[edgev1alpha1.ConfigHashAnnotation]
+	}
+	first := hash(nil)
+	assert.NotEmpty(t, first)
+	assert.Equal(t, first, hash(nil))
+
+	ins.Spec.PublicKeys = []edgev1alpha1.PublicKey{{Name: "key", Data: []byte("data")}}
+	second := hash(nil)
+	assert.NotEqual(t, first, second)
+
+	ins.Spec.NeuronConfig = &edgev1alpha1.NeuronConfig{Plugins: []string{"libplugin-mqtt.so"}}
+	third := hash(nil)
+	assert.NotEqual(t, second, third)
+
+	assert.NotEqual(t, third, hash(map[string]string{"Secret/foo": hashData(map[string]string{"a": "b"})}))
+	assert.NotEqual(t, hash(map[string]string{"Secret/foo": hashData(map[string]string{"a": "b"})}),
+		hash(map[string]string{"Secret/foo": hashData(map[string]string{"a": "c"})}))
+}
+
+func TestGetReferencedObjects(t *testing.T) {
+	ins := &edgev1alpha1.NeuronEX{
+		Spec: edgev1alpha1.NeuronEXSpec{
+			EdgePodSpec: edgev1alpha1.EdgePodSpec{
+				Volumes: []corev1.Volume{
+					{Name: "a", VolumeSource: corev1.VolumeSource{Secret: &corev1.SecretVolumeSource{SecretName: "tls"}}},
+					{Name: "b", VolumeSource: corev1.VolumeSource{Projected: &corev1.ProjectedVolumeSource{
+						Sources: []corev1.VolumeProjection{
+							{ConfigMap: &corev1.ConfigMapProjection{LocalObjectReference: corev1.LocalObjectReference{Name: "ca"}}},
+						},
+					}}},
+				},
+			},
+			Neuron: corev1.Container{
+				EnvFrom: []corev1.EnvFromSource{
+					{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "creds"}}},
+				},
+			},
+			EKuiper: corev1.Container{
+				Env: []corev1.EnvVar{{Name: "A", ValueFrom: &corev1.EnvVarSource{
+					ConfigMapKeyRef: &corev1.ConfigMapKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: "settings"}},
+				}}},
+			},
+		},
+	}
+	refs := getReferencedObjects(ins)
+	assert.Equal(t, []string{"creds", "tls"}, refs.secrets)
+	assert.Equal(t, []string{"ca", "settings"}, refs.configMaps)
+
+	assert.True(t, refs.contains(&corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "tls"}}))
+	assert.False(t, refs.contains(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "tls"}}))
+	assert.True(t, refs.contains(&corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: "settings"}}))
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/banzaicloud/k8s-objectmatcher/patch"
 	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
 	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
@@ -14,10 +15,14 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"time"
 )
 
 var log = logf.Log.WithName("Edge Controller")
 
+// lastAppliedAnnotation keeps the last applied configuration of a child resource created without server-side apply
+var lastAppliedAnnotation = edgev1alpha1.GroupVersion.Group + "/last-applied-configuration"
+
 type CR interface {
 	*edgev1alpha1.EKuiper | *edgev1alpha1.NeuronEX | *edgev1alpha1.Neuron
 }
@@ -31,18 +36,27 @@ type patcher struct {
 	*patch.Annotator
 }
 
+// Options holds the settings shared by the Neuron, eKuiper and NeuronEX controllers
+type Options struct {
+	// ServerSideApply manages the child resources with server-side apply instead of
+	// the last-applied-configuration annotation and a full update
+	ServerSideApply bool
+}
+
 type EdgeController struct {
 	client.Client
 	patcher  *patcher
 	Recorder record.EventRecorder
+	options  Options
 }
 
-func NewEdgeController(k8sClient client.Client, eventRecorder record.EventRecorder) *EdgeController {
-	annotator := patch.NewAnnotator(edgev1alpha1.GroupVersion.Group + "/last-applied-configuration")
+func NewEdgeController(k8sClient client.Client, eventRecorder record.EventRecorder, options Options) *EdgeController {
+	annotator := patch.NewAnnotator(lastAppliedAnnotation)
 
 	return &EdgeController{
 		Client:   k8sClient,
 		Recorder: eventRecorder,
+		options:  options,
 		patcher: &patcher{
 			Maker: patch.NewPatchMaker(
 				annotator,
@@ -62,38 +76,88 @@ func (ec *EdgeController) reconcile(ctx context.Context, req ctrl.Request, cr cl
 		return ctrl.Result{}, err
 	}
 
-	switch cr.(type) {
+	switch ins := cr.(type) {
 	case *edgev1alpha1.EKuiper:
+		if isPaused(ins) {
+			subs := []subReconciler[*edgev1alpha1.EKuiper]{
+				updateEkuiperPause{},
+				updateEkuiperStatus{},
+			}
+			return subReconcile[*edgev1alpha1.EKuiper](ec, ctx, cr, subs)
+		}
 		subs := []subReconciler[*edgev1alpha1.EKuiper]{
+			updateEkuiperPause{},
+			rollbackEKuiper{},
 			updateEkuiperStatus{},
+			adoptEKuiperResources{},
 			addEKuiperPVC{},
+			migrateEKuiperVolumes{},
+			takeEKuiperSnapshots{},
 			addEKuiperSecret{},
+			resolveEKuiperDigests{},
 			addEkuiperDeployment{},
 			addEkuiperService{},
 			updateEkuiperStatus{},
+			recordEKuiperRevision{},
+			cloneEKuiper{},
 		}
 		return subReconcile[*edgev1alpha1.EKuiper](ec, ctx, cr, subs)
 	case *edgev1alpha1.Neuron:
+		if isPaused(ins) {
+			subs := []subReconciler[*edgev1alpha1.Neuron]{
+				updateNeuronPause{},
+				updateNeuronStatus{},
+			}
+			return subReconcile[*edgev1alpha1.Neuron](ec, ctx, cr, subs)
+		}
 		subs := []subReconciler[*edgev1alpha1.Neuron]{
+			updateNeuronPause{},
+			rollbackNeuron{},
 			updateNeuronStatus{},
+			adoptNeuronResources{},
 			addNeuronPVC{},
+			migrateNeuronVolumes{},
+			takeNeuronSnapshots{},
 			addNeuronSecret{},
+			addNeuronConfig{},
+			resolveNeuronDigests{},
 			addNeuronDeployment{},
 			addNeuronService{},
 			updateNeuronStatus{},
+			electNeuronLeader{},
+			recordNeuronRevision{},
+			cloneNeuron{},
 		}
 		return subReconcile[*edgev1alpha1.Neuron](ec, ctx, cr, subs)
-	default:
+	case *edgev1alpha1.NeuronEX:
+		if isPaused(ins) {
+			subs := []subReconciler[*edgev1alpha1.NeuronEX]{
+				updateNeuronEXPause{},
+				updateNeuronEXStatus{},
+			}
+			return subReconcile[*edgev1alpha1.NeuronEX](ec, ctx, cr, subs)
+		}
 		subs := []subReconciler[*edgev1alpha1.NeuronEX]{
+			updateNeuronEXPause{},
+			rollbackNeuronEX{},
 			updateNeuronEXStatus{},
 			addRuleSet{},
+			adoptNeuronEXResources{},
 			addNeuronExPVC{},
+			migrateNeuronEXVolumes{},
+			takeNeuronEXSnapshots{},
 			addNeuronExSecret{},
+			addNeuronExConfig{},
+			resolveNeuronEXDigests{},
 			addNeuronExDeploy{},
 			addNeuronExService{},
 			updateNeuronEXStatus{},
+			recordNeuronEXRevision{},
+			cloneNeuronEX{},
 		}
 		return subReconcile[*edgev1alpha1.NeuronEX](ec, ctx, cr, subs)
+	default:
+		return ctrl.Result{}, emperror.Errorf("unknown custom resource %T", cr)
 	}
 }
 
@@ -103,6 +167,7 @@ func subReconcile[T CR](ec *EdgeController, ctx context.Context, obj client.Obje
 	logger := log.WithValues("namespace", obj.GetNamespace(), "instance", obj.GetName())
 
 	delayedRequeue := false
+	var delay time.Duration
 	for _, subReconciler := range subReconcilers {
 		logger.Info("Attempting to run sub-reconciler", "subReconciler", fmt.Sprintf("%T", subReconciler))
 		requeue := subReconciler.reconcile(ctx, ec, obj.(T))
@@ -116,6 +181,10 @@ func subReconcile[T CR](ec *EdgeController, ctx context.Context, obj client.Obje
 				"subReconciler", fmt.Sprintf("%T", subReconciler),
 				"message", requeue.message,
 				"error", requeue.curError)
+			// the earliest delay wins, a delayed requeue without delay is immediate
+			if !delayedRequeue || requeue.delay < delay {
+				delay = requeue.delay
+			}
 			delayedRequeue = true
 			continue
 		}
@@ -124,7 +193,7 @@ func subReconcile[T CR](ec *EdgeController, ctx context.Context, obj client.Obje
 
 	if delayedRequeue {
 		logger.Info("not fully reconciled by reconciliation process", "kind", obj.GetObjectKind())
-		return ctrl.Result{Requeue: true}, nil
+		return ctrl.Result{Requeue: true, RequeueAfter: delay}, nil
 	}
 
 	logger.Info("Reconciliation complete", "kind", obj.GetObjectKind().GroupVersionKind().String())
@@ -146,6 +215,27 @@ func (ec *EdgeController) createOrUpdate(ctx context.Context, owner, newObj clie
 		return emperror.Wrapf(err, "failed to get %s %s", newObj.GetObjectKind().GroupVersionKind().Kind, newObj.GetName())
 	}
 
+	if ec.options.ServerSideApply {
+		if err := ec.migrateToServerSideApply(ctx, existingObj, logger); err != nil {
+			return err
+		}
+		if deploy, ok := newObj.(*appsv1.Deployment); ok {
+			owned, err := isReplicasOwnedByOthers(existingObj)
+			if err != nil {
+				return emperror.Wrapf(err, "failed to read the managed fields of %s %s", gvk.Kind, newObj.GetName())
+			}
+			// the replicas are left to an external scaler, otherwise the operator owns them. The standby pod of
+			// the high availability mode always runs.
+			if owned && owner.(edgev1alpha1.EdgeInterface).GetHighAvailability() == nil {
+				deploy.Spec.Replicas = nil
+			} else if deploy.Spec.Replicas == nil {
+				deploy.Spec.Replicas = &[]int32{1}[0]
+			}
+		}
+		logger.Info("Apply "+newObj.GetName(), "kind", gvk.Kind)
+		return ec.apply(ctx, owner, newObj)
+	}
+
 	patcherResult, err := ec.patcher.Calculate(existingObj, newObj)
 	if err != nil {
 		return emperror.Wrapf(err, "failed to calculate patch for %s %s", newObj.GetObjectKind().GroupVersionKind().Kind, newObj.GetName())
@@ -158,6 +248,9 @@ func (ec *EdgeController) createOrUpdate(ctx context.Context, owner, newObj clie
 }
 
 func (ec *EdgeController) create(ctx context.Context, owner, newObj client.Object) error {
+	if ec.options.ServerSideApply {
+		return ec.apply(ctx, owner, newObj)
+	}
 	if err := ec.patcher.SetLastAppliedAnnotation(newObj); err != nil {
 		return emperror.Wrapf(err, "failed to set last applied annotation for %s %s", newObj.GetObjectKind().GroupVersionKind().Kind, newObj.GetName())
 	}
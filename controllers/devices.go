@@ -0,0 +1,67 @@
+package controllers
+
+import (
+	"strings"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// setDevicesAccess requests the extended resources of the devices exposed by a device plugin, which grants
+// access to them. The container is never made privileged for the devices mounted from a hostPath, the webhook
+// only accepts them when the user made it privileged.
+func setDevicesAccess(devices []edgev1alpha1.Device, container *corev1.Container) {
+	for _, device := range devices {
+		if device.Resource == "" {
+			continue
+		}
+		if container.Resources.Limits == nil {
+			container.Resources.Limits = corev1.ResourceList{}
+		}
+		if _, ok := container.Resources.Limits[device.Resource]; !ok {
+			container.Resources.Limits[device.Resource] = resource.MustParse("1")
+		}
+	}
+}
+
+// setDevicesNodeAffinity schedules the pod on the nodes labeled with all its devices, the expressions are
+// added to every node selector term as the terms are ORed
+func setDevicesNodeAffinity(devices []edgev1alpha1.Device, podSpec *corev1.PodSpec) {
+	var expressions []corev1.NodeSelectorRequirement
+	for _, device := range devices {
+		if device.NodeLabel == "" {
+			continue
+		}
+		key, value, hasValue := strings.Cut(device.NodeLabel, "=")
+		requirement := corev1.NodeSelectorRequirement{Key: key, Operator: corev1.NodeSelectorOpExists}
+		if hasValue {
+			requirement.Operator = corev1.NodeSelectorOpIn
+			requirement.Values = []string{value}
+		}
+		expressions = append(expressions, requirement)
+	}
+	if len(expressions) == 0 {
+		return
+	}
+
+	if podSpec.Affinity == nil {
+		podSpec.Affinity = &corev1.Affinity{}
+	} else {
+		podSpec.Affinity = podSpec.Affinity.DeepCopy()
+	}
+	if podSpec.Affinity.NodeAffinity == nil {
+		podSpec.Affinity.NodeAffinity = &corev1.NodeAffinity{}
+	}
+	nodeAffinity := podSpec.Affinity.NodeAffinity
+	if nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution == nil {
+		nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution = &corev1.NodeSelector{}
+	}
+	selector := nodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	if len(selector.NodeSelectorTerms) == 0 {
+		selector.NodeSelectorTerms = []corev1.NodeSelectorTerm{{}}
+	}
+	for i := range selector.NodeSelectorTerms {
+		selector.NodeSelectorTerms[i].MatchExpressions = append(selector.NodeSelectorTerms[i].MatchExpressions, expressions...)
+	}
+}
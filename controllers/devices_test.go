@@ -0,0 +1,55 @@
+package controllers
+
+import (
+	"testing"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+func TestSetDevicesAccess(t *testing.T) {
+	container := &corev1.Container{}
+	setDevicesAccess([]edgev1alpha1.Device{{HostPath: "/dev/ttyUSB0", Resource: "smarter-devices/ttyUSB0"}}, container)
+	assert.Nil(t, container.SecurityContext)
+	assert.Equal(t, resource.MustParse("1"), container.Resources.Limits["smarter-devices/ttyUSB0"])
+
+	// a device mounted from a hostPath does not make the container privileged
+	setDevicesAccess([]edgev1alpha1.Device{{HostPath: "/dev/ttyUSB1"}}, container)
+	assert.Nil(t, container.SecurityContext)
+}
+
+func TestSetDevicesNodeAffinity(t *testing.T) {
+	devices := []edgev1alpha1.Device{
+		{HostPath: "/dev/ttyUSB0", NodeLabel: "edge.emqx.io/ttyUSB0"},
+		{HostPath: "/dev/ttyUSB1", NodeLabel: "serial=rs485"},
+		{HostPath: "/dev/ttyUSB2"},
+	}
+	expressions := []corev1.NodeSelectorRequirement{
+		{Key: "edge.emqx.io/ttyUSB0", Operator: corev1.NodeSelectorOpExists},
+		{Key: "serial", Operator: corev1.NodeSelectorOpIn, Values: []string{"rs485"}},
+	}
+
+	podSpec := &corev1.PodSpec{}
+	setDevicesNodeAffinity(devices, podSpec)
+	assert.Equal(t, []corev1.NodeSelectorTerm{{MatchExpressions: expressions}},
+		podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms)
+
+	// every term of the user is restricted to the nodes with the devices
+	userTerms := []corev1.NodeSelectorTerm{
+		{MatchExpressions: []corev1.NodeSelectorRequirement{{Key: "zone", Operator: corev1.NodeSelectorOpIn, Values: []string{"a"}}}},
+		{MatchFields: []corev1.NodeSelectorRequirement{{Key: "metadata.name", Operator: corev1.NodeSelectorOpIn, Values: []string{"edge"}}}},
+	}
+	affinity := &corev1.Affinity{NodeAffinity: &corev1.NodeAffinity{
+		RequiredDuringSchedulingIgnoredDuringExecution: &corev1.NodeSelector{NodeSelectorTerms: userTerms},
+	}}
+	podSpec = &corev1.PodSpec{Affinity: affinity}
+	setDevicesNodeAffinity(devices, podSpec)
+	terms := podSpec.Affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms
+	assert.Len(t, terms, 2)
+	assert.Equal(t, append(userTerms[0].MatchExpressions, expressions...), terms[0].MatchExpressions)
+	assert.Equal(t, expressions, terms[1].MatchExpressions)
+	assert.Equal(t, userTerms[1].MatchFields, terms[1].MatchFields)
+	assert.Len(t, affinity.NodeAffinity.RequiredDuringSchedulingIgnoredDuringExecution.NodeSelectorTerms[1].MatchExpressions, 0)
+}
@@ -0,0 +1,30 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+)
+
+// ekuiperAPI is a client of the REST API of an ekuiper pod
+type ekuiperAPI struct {
+	restAPI
+}
+
+func newEKuiperAPI(baseURL string) *ekuiperAPI {
+	return &ekuiperAPI{restAPI: newRESTAPI(baseURL, "")}
+}
+
+// exportConfig returns the streams, tables, rules and plugins of ekuiper
+func (api *ekuiperAPI) exportConfig(ctx context.Context) (json.RawMessage, error) {
+	var config json.RawMessage
+	if err := api.do(ctx, http.MethodGet, "/data/export", nil, &config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+// importConfig replaces the streams, tables, rules and plugins of ekuiper with the exported ones
+func (api *ekuiperAPI) importConfig(ctx context.Context, config json.RawMessage) error {
+	return api.do(ctx, http.MethodPost, "/data/import", map[string]string{"content": string(config)}, nil)
+}
@@ -23,6 +23,7 @@ import (
 
 	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
 	corev1 "k8s.io/api/core/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
@@ -32,9 +33,9 @@ type EKuiperReconciler struct {
 	*EdgeController
 }
 
-func NewEKuiperReconciler(k8sClient client.Client, eventRecorder record.EventRecorder) *EKuiperReconciler {
+func NewEKuiperReconciler(k8sClient client.Client, eventRecorder record.EventRecorder, options Options) *EKuiperReconciler {
 	return &EKuiperReconciler{
-		EdgeController: NewEdgeController(k8sClient, eventRecorder),
+		EdgeController: NewEdgeController(k8sClient, eventRecorder, options),
 	}
 }
 
@@ -64,5 +65,9 @@ func (r *EKuiperReconciler) SetupWithManager(mgr ctrl.Manager) error {
 		Owns(&corev1.PersistentVolumeClaim{}).
 		Owns(&corev1.ConfigMap{}).
 		Owns(&corev1.Secret{}).
+		Owns(&batchv1.Job{}).
+		Watches(&corev1.Pod{}, enqueuePodOwner(edgev1alpha1.ComponentTypeEKuiper)).
+		Watches(&corev1.Secret{}, enqueueReferencing(mgr.GetClient(), &edgev1alpha1.EKuiperList{})).
+		Watches(&corev1.ConfigMap{}, enqueueReferencing(mgr.GetClient(), &edgev1alpha1.EKuiperList{})).
 		Complete(r)
 }
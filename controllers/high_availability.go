@@ -0,0 +1,445 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/emqx/edge-operator/internal"
+	"github.com/go-logr/logr"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	neuronLeader = "neuron-leader"
+	// waitActiveContainer is the init container holding the neuron pods until they are elected active
+	waitActiveContainer = "wait-active"
+	activeVolume        = "neuron-active"
+)
+
+// leaseHolderNodeAnnotation is the node of the holder of the lease, to know whether a deleted holder is stopped
+var leaseHolderNodeAnnotation = edgev1alpha1.GroupVersion.Group + "/holder-node"
+
+type electNeuronLeader struct{}
+
+func (e electNeuronLeader) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"elect Neuron leader")
+	return electLeader(ctx, r, instance, logger)
+}
+
+// electLeader keeps one healthy neuron pod active in high availability mode. The holder of the Lease is
+// the active pod, it loses the Lease when it stays unready longer than the lease duration. The pods wait in
+// an init container until they are elected, so that only the active pod runs neuron with the southbound
+// nodes persisted as running.
+func electLeader(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	ha := ins.GetHighAvailability()
+	if ha == nil {
+		return setActivePod(ctx, r, ins, "")
+	}
+
+	// the terminating pods are kept, as their neuron may still run
+	pods, err := listInstancePods(ctx, r.Client, ins)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+
+	lease := &coordinationv1.Lease{}
+	leaseKey := types.NamespacedName{Namespace: ins.GetNamespace(), Name: internal.GetResNameOnPanic(ins, neuronLeader)}
+	if err := r.Get(ctx, leaseKey, lease); err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return &requeue{curError: err}
+		}
+		lease = &coordinationv1.Lease{
+			ObjectMeta: internal.GetObjectMetadata(ins, leaseKey.Name),
+			Spec: coordinationv1.LeaseSpec{
+				LeaseDurationSeconds: &[]int32{ha.GetLeaseDurationSeconds()}[0],
+			},
+		}
+		lease.SetGroupVersionKind(coordinationv1.SchemeGroupVersion.WithKind("Lease"))
+	}
+
+	holder := ""
+	if lease.Spec.HolderIdentity != nil {
+		holder = *lease.Spec.HolderIdentity
+	}
+	acquired := time.Time{}
+	if lease.Spec.AcquireTime != nil {
+		acquired = lease.Spec.AcquireTime.Time
+	}
+	now := time.Now()
+	active, wait := electActivePod(holder, acquired, pods, time.Duration(ha.GetLeaseDurationSeconds())*time.Second, now)
+	token, err := getNeuronToken(ctx, r.Client, ins)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+	if active != holder {
+		// the previous holder stops polling before the standby takes over, so that a device is never polled twice
+		stopped, err := stopPreviousHolders(ctx, r, ins, pods, holder, active, lease, token, logger)
+		if err != nil {
+			return &requeue{curError: err}
+		}
+		if !stopped {
+			return &requeue{delay: 5 * time.Second, message: fmt.Sprintf("waiting for the previous active pod %s to stop", holder)}
+		}
+		logger.Info("Elect active pod", "previous", holder, "active", active)
+		r.Recorder.Eventf(ins, corev1.EventTypeNormal, "LeaderElected", "Pod %s is active", active)
+		lease.Spec.HolderIdentity = &active
+		lease.Spec.AcquireTime = &metav1.MicroTime{Time: now}
+		if holder != "" {
+			lease.Spec.LeaseTransitions = &[]int32{transitions(lease) + 1}[0]
+		}
+	}
+	if active != "" {
+		for i := range pods {
+			if pods[i].Name == active && pods[i].Spec.NodeName != "" {
+				if lease.Annotations == nil {
+					lease.Annotations = map[string]string{}
+				}
+				lease.Annotations[leaseHolderNodeAnnotation] = pods[i].Spec.NodeName
+			}
+		}
+		lease.Spec.RenewTime = &metav1.MicroTime{Time: now}
+		lease.Spec.LeaseDurationSeconds = &[]int32{ha.GetLeaseDurationSeconds()}[0]
+		if lease.ResourceVersion == "" {
+			err = r.create(ctx, ins, lease)
+		} else {
+			err = r.Update(ctx, lease)
+		}
+		if err != nil {
+			return &requeue{curError: err}
+		}
+	}
+
+	// the standby pods stop polling before the active pod starts
+	for i := range pods {
+		if pods[i].Name != active && pods[i].DeletionTimestamp == nil {
+			if err := setPodActive(ctx, r, ins, &pods[i], false, token); err != nil {
+				return &requeue{curError: err}
+			}
+		}
+	}
+	for i := range pods {
+		if pods[i].Name == active {
+			if err := setPodActive(ctx, r, ins, &pods[i], true, token); err != nil {
+				return &requeue{curError: err}
+			}
+		}
+	}
+
+	if req := setActivePod(ctx, r, ins, active); req != nil {
+		return req
+	}
+	if wait > 0 {
+		return &requeue{delay: wait, message: fmt.Sprintf("active pod %s is not ready, waiting for its lease to expire", active)}
+	}
+	return nil
+}
+
+// electActivePod returns the pod that should be active. The holder stays active while it is healthy or
+// unhealthy for less than the lease duration since it got the lease, wait is then the time left before the
+// standby can take over. The standby is a healthy pod, or a pod waiting to be elected.
+func electActivePod(holder string, acquired time.Time, pods []corev1.Pod, leaseDuration time.Duration,
+	now time.Time) (active string, wait time.Duration) {
+	var candidate string
+	for i := range pods {
+		pod := &pods[i]
+		if pod.Name == holder {
+			if isPodHealthy(pod) {
+				return holder, 0
+			}
+			if (pod.Status.Phase == corev1.PodPending || pod.Status.Phase == corev1.PodRunning) && pod.DeletionTimestamp == nil {
+				// a pod that just got the lease is starting neuron
+				_, since := getPodHealth(pod)
+				if since.Before(acquired) {
+					since = acquired
+				}
+				if since.Add(leaseDuration).After(now) {
+					return holder, since.Add(leaseDuration).Sub(now)
+				}
+			}
+			continue
+		}
+		if candidate == "" && (isPodHealthy(pod) || isPodWaitingActive(pod)) {
+			candidate = pod.Name
+		}
+	}
+	if candidate == "" {
+		return holder, 0
+	}
+	return candidate, 0
+}
+
+func isPodHealthy(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodRunning || pod.DeletionTimestamp != nil {
+		return false
+	}
+	healthy, _ := getPodHealth(pod)
+	return healthy
+}
+
+// isPodWaitingActive returns true for a pod that waits to be elected active in its init container
+func isPodWaitingActive(pod *corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodPending || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, status := range pod.Status.InitContainerStatuses {
+		if status.Name == waitActiveContainer {
+			return status.State.Running != nil
+		}
+	}
+	return false
+}
+
+// getPodHealth returns whether the containers of the pod are ready on a live node, and otherwise since when
+// they are not. The node lifecycle controller marks the pods of a node that stopped reporting unready without
+// touching their containers, which shows as a Ready condition that turned false after the readiness gate of
+// the active pod turned true.
+func getPodHealth(pod *corev1.Pod) (bool, time.Time) {
+	var containersReady, ready, active *corev1.PodCondition
+	for i := range pod.Status.Conditions {
+		switch pod.Status.Conditions[i].Type {
+		case corev1.ContainersReady:
+			containersReady = &pod.Status.Conditions[i]
+		case corev1.PodReady:
+			ready = &pod.Status.Conditions[i]
+		case edgev1alpha1.ActiveReadinessGate:
+			active = &pod.Status.Conditions[i]
+		}
+	}
+	if containersReady == nil {
+		return false, time.Time{}
+	}
+	if containersReady.Status != corev1.ConditionTrue {
+		return false, containersReady.LastTransitionTime.Time
+	}
+	if ready != nil && ready.Status == corev1.ConditionFalse && active != nil && active.Status == corev1.ConditionTrue &&
+		ready.LastTransitionTime.After(active.LastTransitionTime.Time) {
+		return false, ready.LastTransitionTime.Time
+	}
+	return true, time.Time{}
+}
+
+// stopPreviousHolders returns true once the pods that may run neuron, other than the elected one, are
+// stopped. These are the holder of the lease and the pods elected before, the holder may be gone already.
+func stopPreviousHolders(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, pods []corev1.Pod,
+	holder, active string, lease *coordinationv1.Lease, token string, logger logr.Logger) (bool, error) {
+	stopped := true
+	found := false
+	for i := range pods {
+		pod := &pods[i]
+		found = found || pod.Name == holder
+		if pod.Name == active || (pod.Name != holder && pod.Annotations[edgev1alpha1.ActiveAnnotation] != "true") {
+			continue
+		}
+		podStopped, err := stopPreviousHolder(ctx, r, ins, pod, token, logger)
+		if err != nil {
+			return false, err
+		}
+		stopped = stopped && podStopped
+	}
+
+	// a deleted holder is stopped once the kubelet of its node has removed its containers
+	if holder != "" && !found {
+		nodeName := lease.Annotations[leaseHolderNodeAnnotation]
+		if nodeName == "" {
+			return stopped, nil
+		}
+		node, err := getNode(ctx, r.Client, nodeName)
+		if err != nil {
+			return false, err
+		}
+		if node != nil && !isNodeReady(node) && !isNodeOutOfService(node) {
+			r.Recorder.Eventf(ins, corev1.EventTypeWarning, "NodeNotReady", "Node %s of the previous active pod %s "+
+				"is not ready, the standby takes over once the node is deleted or tainted with %s", nodeName, holder,
+				corev1.TaintNodeOutOfService)
+			return false, nil
+		}
+	}
+	return stopped, nil
+}
+
+// stopPreviousHolder returns true once the neuron of a pod that was active is known to be stopped. A pod whose
+// REST API answers has its southbound nodes stopped, and is deleted to come back as a standby. Otherwise the
+// pod is deleted and stopped once the kubelet removed it, or once its node is deleted or tainted out of
+// service after a shutdown. The pods of a node that stopped reporting may still run, they are deleted right
+// away only once the node is known to be down.
+func stopPreviousHolder(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, pod *corev1.Pod,
+	token string, logger logr.Logger) (bool, error) {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed || pod.Spec.NodeName == "" {
+		return true, nil
+	}
+	if pod.Status.Phase == corev1.PodRunning && pod.Status.PodIP != "" {
+		api := newNeuronAPI(fmt.Sprintf("http://%s:%d", pod.Status.PodIP, ins.GetNeuronPort()), token)
+		err := api.setDriversRunning(ctx, false)
+		if err == nil {
+			return true, deletePod(ctx, r, pod)
+		}
+		logger.Info("Cannot stop the southbound nodes of the previous active pod", "pod", pod.Name, "error", err.Error())
+	}
+
+	node, err := getNode(ctx, r.Client, pod.Spec.NodeName)
+	if err != nil {
+		return false, err
+	}
+	if node == nil || (!isNodeReady(node) && isNodeOutOfService(node)) {
+		logger.Info("Fence the previous active pod", "pod", pod.Name, "node", pod.Spec.NodeName)
+		r.Recorder.Eventf(ins, corev1.EventTypeWarning, "PodFenced",
+			"Deleted pod %s whose node %s is down", pod.Name, pod.Spec.NodeName)
+		if err := r.Delete(ctx, pod, client.GracePeriodSeconds(0)); err != nil && !k8sErrors.IsNotFound(err) {
+			return false, err
+		}
+		return true, nil
+	}
+	if !isNodeReady(node) {
+		r.Recorder.Eventf(ins, corev1.EventTypeWarning, "NodeNotReady", "Node %s of the previous active pod %s "+
+			"is not ready, the standby takes over once the node is deleted or tainted with %s", node.Name, pod.Name,
+			corev1.TaintNodeOutOfService)
+	}
+	return false, deletePod(ctx, r, pod)
+}
+
+// deletePod deletes a pod with its grace period, unless it is terminating already
+func deletePod(ctx context.Context, r *EdgeController, pod *corev1.Pod) error {
+	if pod.DeletionTimestamp != nil {
+		return nil
+	}
+	if err := r.Delete(ctx, pod); err != nil && !k8sErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// getNode returns the node of the name, or nil if it does not exist
+func getNode(ctx context.Context, c client.Reader, name string) (*corev1.Node, error) {
+	node := &corev1.Node{}
+	if err := c.Get(ctx, types.NamespacedName{Name: name}, node); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return node, nil
+}
+
+func isNodeReady(node *corev1.Node) bool {
+	for _, c := range node.Status.Conditions {
+		if c.Type == corev1.NodeReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// isNodeOutOfService returns true when the administrator marked the node as shut down with the taint of the
+// non-graceful node shutdown
+func isNodeOutOfService(node *corev1.Node) bool {
+	for _, taint := range node.Spec.Taints {
+		if taint.Key == corev1.TaintNodeOutOfService {
+			return true
+		}
+	}
+	return false
+}
+
+func transitions(lease *coordinationv1.Lease) int32 {
+	if lease.Spec.LeaseTransitions == nil {
+		return 0
+	}
+	return *lease.Spec.LeaseTransitions
+}
+
+// setPodActive starts or stops the southbound nodes of a healthy pod, then sets its readiness gate. The active
+// pod is annotated first, which lets it start neuron.
+func setPodActive(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, pod *corev1.Pod, active bool,
+	token string) error {
+	if active && pod.Annotations[edgev1alpha1.ActiveAnnotation] != "true" {
+		patch := client.MergeFrom(pod.DeepCopy())
+		if pod.Annotations == nil {
+			pod.Annotations = map[string]string{}
+		}
+		pod.Annotations[edgev1alpha1.ActiveAnnotation] = "true"
+		if err := r.Patch(ctx, pod, patch); err != nil {
+			return err
+		}
+	}
+
+	if isPodHealthy(pod) && pod.Status.PodIP != "" {
+		api := newNeuronAPI(fmt.Sprintf("http://%s:%d", pod.Status.PodIP, ins.GetNeuronPort()), token)
+		if err := api.setDriversRunning(ctx, active); err != nil {
+			return fmt.Errorf("pod %s: %w", pod.Name, err)
+		}
+	}
+
+	status := corev1.ConditionFalse
+	reason := "Standby"
+	if active {
+		status = corev1.ConditionTrue
+		reason = "Active"
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == edgev1alpha1.ActiveReadinessGate && c.Status == status {
+			return nil
+		}
+	}
+
+	patch := client.StrategicMergeFrom(pod.DeepCopy())
+	condition := corev1.PodCondition{
+		Type:               edgev1alpha1.ActiveReadinessGate,
+		Status:             status,
+		Reason:             reason,
+		LastTransitionTime: metav1.Now(),
+	}
+	updated := false
+	for i := range pod.Status.Conditions {
+		if pod.Status.Conditions[i].Type == condition.Type {
+			pod.Status.Conditions[i] = condition
+			updated = true
+		}
+	}
+	if !updated {
+		pod.Status.Conditions = append(pod.Status.Conditions, condition)
+	}
+	return r.Status().Patch(ctx, pod, patch)
+}
+
+// getNeuronToken returns the token of the neuron REST API, or "" when none is configured
+func getNeuronToken(ctx context.Context, c client.Reader, ins edgev1alpha1.EdgeInterface) (string, error) {
+	return getSecretKey(ctx, c, ins.GetNamespace(), ins.GetHighAvailability().TokenSecretRef)
+}
+
+// getSecretKey returns the value of the key of a secret, or an empty string if ref is nil
+func getSecretKey(ctx context.Context, c client.Reader, namespace string, ref *corev1.SecretKeySelector) (string, error) {
+	if ref == nil {
+		return "", nil
+	}
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: ref.Name}, secret); err != nil {
+		return "", err
+	}
+	token, ok := secret.Data[ref.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key %s", ref.Name, ref.Key)
+	}
+	return string(token), nil
+}
+
+// setActivePod records the active pod in the status of the instance
+func setActivePod(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, active string) *requeue {
+	status := ins.GetStatus()
+	if status.ActivePod == active {
+		return nil
+	}
+	status.ActivePod = active
+	ins.SetStatus(&status)
+	if err := r.Status().Update(ctx, ins); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
@@ -0,0 +1,302 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestElectActivePod(t *testing.T) {
+	now := time.Now()
+	pod := func(name string, ready bool, since time.Time) corev1.Pod {
+		status := corev1.ConditionFalse
+		if ready {
+			status = corev1.ConditionTrue
+		}
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				Conditions: []corev1.PodCondition{{
+					Type: corev1.ContainersReady, Status: status, LastTransitionTime: metav1.NewTime(since),
+				}},
+			},
+		}
+	}
+	lease := 15 * time.Second
+
+	active, wait := electActivePod("", time.Time{}, []corev1.Pod{pod("a", false, now), pod("b", true, now)}, lease, now)
+	assert.Equal(t, "b", active)
+	assert.Zero(t, wait)
+
+	// a healthy holder keeps the lease
+	active, _ = electActivePod("b", time.Time{}, []corev1.Pod{pod("a", true, now), pod("b", true, now)}, lease, now)
+	assert.Equal(t, "b", active)
+
+	// an unready holder keeps the lease until it expires
+	active, wait = electActivePod("b", time.Time{}, []corev1.Pod{pod("a", true, now), pod("b", false, now.Add(-5*time.Second))}, lease, now)
+	assert.Equal(t, "b", active)
+	assert.Equal(t, 10*time.Second, wait)
+
+	active, wait = electActivePod("b", time.Time{}, []corev1.Pod{pod("a", true, now), pod("b", false, now.Add(-20*time.Second))}, lease, now)
+	assert.Equal(t, "a", active)
+	assert.Zero(t, wait)
+
+	// a deleted holder is replaced right away
+	active, _ = electActivePod("b", time.Time{}, []corev1.Pod{pod("a", true, now)}, lease, now)
+	assert.Equal(t, "a", active)
+
+	// no healthy pod to take over
+	active, _ = electActivePod("b", time.Time{}, []corev1.Pod{pod("a", false, now)}, lease, now)
+	assert.Equal(t, "b", active)
+
+	// the node lifecycle controller marks the holder unready when its node stops reporting
+	holder := pod("b", true, now.Add(-time.Hour))
+	holder.Status.Conditions = append(holder.Status.Conditions,
+		corev1.PodCondition{Type: edgev1alpha1.ActiveReadinessGate, Status: corev1.ConditionTrue,
+			LastTransitionTime: metav1.NewTime(now.Add(-time.Hour))},
+		corev1.PodCondition{Type: corev1.PodReady, Status: corev1.ConditionFalse,
+			LastTransitionTime: metav1.NewTime(now.Add(-20 * time.Second))})
+	active, _ = electActivePod("b", time.Time{}, []corev1.Pod{pod("a", true, now), holder}, lease, now)
+	assert.Equal(t, "a", active)
+
+	// a pod that was unready before it got activated is healthy
+	holder.Status.Conditions[1].LastTransitionTime = metav1.NewTime(now.Add(-time.Minute))
+	holder.Status.Conditions[2].LastTransitionTime = metav1.NewTime(now.Add(-time.Hour))
+	active, _ = electActivePod("b", time.Time{}, []corev1.Pod{pod("a", true, now), holder}, lease, now)
+	assert.Equal(t, "b", active)
+
+	// a pod waiting in its init container is a standby
+	waiting := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "a"},
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			InitContainerStatuses: []corev1.ContainerStatus{{
+				Name: waitActiveContainer, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			}},
+		},
+	}
+	active, _ = electActivePod("", time.Time{}, []corev1.Pod{waiting, pod("b", false, now)}, lease, now)
+	assert.Equal(t, "a", active)
+
+	// the holder that was just elected starts neuron within the lease
+	waiting.Status.InitContainerStatuses[0].State = corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{}}
+	active, wait = electActivePod("a", now.Add(-5*time.Second), []corev1.Pod{waiting, pod("b", true, now)}, lease, now)
+	assert.Equal(t, "a", active)
+	assert.Equal(t, 10*time.Second, wait)
+	active, _ = electActivePod("a", now.Add(-20*time.Second), []corev1.Pod{waiting, pod("b", true, now)}, lease, now)
+	assert.Equal(t, "b", active)
+}
+
+func TestStopPreviousHolder(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default"},
+		Spec:       edgev1alpha1.NeuronSpec{HighAvailability: &edgev1alpha1.HighAvailability{}},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-b"},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{
+			Type: corev1.NodeReady, Status: corev1.ConditionUnknown,
+		}}},
+	}
+	newPod := func() *corev1.Pod {
+		return &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "neuron-b", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: "node-b"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	ctx := context.Background()
+
+	// a pod that can not be reached on a node that stopped reporting may still run
+	pod := newPod()
+	r := newFakeController(t, ins, pod, node)
+	stopped, err := stopPreviousHolder(ctx, r, ins, pod, "", log)
+	assert.Nil(t, err)
+	assert.False(t, stopped)
+
+	// it is stopped once the node is marked out of service
+	pod = newPod()
+	node.Spec.Taints = []corev1.Taint{{Key: corev1.TaintNodeOutOfService, Effect: corev1.TaintEffectNoExecute}}
+	r = newFakeController(t, ins, pod, node)
+	stopped, err = stopPreviousHolder(ctx, r, ins, pod, "", log)
+	assert.Nil(t, err)
+	assert.True(t, stopped)
+	assert.True(t, k8sErrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})))
+
+	// or once the node is deleted
+	pod = newPod()
+	r = newFakeController(t, ins, pod)
+	stopped, err = stopPreviousHolder(ctx, r, ins, pod, "", log)
+	assert.Nil(t, err)
+	assert.True(t, stopped)
+
+	// a pod that is not running anymore is stopped
+	pod.Status.Phase = corev1.PodFailed
+	stopped, err = stopPreviousHolder(ctx, r, ins, pod, "", log)
+	assert.Nil(t, err)
+	assert.True(t, stopped)
+}
+
+func TestStopPreviousHolders(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default"},
+		Spec:       edgev1alpha1.NeuronSpec{HighAvailability: &edgev1alpha1.HighAvailability{}},
+	}
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-b"},
+		Status: corev1.NodeStatus{Conditions: []corev1.NodeCondition{{
+			Type: corev1.NodeReady, Status: corev1.ConditionUnknown,
+		}}},
+	}
+	lease := &coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{leaseHolderNodeAnnotation: "node-b"}},
+	}
+	r := newFakeController(t, ins, node)
+	ctx := context.Background()
+
+	// the deleted holder may still run on its node that stopped reporting
+	stopped, err := stopPreviousHolders(ctx, r, ins, nil, "neuron-b", "neuron-a", lease, "", log)
+	assert.Nil(t, err)
+	assert.False(t, stopped)
+
+	node.Status.Conditions[0].Status = corev1.ConditionTrue
+	assert.Nil(t, r.Status().Update(ctx, node))
+	stopped, err = stopPreviousHolders(ctx, r, ins, nil, "neuron-b", "neuron-a", lease, "", log)
+	assert.Nil(t, err)
+	assert.True(t, stopped)
+
+	// a pod elected before is stopped too
+	pod := corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron-c", Namespace: "default",
+			Annotations: map[string]string{edgev1alpha1.ActiveAnnotation: "true"}},
+		Spec:   corev1.PodSpec{NodeName: "node-c"},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	assert.Nil(t, r.Create(ctx, &pod))
+	stopped, err = stopPreviousHolders(ctx, r, ins, []corev1.Pod{pod}, "", "neuron-a", lease, "", log)
+	assert.Nil(t, err)
+	assert.True(t, stopped)
+	assert.True(t, k8sErrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(&pod), &corev1.Pod{})))
+}
+
+func TestStopPreviousHolderRBAC(t *testing.T) {
+	ctx := context.Background()
+
+	for file, rules := range managerRoles(t) {
+		ins := &edgev1alpha1.Neuron{
+			ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default"},
+			Spec:       edgev1alpha1.NeuronSpec{HighAvailability: &edgev1alpha1.HighAvailability{}},
+		}
+		node := &corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-b"},
+			Spec:       corev1.NodeSpec{Taints: []corev1.Taint{{Key: corev1.TaintNodeOutOfService, Effect: corev1.TaintEffectNoExecute}}},
+		}
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "neuron-b", Namespace: "default"},
+			Spec:       corev1.PodSpec{NodeName: "node-b"},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+		c := &rbacClient{Client: newFakeClient(t, ins, pod, node), rules: rules}
+		r := NewEdgeController(c, record.NewFakeRecorder(20), Options{})
+		stopped, err := stopPreviousHolder(ctx, r, ins, pod, "", log)
+		assert.Nil(t, err, file)
+		assert.True(t, stopped, file)
+		assert.True(t, k8sErrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(pod), &corev1.Pod{})), file)
+	}
+}
+
+func TestGetPhaseHighAvailability(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{Spec: edgev1alpha1.NeuronSpec{HighAvailability: &edgev1alpha1.HighAvailability{}}}
+	deploy := &appsv1.Deployment{
+		Spec:   appsv1.DeploymentSpec{Replicas: &[]int32{2}[0]},
+		Status: appsv1.DeploymentStatus{Replicas: 2, UpdatedReplicas: 2},
+	}
+	assert.Equal(t, edgev1alpha1.CRNotReady, getPhase(ins, deploy))
+	assert.False(t, isRolledOut(ins, deploy))
+
+	// the standby pod stays unready behind the active readiness gate
+	deploy.Status.ReadyReplicas = 1
+	assert.Equal(t, edgev1alpha1.CRReady, getPhase(ins, deploy))
+	assert.True(t, isRolledOut(ins, deploy))
+
+	// without high availability all the pods are ready
+	ins.Spec.HighAvailability = nil
+	assert.Equal(t, edgev1alpha1.CRNotReady, getPhase(ins, deploy))
+	assert.False(t, isRolledOut(ins, deploy))
+	deploy.Status.ReadyReplicas = 2
+	assert.Equal(t, edgev1alpha1.CRReady, getPhase(ins, deploy))
+}
+
+func TestSetHighAvailabilityPodSpec(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default"},
+		Spec: edgev1alpha1.NeuronSpec{
+			HighAvailability: &edgev1alpha1.HighAvailability{},
+		},
+	}
+	ins.Default()
+	deploy := getDeployment(ins, nil)
+	assert.Equal(t, int32(2), *deploy.Spec.Replicas)
+	assert.Equal(t, []corev1.PodReadinessGate{{ConditionType: edgev1alpha1.ActiveReadinessGate}},
+		deploy.Spec.Template.Spec.ReadinessGates)
+	terms := deploy.Spec.Template.Spec.Affinity.PodAntiAffinity.RequiredDuringSchedulingIgnoredDuringExecution
+	assert.Len(t, terms, 1)
+	assert.Equal(t, corev1.LabelHostname, terms[0].TopologyKey)
+	tolerations := deploy.Spec.Template.Spec.Tolerations
+	assert.Len(t, tolerations, 2)
+	assert.Equal(t, corev1.TaintNodeUnreachable, tolerations[1].Key)
+	assert.Equal(t, int64(15), *tolerations[1].TolerationSeconds)
+	initContainers := deploy.Spec.Template.Spec.InitContainers
+	assert.Len(t, initContainers, 1)
+	assert.Equal(t, waitActiveContainer, initContainers[0].Name)
+	assert.Equal(t, deploy.Spec.Template.Spec.Containers[0].Image, initContainers[0].Image)
+	volumes := deploy.Spec.Template.Spec.Volumes
+	assert.Equal(t, "metadata.annotations['edge.emqx.io/active']", volumes[len(volumes)-1].DownwardAPI.Items[0].FieldRef.FieldPath)
+
+	ins.SetReplicas(0)
+	assert.Equal(t, int32(0), *getDeployment(ins, nil).Spec.Replicas)
+}
+
+func TestNeuronAPISetDriversRunning(t *testing.T) {
+	var commands []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+		switch r.URL.Path {
+		case "/api/v2/node":
+			assert.Equal(t, "1", r.URL.Query().Get("type"))
+			_, _ = w.Write([]byte(`{"nodes": [{"name": "modbus"}, {"name": "opcua"}]}`))
+		case "/api/v2/node/state":
+			_, _ = w.Write([]byte(`{"states": [{"node": "modbus", "running": 3}, {"node": "opcua", "running": 4}]}`))
+		case "/api/v2/node/ctl":
+			cmd := map[string]interface{}{}
+			assert.Nil(t, json.NewDecoder(r.Body).Decode(&cmd))
+			commands = append(commands, cmd)
+			_, _ = w.Write([]byte(`{"error": 0}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	api := newNeuronAPI(server.URL, "token")
+	assert.Nil(t, api.setDriversRunning(context.Background(), true))
+	assert.Equal(t, []map[string]interface{}{{"node": "opcua", "cmd": float64(0)}}, commands)
+
+	commands = nil
+	assert.Nil(t, api.setDriversRunning(context.Background(), false))
+	assert.Equal(t, []map[string]interface{}{{"node": "modbus", "cmd": float64(1)}}, commands)
+}
@@ -0,0 +1,120 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+)
+
+type resolveEKuiperDigests struct{}
+
+func (r resolveEKuiperDigests) reconcile(ctx context.Context, ec *EdgeController, instance *edgev1alpha1.EKuiper) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"resolve eKuiper image digests")
+	return resolveImageDigests(ctx, ec, instance, logger)
+}
+
+type resolveNeuronDigests struct{}
+
+func (r resolveNeuronDigests) reconcile(ctx context.Context, ec *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"resolve Neuron image digests")
+	return resolveImageDigests(ctx, ec, instance, logger)
+}
+
+type resolveNeuronEXDigests struct{}
+
+func (r resolveNeuronEXDigests) reconcile(ctx context.Context, ec *EdgeController, instance *edgev1alpha1.NeuronEX) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"resolve NeuronEX image digests")
+	return resolveImageDigests(ctx, ec, instance, logger)
+}
+
+// resolveImageDigests records in the status the digests of the container images, that the pod is pinned
+// to. A digest is only resolved again when the image changes or the resolve-digests annotation changes.
+func resolveImageDigests(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	status := ins.GetStatus()
+	var digests *edgev1alpha1.ImageDigests
+	if ins.GetPinImageDigests() {
+		request := ins.GetAnnotations()[edgev1alpha1.ResolveDigestsAnnotation]
+		var previous []edgev1alpha1.ImageDigest
+		if status.ImageDigests != nil && status.ImageDigests.ResolveRequest == request {
+			previous = status.ImageDigests.Images
+		}
+
+		digests = &edgev1alpha1.ImageDigests{ResolveRequest: request}
+		var registry *registryClient
+		for _, container := range getImageContainers(ins) {
+			if digest := findImageDigest(previous, container); digest != nil {
+				digests.Images = append(digests.Images, *digest)
+				continue
+			}
+			if registry == nil {
+				auths, err := getRegistryAuths(ctx, r.Client, ins)
+				if err != nil {
+					return &requeue{curError: err}
+				}
+				registry = newRegistryClient(auths)
+			}
+			digest, err := registry.resolve(ctx, container.Image)
+			if err != nil {
+				r.Recorder.Eventf(ins, corev1.EventTypeWarning, "ResolveImageDigestFailed",
+					"Failed to resolve the digest of image %s: %s", container.Image, err)
+				return &requeue{curError: fmt.Errorf("failed to resolve the digest of image %s: %w", container.Image, err)}
+			}
+			logger.Info("Resolved image digest", "container", container.Name, "image", container.Image, "digest", digest)
+			digests.Images = append(digests.Images, edgev1alpha1.ImageDigest{
+				Container: container.Name,
+				Image:     container.Image,
+				Digest:    digest,
+			})
+		}
+	}
+
+	if reflect.DeepEqual(status.ImageDigests, digests) {
+		return nil
+	}
+	status.ImageDigests = digests
+	ins.SetStatus(&status)
+	if err := r.Status().Update(ctx, ins); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
+
+// getImageContainers returns the init, neuron and ekuiper containers of the instance
+func getImageContainers(ins edgev1alpha1.EdgeInterface) []corev1.Container {
+	containers := append([]corev1.Container{}, ins.GetEdgePodSpec().InitContainers...)
+	if ins.GetNeuron() != nil {
+		containers = append(containers, *ins.GetNeuron())
+	}
+	if ins.GetEKuiper() != nil {
+		containers = append(containers, *ins.GetEKuiper())
+	}
+	return containers
+}
+
+func findImageDigest(digests []edgev1alpha1.ImageDigest, container corev1.Container) *edgev1alpha1.ImageDigest {
+	for i := range digests {
+		if digests[i].Container == container.Name && digests[i].Image == container.Image {
+			return &digests[i]
+		}
+	}
+	return nil
+}
+
+// pinImage pins the image of a container to the digest resolved for it, the tag is kept for readability
+func pinImage(ins edgev1alpha1.EdgeInterface, container *corev1.Container) {
+	status := ins.GetStatus()
+	if !ins.GetPinImageDigests() || status.ImageDigests == nil || strings.Contains(container.Image, "@") {
+		return
+	}
+	if digest := findImageDigest(status.ImageDigests.Images, *container); digest != nil {
+		container.Image = container.Image + "@" + digest.Digest
+	}
+}
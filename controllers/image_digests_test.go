@@ -0,0 +1,115 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	configv1alpha1 "github.com/emqx/edge-operator/api/config/v1alpha1"
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestResolveImageDigests(t *testing.T) {
+	digests := map[string]string{"emqx/neuron:2.3.0": "sha256:0123", "emqx/neuron:2.4.0": "sha256:4567"}
+	server, resolved := newFakeRegistry(t, digests)
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default"},
+		Spec: edgev1alpha1.NeuronSpec{
+			EdgePodSpec:     edgev1alpha1.EdgePodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}}},
+			Neuron:          corev1.Container{Name: "neuron", Image: host + "/emqx/neuron:2.3.0"},
+			PinImageDigests: true,
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "registry", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths": {"%s": {"username": "user", "password": "pass"}}}`, host)),
+		},
+	}
+	r := newFakeController(t, ins, secret)
+	reconcile := func() {
+		assert.Nil(t, r.Update(context.Background(), ins))
+		assert.Nil(t, resolveImageDigests(context.Background(), r, ins, log))
+		assert.Nil(t, r.Get(context.Background(), client.ObjectKeyFromObject(ins), ins))
+	}
+
+	reconcile()
+	assert.Equal(t, &edgev1alpha1.ImageDigests{Images: []edgev1alpha1.ImageDigest{
+		{Container: "neuron", Image: host + "/emqx/neuron:2.3.0", Digest: "sha256:0123"},
+	}}, ins.Status.ImageDigests)
+	assert.Equal(t, host+"/emqx/neuron:2.3.0@sha256:0123", getDeployment(ins, nil).Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, 1, *resolved)
+
+	// a retag is not followed until the annotation is bumped
+	digests["emqx/neuron:2.3.0"] = "sha256:89ab"
+	reconcile()
+	assert.Equal(t, 1, *resolved)
+	ins.Annotations = map[string]string{edgev1alpha1.ResolveDigestsAnnotation: "1"}
+	reconcile()
+	assert.Equal(t, 2, *resolved)
+	assert.Equal(t, "1", ins.Status.ImageDigests.ResolveRequest)
+	assert.Equal(t, "sha256:89ab", ins.Status.ImageDigests.Images[0].Digest)
+
+	// a new image is resolved
+	ins.Spec.Neuron.Image = host + "/emqx/neuron:2.4.0"
+	reconcile()
+	assert.Equal(t, 3, *resolved)
+	assert.Equal(t, "sha256:4567", ins.Status.ImageDigests.Images[0].Digest)
+
+	// the digests are dropped with the pinning
+	ins.Spec.PinImageDigests = false
+	reconcile()
+	assert.Nil(t, ins.Status.ImageDigests)
+	assert.Equal(t, host+"/emqx/neuron:2.4.0", getDeployment(ins, nil).Spec.Template.Spec.Containers[0].Image)
+}
+
+func TestRewriteImages(t *testing.T) {
+	server, _ := newFakeRegistry(t, map[string]string{"emqx/neuron:2.3.0": "sha256:0123"})
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+	edgev1alpha1.SetOperatorConfig(&configv1alpha1.OperatorConfig{
+		ImageRewrite: configv1alpha1.ImageRewrite{
+			Registries:       map[string]string{"docker.io": host},
+			ImagePullSecrets: []corev1.LocalObjectReference{{Name: "mirror"}},
+		},
+	})
+	defer edgev1alpha1.SetOperatorConfig(nil)
+
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default"},
+		Spec: edgev1alpha1.NeuronSpec{
+			Neuron:          corev1.Container{Name: "neuron", Image: "emqx/neuron:2.3.0"},
+			PinImageDigests: true,
+		},
+	}
+	ins.Default()
+	podSpec := getDeployment(ins, nil).Spec.Template.Spec
+	assert.Equal(t, host+"/emqx/neuron:2.3.0", podSpec.Containers[0].Image)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "mirror"}}, podSpec.ImagePullSecrets)
+
+	// the digests are resolved from the mirror, with the pull secrets of the policy
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "mirror", Namespace: "default"},
+		Type:       corev1.SecretTypeDockerConfigJson,
+		Data: map[string][]byte{
+			corev1.DockerConfigJsonKey: []byte(fmt.Sprintf(`{"auths": {"%s": {"username": "user", "password": "pass"}}}`, host)),
+		},
+	}
+	r := newFakeController(t, ins, secret)
+	assert.Nil(t, resolveImageDigests(context.Background(), r, ins, log))
+	assert.Equal(t, host+"/emqx/neuron:2.3.0@sha256:0123", getDeployment(ins, nil).Spec.Template.Spec.Containers[0].Image)
+
+	// the Jobs of the operator pull from the mirror too
+	job := getMigrationJob(ins, &edgev1alpha1.VolumeMigration{Job: "neuron-migrate-volumes"})
+	assert.Equal(t, host+"/library/busybox:1.36", job.Spec.Template.Spec.Containers[0].Image)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "mirror"}}, job.Spec.Template.Spec.ImagePullSecrets)
+}
@@ -0,0 +1,265 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// isRestartHeld returns whether the changes that restart the pods are held now, and otherwise when the next
+// maintenance window opens. Nothing is held without windows, when none of them ever opens or with the apply
+// now annotation.
+func isRestartHeld(ins edgev1alpha1.EdgeInterface) (bool, time.Time) {
+	windows := ins.GetMaintenanceWindows()
+	if len(windows) == 0 || ins.GetAnnotations()[edgev1alpha1.ApplyNowAnnotation] == "true" {
+		return false, time.Time{}
+	}
+	open, next := edgev1alpha1.GetMaintenanceWindowState(windows, time.Now())
+	if open || next.IsZero() {
+		return false, time.Time{}
+	}
+	return true, next
+}
+
+// holdPodTemplate keeps the pod template of the existing Deployment in the desired one outside of the
+// maintenance windows, and returns the held changes with the opening of the next window. The template
+// is not held while no pod runs, nor when it mounts the claims of a completed or discarded volume migration.
+func holdPodTemplate(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, deploy *appsv1.Deployment,
+	logger logr.Logger) ([]string, time.Time, error) {
+	held, next := isRestartHeld(ins)
+	if !held {
+		return nil, next, nil
+	}
+
+	existing := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: deploy.Namespace, Name: deploy.Name}, existing); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil, next, nil
+		}
+		return nil, next, err
+	}
+	if existing.Spec.Replicas != nil && *existing.Spec.Replicas == 0 && existing.Status.Replicas == 0 {
+		return nil, next, nil
+	}
+	if migration := ins.GetStatus().VolumeMigration; migration != nil &&
+		(migration.Phase == edgev1alpha1.VolumeMigrationCompleted || migration.Phase == edgev1alpha1.VolumeMigrationDiscarded) &&
+		reflect.DeepEqual(migration.Volumes, getMigratedVolumes(ins, existing)) {
+		return nil, next, nil
+	}
+
+	changes := getPodTemplateChanges(&deploy.Spec.Template, getAppliedPodTemplate(existing))
+	if len(changes) > 0 {
+		logger.Info("Hold the pod template until the next maintenance window", "changes", changes, "next", next)
+		deploy.Spec.Template = *existing.Spec.Template.DeepCopy()
+	}
+	return changes, next, nil
+}
+
+// getAppliedPodTemplate returns the pod template of the existing Deployment without the labels and annotations
+// that other field managers added, such as the restartedAt annotation of kubectl rollout restart. They are
+// kept by server-side apply and are not a change of the desired template.
+func getAppliedPodTemplate(existing *appsv1.Deployment) *corev1.PodTemplateSpec {
+	template := existing.Spec.Template.DeepCopy()
+	for _, entry := range existing.GetManagedFields() {
+		if entry.Manager != fieldManager || entry.Operation != metav1.ManagedFieldsOperationApply ||
+			entry.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return template
+		}
+		for key, values := range map[string]map[string]string{"f:labels": template.Labels, "f:annotations": template.Annotations} {
+			for name := range values {
+				if !hasField(fields, []string{"f:spec", "f:template", "f:metadata", key, "f:" + name}) {
+					delete(values, name)
+				}
+			}
+		}
+	}
+	return template
+}
+
+// getPodTemplateChanges returns what differs between the desired pod template and the existing one. The
+// zero fields of the desired template that the API server defaults are taken from the existing one, a
+// removed env var, volume or annotation is a change.
+func getPodTemplateChanges(desired, existing *corev1.PodTemplateSpec) []string {
+	desired = desired.DeepCopy()
+	setServerDefaults(reflect.ValueOf(desired).Elem(), reflect.ValueOf(existing).Elem())
+
+	var changes []string
+	if desired.Annotations[edgev1alpha1.ConfigHashAnnotation] != existing.Annotations[edgev1alpha1.ConfigHashAnnotation] {
+		changes = append(changes, "config")
+	}
+	changes = append(changes, getContainerChanges(desired.Spec.InitContainers, existing.Spec.InitContainers)...)
+	changes = append(changes, getContainerChanges(desired.Spec.Containers, existing.Spec.Containers)...)
+	if !equality.Semantic.DeepEqual(desired.Spec.Volumes, existing.Spec.Volumes) {
+		changes = append(changes, "volumes")
+	}
+
+	// everything else is reported as a whole
+	desiredRest, existingRest := desired.DeepCopy(), existing.DeepCopy()
+	for _, rest := range []*corev1.PodTemplateSpec{desiredRest, existingRest} {
+		delete(rest.Annotations, edgev1alpha1.ConfigHashAnnotation)
+		rest.Spec.InitContainers, rest.Spec.Containers, rest.Spec.Volumes = nil, nil, nil
+	}
+	if !equality.Semantic.DeepEqual(desiredRest, existingRest) {
+		changes = append(changes, "pod template")
+	}
+	return changes
+}
+
+// setServerDefaults sets the zero scalars and nil pointers of desired to the values of existing, as the API
+// server defaults them. The items of slices and the entries of maps are never added, so that a removed item
+// is still a difference.
+func setServerDefaults(desired, existing reflect.Value) {
+	switch desired.Kind() {
+	case reflect.Struct:
+		for i := 0; i < desired.NumField(); i++ {
+			if desired.Type().Field(i).IsExported() {
+				setServerDefaults(desired.Field(i), existing.Field(i))
+			}
+		}
+	case reflect.Pointer:
+		if desired.IsNil() {
+			desired.Set(existing)
+		} else if !existing.IsNil() {
+			setServerDefaults(desired.Elem(), existing.Elem())
+		}
+	case reflect.Slice:
+		// the items are compared by index, a slice of another length differs anyway
+		if desired.Len() == existing.Len() {
+			for i := 0; i < desired.Len(); i++ {
+				setServerDefaults(desired.Index(i), existing.Index(i))
+			}
+		}
+	case reflect.Map:
+		for _, key := range desired.MapKeys() {
+			if value := existing.MapIndex(key); value.IsValid() {
+				item := reflect.New(desired.Type().Elem()).Elem()
+				item.Set(desired.MapIndex(key))
+				setServerDefaults(item, value)
+				desired.SetMapIndex(key, item)
+			}
+		}
+	case reflect.Interface:
+	default:
+		if desired.IsZero() {
+			desired.Set(existing)
+		}
+	}
+}
+
+func getContainerChanges(desired, existing []corev1.Container) []string {
+	var changes []string
+	for i := range desired {
+		var current *corev1.Container
+		for j := range existing {
+			if existing[j].Name == desired[i].Name {
+				current = &existing[j]
+			}
+		}
+		if current == nil {
+			changes = append(changes, "container "+desired[i].Name)
+			continue
+		}
+		if desired[i].Image != current.Image {
+			changes = append(changes, "image of "+desired[i].Name)
+		}
+		if !equality.Semantic.DeepEqual(desired[i].Env, current.Env) ||
+			!equality.Semantic.DeepEqual(desired[i].EnvFrom, current.EnvFrom) {
+			changes = append(changes, "env of "+desired[i].Name)
+		}
+		desiredRest, currentRest := desired[i].DeepCopy(), current.DeepCopy()
+		for _, rest := range []*corev1.Container{desiredRest, currentRest} {
+			rest.Image, rest.Env, rest.EnvFrom = "", nil, nil
+		}
+		if !equality.Semantic.DeepEqual(desiredRest, currentRest) {
+			changes = append(changes, "container "+desired[i].Name)
+		}
+	}
+	if len(desired) < len(existing) {
+		changes = append(changes, "removed containers")
+	}
+	return changes
+}
+
+// updatePendingRestart keeps the PendingRestart condition in sync with the held changes. Held changes are
+// requeued for the opening of the next window, the apply now annotation is removed once nothing is held.
+func updatePendingRestart(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, changes []string,
+	next time.Time, logger logr.Logger) *requeue {
+	status := ins.GetStatus()
+	wasPending := meta.IsStatusConditionTrue(status.Conditions, edgev1alpha1.ConditionPendingRestart)
+	if len(changes) == 0 && !wasPending {
+		return removeApplyNow(ctx, r, ins)
+	}
+
+	condition := metav1.Condition{
+		Type:               edgev1alpha1.ConditionPendingRestart,
+		Status:             metav1.ConditionFalse,
+		Reason:             "ChangesApplied",
+		Message:            "The pod template is up to date",
+		ObservedGeneration: ins.GetGeneration(),
+	}
+	if len(changes) > 0 {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "WaitingForMaintenanceWindow"
+		condition.Message = fmt.Sprintf("Held until the maintenance window at %s: %s",
+			next.UTC().Format(time.RFC3339), strings.Join(changes, ", "))
+	}
+
+	if !wasPending && len(changes) > 0 {
+		r.Recorder.Event(ins, corev1.EventTypeNormal, "RestartHeld", condition.Message)
+	}
+	if wasPending && len(changes) == 0 {
+		message := "Applied the changes held for the maintenance window"
+		if ins.GetAnnotations()[edgev1alpha1.ApplyNowAnnotation] == "true" {
+			message = "Applied the changes held for the maintenance window on " + edgev1alpha1.ApplyNowAnnotation
+		}
+		logger.Info(message)
+		r.Recorder.Event(ins, corev1.EventTypeNormal, "HeldChangesApplied", message)
+	}
+
+	if !meta.IsStatusConditionPresentAndEqual(status.Conditions, condition.Type, condition.Status) ||
+		meta.FindStatusCondition(status.Conditions, condition.Type).Message != condition.Message {
+		meta.SetStatusCondition(&status.Conditions, condition)
+		ins.SetStatus(&status)
+		if err := r.Status().Update(ctx, ins); err != nil {
+			return &requeue{curError: err}
+		}
+	}
+
+	if len(changes) > 0 {
+		return &requeue{delay: time.Until(next), message: condition.Message, delayedRequeue: true}
+	}
+	return removeApplyNow(ctx, r, ins)
+}
+
+// removeApplyNow removes the apply now annotation, it applies the held changes only once
+func removeApplyNow(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface) *requeue {
+	if _, ok := ins.GetAnnotations()[edgev1alpha1.ApplyNowAnnotation]; !ok {
+		return nil
+	}
+	patch := client.MergeFrom(ins.DeepCopyObject().(client.Object))
+	annotations := ins.GetAnnotations()
+	delete(annotations, edgev1alpha1.ApplyNowAnnotation)
+	ins.SetAnnotations(annotations)
+	if err := r.Patch(ctx, ins, patch); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newMaintenanceWindowTest(t *testing.T, schedule string, replicas int32) (*EdgeController, *edgev1alpha1.Neuron) {
+	ins := getFakeNeuron()
+	ins.Spec.MaintenanceWindows = []edgev1alpha1.MaintenanceWindow{{
+		Schedule: schedule,
+		Duration: metav1.Duration{Duration: time.Hour},
+		TimeZone: "Asia/Shanghai",
+	}}
+	deploy := getDeployment(ins, nil)
+	deploy.Spec.Replicas = &replicas
+	deploy.Status.Replicas = replicas
+	return newFakeController(t, ins, &deploy), ins
+}
+
+func TestHoldPodTemplate(t *testing.T) {
+	// the window opens in the next minute at the latest
+	next := time.Now().Add(time.Minute).Truncate(time.Minute)
+	closed := next.In(time.FixedZone("CST", 8*3600)).Format("4 15 * * *")
+	ctx := context.Background()
+
+	r, ins := newMaintenanceWindowTest(t, closed, 1)
+	ins.Spec.Neuron.Image = "emqx/neuron:2.4.0"
+	assert.Nil(t, r.Update(ctx, ins))
+	deploy := getDeployment(ins, nil)
+	changes, opening, err := holdPodTemplate(ctx, r, ins, &deploy, log)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"image of neuron"}, changes)
+	assert.True(t, opening.Equal(next))
+	assert.Equal(t, "emqx/neuron:2.3.0", deploy.Spec.Template.Spec.Containers[0].Image)
+
+	req := updatePendingRestart(ctx, r, ins, changes, opening, log)
+	assert.NotNil(t, req)
+	assert.True(t, req.delayedRequeue)
+	assert.True(t, req.delay > 0 && req.delay <= time.Minute)
+	condition := meta.FindStatusCondition(ins.Status.Conditions, edgev1alpha1.ConditionPendingRestart)
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+	assert.True(t, strings.HasSuffix(condition.Message, ": image of neuron"), condition.Message)
+
+	// the apply now annotation applies the change once
+	ins.Annotations = map[string]string{edgev1alpha1.ApplyNowAnnotation: "true"}
+	assert.Nil(t, r.Update(ctx, ins))
+	deploy = getDeployment(ins, nil)
+	changes, _, err = holdPodTemplate(ctx, r, ins, &deploy, log)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+	assert.Equal(t, "emqx/neuron:2.4.0", deploy.Spec.Template.Spec.Containers[0].Image)
+	assert.Nil(t, updatePendingRestart(ctx, r, ins, changes, time.Time{}, log))
+	assert.True(t, meta.IsStatusConditionFalse(ins.Status.Conditions, edgev1alpha1.ConditionPendingRestart))
+	got := &edgev1alpha1.Neuron{}
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(ins), got))
+	assert.NotContains(t, got.Annotations, edgev1alpha1.ApplyNowAnnotation)
+
+	// changes are applied in an open window
+	r, ins = newMaintenanceWindowTest(t, "* * * * *", 1)
+	ins.Spec.Neuron.Image = "emqx/neuron:2.4.0"
+	deploy = getDeployment(ins, nil)
+	changes, _, err = holdPodTemplate(ctx, r, ins, &deploy, log)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	// and while no pod runs
+	r, ins = newMaintenanceWindowTest(t, closed, 0)
+	ins.Spec.Neuron.Image = "emqx/neuron:2.4.0"
+	deploy = getDeployment(ins, nil)
+	changes, _, err = holdPodTemplate(ctx, r, ins, &deploy, log)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+}
+
+func TestGetPodTemplateChanges(t *testing.T) {
+	ins := &edgev1alpha1.NeuronEX{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuronex", Namespace: "default"},
+		Spec: edgev1alpha1.NeuronEXSpec{
+			Neuron:  corev1.Container{Name: "neuron", Image: "emqx/neuron:2.3.0"},
+			EKuiper: corev1.Container{Name: "ekuiper", Image: "lfedge/ekuiper:1.8.0"},
+		},
+	}
+	ins.Default()
+	existing := getPodTemplate(ins, nil)
+	assert.Empty(t, getPodTemplateChanges(&existing, &existing))
+
+	ins.Spec.Neuron.Image = "emqx/neuron:2.4.0"
+	ins.Spec.EKuiper.Env = append(ins.Spec.EKuiper.Env, corev1.EnvVar{Name: "KUIPER__BASIC__DEBUG", Value: "true"})
+	ins.Spec.NodeSelector = map[string]string{"edge": "true"}
+	desired := getPodTemplate(ins, map[string]string{"secret/token": "hash"})
+	assert.Equal(t, []string{"config", "image of neuron", "env of ekuiper", "pod template"},
+		getPodTemplateChanges(&desired, &existing))
+
+	// the fields defaulted by the API server are not changes
+	defaulted := existing.DeepCopy()
+	defaulted.Spec.RestartPolicy = corev1.RestartPolicyAlways
+	defaulted.Spec.TerminationGracePeriodSeconds = &[]int64{30}[0]
+	defaulted.Spec.Containers[0].Ports[0].Protocol = corev1.ProtocolTCP
+	assert.Empty(t, getPodTemplateChanges(&existing, defaulted))
+
+	// removed items are changes
+	existing.Spec.Containers[0].Env = append(existing.Spec.Containers[0].Env, corev1.EnvVar{Name: "LOG_LEVEL", Value: "debug"})
+	existing.Spec.Containers[1].EnvFrom = []corev1.EnvFromSource{{ConfigMapRef: &corev1.ConfigMapEnvSource{
+		LocalObjectReference: corev1.LocalObjectReference{Name: "ekuiper-env"},
+	}}}
+	existing.Spec.Volumes = append(existing.Spec.Volumes, corev1.Volume{
+		Name:         "cache",
+		VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+	})
+	existing.Annotations["example.com/owner"] = "edge"
+	desired = getPodTemplate(ins, nil)
+	desired.Spec.Containers[0].Image = existing.Spec.Containers[0].Image
+	desired.Spec.Containers[1].Env = existing.Spec.Containers[1].Env
+	assert.Equal(t, []string{"env of neuron", "env of ekuiper", "volumes", "pod template"},
+		getPodTemplateChanges(&desired, &existing))
+}
+
+func TestGetAppliedPodTemplate(t *testing.T) {
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+			{
+				Manager:    fieldManager,
+				Operation:  metav1.ManagedFieldsOperationApply,
+				FieldsType: "FieldsV1",
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec": {"f:template": {"f:metadata": {` +
+					`"f:annotations": {"f:edge.emqx.io/config-hash": {}, "f:example.com/removed": {}}}}}}`)},
+			},
+			{
+				Manager:    "kubectl-rollout",
+				Operation:  metav1.ManagedFieldsOperationUpdate,
+				FieldsType: "FieldsV1",
+				FieldsV1: &metav1.FieldsV1{Raw: []byte(`{"f:spec": {"f:template": {"f:metadata": {` +
+					`"f:annotations": {"f:kubectl.kubernetes.io/restartedAt": {}}}}}}`)},
+			},
+		}},
+		Spec: appsv1.DeploymentSpec{Template: corev1.PodTemplateSpec{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{
+			edgev1alpha1.ConfigHashAnnotation:   "hash",
+			"example.com/removed":               "true",
+			"kubectl.kubernetes.io/restartedAt": "2023-01-01T00:00:00Z",
+		}}}},
+	}
+
+	// the annotations of the other managers are not compared, the ones of the operator are
+	assert.Equal(t, map[string]string{
+		edgev1alpha1.ConfigHashAnnotation: "hash",
+		"example.com/removed":             "true",
+	}, getAppliedPodTemplate(deploy).Annotations)
+	assert.Len(t, deploy.Spec.Template.Annotations, 3)
+
+	// without server-side apply the whole template is compared
+	deploy.ManagedFields = nil
+	assert.Len(t, getAppliedPodTemplate(deploy).Annotations, 3)
+}
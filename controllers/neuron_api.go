@@ -0,0 +1,222 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// neuron node types and commands of the REST API
+const (
+	neuronNodeTypeDriver = 1
+	neuronNodeTypeApp    = 2
+	neuronNodeCmdStart   = 0
+	neuronNodeCmdStop    = 1
+	neuronNodeRunning    = 3
+)
+
+// neuronAPI is a client of the REST API of a neuron pod
+type neuronAPI struct {
+	restAPI
+}
+
+func newNeuronAPI(baseURL, token string) *neuronAPI {
+	return &neuronAPI{restAPI: newRESTAPI(baseURL, token)}
+}
+
+// setDriversRunning starts or stops all the southbound nodes that are not yet in the wanted state
+func (api *neuronAPI) setDriversRunning(ctx context.Context, running bool) error {
+	var nodes struct {
+		Nodes []struct {
+			Name string `json:"name"`
+		} `json:"nodes"`
+	}
+	if err := api.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/node?type=%d", neuronNodeTypeDriver), nil, &nodes); err != nil {
+		return err
+	}
+	if len(nodes.Nodes) == 0 {
+		return nil
+	}
+
+	var states struct {
+		States []struct {
+			Node    string `json:"node"`
+			Running int    `json:"running"`
+		} `json:"states"`
+	}
+	if err := api.do(ctx, http.MethodGet, "/api/v2/node/state", nil, &states); err != nil {
+		return err
+	}
+	isRunning := map[string]bool{}
+	for _, state := range states.States {
+		isRunning[state.Node] = state.Running == neuronNodeRunning
+	}
+
+	cmd := neuronNodeCmdStop
+	if running {
+		cmd = neuronNodeCmdStart
+	}
+	for _, node := range nodes.Nodes {
+		if isRunning[node.Name] == running {
+			continue
+		}
+		body := map[string]interface{}{"node": node.Name, "cmd": cmd}
+		if err := api.do(ctx, http.MethodPost, "/api/v2/node/ctl", body, nil); err != nil {
+			return fmt.Errorf("failed to set node %s running=%t: %w", node.Name, running, err)
+		}
+	}
+	return nil
+}
+
+// neuronNode is the configuration of a neuron node, the groups of a driver and the subscriptions of an app
+type neuronNode struct {
+	Name          string               `json:"name"`
+	Plugin        string               `json:"plugin"`
+	Type          int                  `json:"type"`
+	Params        json.RawMessage      `json:"params,omitempty"`
+	Groups        []neuronGroup        `json:"groups,omitempty"`
+	Subscriptions []neuronSubscription `json:"subscriptions,omitempty"`
+}
+
+type neuronGroup struct {
+	Name     string            `json:"name"`
+	Interval int               `json:"interval"`
+	Tags     []json.RawMessage `json:"tags,omitempty"`
+}
+
+type neuronSubscription struct {
+	Driver string `json:"driver"`
+	Group  string `json:"group"`
+}
+
+// exportNodes returns the configuration of the drivers followed by the apps
+func (api *neuronAPI) exportNodes(ctx context.Context) ([]neuronNode, error) {
+	var result []neuronNode
+	for _, nodeType := range []int{neuronNodeTypeDriver, neuronNodeTypeApp} {
+		var nodes struct {
+			Nodes []neuronNode `json:"nodes"`
+		}
+		if err := api.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/node?type=%d", nodeType), nil, &nodes); err != nil {
+			return nil, err
+		}
+		for _, node := range nodes.Nodes {
+			node.Type = nodeType
+			var setting struct {
+				Params json.RawMessage `json:"params"`
+			}
+			// a node that is not configured yet has no setting
+			err := api.do(ctx, http.MethodGet, "/api/v2/node/setting?node="+url.QueryEscape(node.Name), nil, &setting)
+			if err != nil && !isRESTNotFound(err) {
+				return nil, err
+			}
+			node.Params = setting.Params
+
+			if nodeType == neuronNodeTypeDriver {
+				if node.Groups, err = api.exportGroups(ctx, node.Name); err != nil {
+					return nil, err
+				}
+			} else {
+				var subscriptions struct {
+					Groups []neuronSubscription `json:"groups"`
+				}
+				if err := api.do(ctx, http.MethodGet, "/api/v2/subscribe?app="+url.QueryEscape(node.Name), nil,
+					&subscriptions); err != nil {
+					return nil, err
+				}
+				node.Subscriptions = subscriptions.Groups
+			}
+			result = append(result, node)
+		}
+	}
+	return result, nil
+}
+
+func (api *neuronAPI) exportGroups(ctx context.Context, node string) ([]neuronGroup, error) {
+	var groups struct {
+		Groups []neuronGroup `json:"groups"`
+	}
+	if err := api.do(ctx, http.MethodGet, "/api/v2/group?node="+url.QueryEscape(node), nil, &groups); err != nil {
+		return nil, err
+	}
+	for i := range groups.Groups {
+		var tags struct {
+			Tags []json.RawMessage `json:"tags"`
+		}
+		path := fmt.Sprintf("/api/v2/tags?node=%s&group=%s", url.QueryEscape(node), url.QueryEscape(groups.Groups[i].Name))
+		if err := api.do(ctx, http.MethodGet, path, nil, &tags); err != nil {
+			return nil, err
+		}
+		groups.Groups[i].Tags = tags.Tags
+	}
+	return groups.Groups, nil
+}
+
+// importNodes creates the nodes that do not exist yet with their settings, groups, tags and subscriptions,
+// the existing nodes are left as they are. A node that fails to import is deleted, so that a retry creates
+// it again as a whole.
+func (api *neuronAPI) importNodes(ctx context.Context, nodes []neuronNode) error {
+	existing := map[string]bool{}
+	for _, nodeType := range []int{neuronNodeTypeDriver, neuronNodeTypeApp} {
+		var current struct {
+			Nodes []neuronNode `json:"nodes"`
+		}
+		if err := api.do(ctx, http.MethodGet, fmt.Sprintf("/api/v2/node?type=%d", nodeType), nil, &current); err != nil {
+			return err
+		}
+		for _, node := range current.Nodes {
+			existing[node.Name] = true
+		}
+	}
+
+	// the drivers are created before the apps subscribe to their groups
+	for _, node := range nodes {
+		if existing[node.Name] {
+			continue
+		}
+		body := map[string]interface{}{"name": node.Name, "plugin": node.Plugin}
+		if err := api.do(ctx, http.MethodPost, "/api/v2/node", body, nil); err != nil {
+			return fmt.Errorf("failed to create node %s: %w", node.Name, err)
+		}
+		if err := api.configureNode(ctx, node); err != nil {
+			body := map[string]interface{}{"name": node.Name}
+			if delErr := api.do(ctx, http.MethodDelete, "/api/v2/node", body, nil); delErr != nil {
+				return fmt.Errorf("%w, and failed to delete node %s: %v", err, node.Name, delErr)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// configureNode sets the settings, groups, tags and subscriptions of a created node
+func (api *neuronAPI) configureNode(ctx context.Context, node neuronNode) error {
+	if len(node.Params) > 0 && string(node.Params) != "null" {
+		body := map[string]interface{}{"node": node.Name, "params": node.Params}
+		if err := api.do(ctx, http.MethodPost, "/api/v2/node/setting", body, nil); err != nil {
+			return fmt.Errorf("failed to set node %s: %w", node.Name, err)
+		}
+	}
+	for _, group := range node.Groups {
+		body := map[string]interface{}{"node": node.Name, "group": group.Name, "interval": group.Interval}
+		if err := api.do(ctx, http.MethodPost, "/api/v2/group", body, nil); err != nil {
+			return fmt.Errorf("failed to create group %s of node %s: %w", group.Name, node.Name, err)
+		}
+		if len(group.Tags) == 0 {
+			continue
+		}
+		body = map[string]interface{}{"node": node.Name, "group": group.Name, "tags": group.Tags}
+		if err := api.do(ctx, http.MethodPost, "/api/v2/tags", body, nil); err != nil {
+			return fmt.Errorf("failed to create the tags of group %s of node %s: %w", group.Name, node.Name, err)
+		}
+	}
+	for _, sub := range node.Subscriptions {
+		body := map[string]interface{}{"app": node.Name, "driver": sub.Driver, "group": sub.Group}
+		if err := api.do(ctx, http.MethodPost, "/api/v2/subscribe", body, nil); err != nil {
+			return fmt.Errorf("failed to subscribe node %s to group %s of node %s: %w", node.Name, sub.Group,
+				sub.Driver, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package controllers
+
+import (
+	"context"
+	"strings"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+type updateEkuiperPause struct{}
+
+func (u updateEkuiperPause) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.EKuiper) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"update pause")
+
+	return updatePause(ctx, r, instance, logger)
+}
+
+type updateNeuronPause struct{}
+
+func (u updateNeuronPause) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"update pause")
+
+	return updatePause(ctx, r, instance, logger)
+}
+
+type updateNeuronEXPause struct{}
+
+func (u updateNeuronEXPause) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.NeuronEX) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"update pause")
+
+	return updatePause(ctx, r, instance, logger)
+}
+
+// isPaused returns true if the reconciliation of the instance is paused by spec.paused or by the paused annotation
+func isPaused(ins edgev1alpha1.EdgeInterface) bool {
+	return ins.GetPaused() || ins.GetAnnotations()[edgev1alpha1.PausedAnnotation] == "true"
+}
+
+// updatePause keeps the Paused condition in sync with the instance. While paused, the condition lists
+// the changes that are held back; on resume, the changes about to be applied are recorded in an event.
+func updatePause(ctx context.Context, r *EdgeController, instance edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	status := instance.GetStatus()
+	wasPaused := meta.IsStatusConditionTrue(status.Conditions, edgev1alpha1.ConditionPaused)
+	paused := isPaused(instance)
+	if !paused && !wasPaused {
+		return nil
+	}
+
+	changes, err := getPendingChanges(ctx, r, instance, logger)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+
+	condition := metav1.Condition{
+		Type:               edgev1alpha1.ConditionPaused,
+		Status:             metav1.ConditionFalse,
+		Reason:             "ReconciliationResumed",
+		Message:            "Reconciliation is running",
+		ObservedGeneration: instance.GetGeneration(),
+	}
+	if paused {
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = "ReconciliationPaused"
+		condition.Message = "Reconciliation is paused"
+		if len(changes) > 0 {
+			condition.Message += ", pending changes: " + strings.Join(changes, ", ")
+		}
+	}
+
+	if wasPaused && !paused {
+		message := "Reconciliation resumed, no pending changes"
+		if len(changes) > 0 {
+			message = "Reconciliation resumed, applying pending changes: " + strings.Join(changes, ", ")
+		}
+		logger.Info(message)
+		r.Recorder.Event(instance, corev1.EventTypeNormal, "ReconciliationResumed", message)
+	}
+	if !wasPaused && paused {
+		logger.Info("Reconciliation paused")
+		r.Recorder.Event(instance, corev1.EventTypeNormal, "ReconciliationPaused", condition.Message)
+	}
+
+	if !meta.IsStatusConditionPresentAndEqual(status.Conditions, condition.Type, condition.Status) ||
+		meta.FindStatusCondition(status.Conditions, condition.Type).Message != condition.Message {
+		meta.SetStatusCondition(&status.Conditions, condition)
+		instance.SetStatus(&status)
+		if err := r.Status().Update(ctx, instance); err != nil {
+			return &requeue{curError: err}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"fmt"
+	"math/rand"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("pause reconciliation", func() {
+	var namespace *corev1.Namespace
+
+	BeforeEach(func() {
+		namespace = &corev1.Namespace{
+			ObjectMeta: metav1.ObjectMeta{
+				Name: fmt.Sprint("pause-reconciliation", +rand.Intn(10000)),
+				Labels: map[string]string{
+					"test": "e2e",
+				},
+			},
+		}
+		Expect(k8sClient.Create(ctx, namespace)).Should(Succeed())
+	})
+
+	DescribeTable("should hold back changes until resumed",
+		func(ins edgev1alpha1.EdgeInterface) {
+			ins.SetNamespace(namespace.Name)
+			ins.SetAnnotations(map[string]string{edgev1alpha1.PausedAnnotation: "true"})
+
+			defer func() {
+				Expect(k8sClient.Delete(ctx, ins)).Should(Succeed())
+			}()
+
+			Expect(k8sClient.Create(ctx, ins)).Should(Succeed())
+
+			By("check paused condition")
+			Eventually(func() *metav1.Condition {
+				got := deepCopyEdgeEdgeInterface(ins)
+				_ = k8sClient.Get(ctx, client.ObjectKeyFromObject(ins), got)
+				return meta.FindStatusCondition(got.GetStatus().Conditions, edgev1alpha1.ConditionPaused)
+			}, timeout, interval).Should(And(
+				Not(BeNil()),
+				HaveField("Status", metav1.ConditionTrue),
+				HaveField("Message", ContainSubstring("Deployment/"+ins.GetName())),
+			))
+
+			deployment := &appsv1.Deployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      ins.GetName(),
+					Namespace: ins.GetNamespace(),
+				},
+			}
+			Consistently(func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)
+			}, timeout, interval).ShouldNot(Succeed())
+
+			By("resume reconciliation")
+			got := deepCopyEdgeEdgeInterface(ins)
+			Expect(k8sClient.Get(ctx, client.ObjectKeyFromObject(ins), got)).Should(Succeed())
+			resumed := deepCopyEdgeEdgeInterface(got)
+			resumed.SetAnnotations(nil)
+			Expect(k8sClient.Patch(ctx, resumed, client.MergeFrom(got))).Should(Succeed())
+
+			Eventually(func() error {
+				return k8sClient.Get(ctx, client.ObjectKeyFromObject(deployment), deployment)
+			}, timeout, interval).Should(Succeed())
+			Expect(deployment.Annotations).ShouldNot(HaveKey(edgev1alpha1.PausedAnnotation))
+
+			Eventually(func() *metav1.Condition {
+				got := deepCopyEdgeEdgeInterface(ins)
+				_ = k8sClient.Get(ctx, client.ObjectKeyFromObject(ins), got)
+				return meta.FindStatusCondition(got.GetStatus().Conditions, edgev1alpha1.ConditionPaused)
+			}, timeout, interval).Should(HaveField("Status", metav1.ConditionFalse))
+		},
+		Entry("neuronEX", getNeuronEX()),
+		Entry("neuron", getNeuron()),
+		Entry("ekuiper", getEKuiper()),
+	)
+})
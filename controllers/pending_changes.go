@@ -0,0 +1,100 @@
+package controllers
+
+import (
+	"context"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// getCreatedObjects returns the child resources that the operator creates but never updates, the PVCs of
+// the volume claim template and the eKuiper rule set
+func getCreatedObjects(ins edgev1alpha1.EdgeInterface) []client.Object {
+	var objects []client.Object
+	pvcs := getPVCs(ins)
+	for i := range pvcs {
+		objects = append(objects, &pvcs[i])
+	}
+	if ins.GetComponentType() == edgev1alpha1.ComponentTypeNeuronEx {
+		objects = append(objects, getRuleSet(ins))
+	}
+	return objects
+}
+
+// getDesiredObjects returns the child resources that the operator keeps in sync with the instance,
+// referenced are the content hashes of the Secrets and ConfigMaps that the user mounts in the pod
+func getDesiredObjects(ins edgev1alpha1.EdgeInterface, referenced map[string]string) []client.Object {
+	secret := getSecret(ins)
+	deploy := getDeployment(ins, referenced)
+	objects := []client.Object{&secret}
+	if hasNeuronConfigFiles(ins) {
+		objects = append(objects, getNeuronConfigMap(ins))
+	}
+	objects = append(objects, &deploy)
+
+	if svc := getService(ins); svc != nil {
+		objects = append(objects, svc)
+	}
+	return objects
+}
+
+// getPendingChanges lists the child resources that would be created or changed by the next
+// full reconciliation, as "Kind/name" strings. The pod template held until the next maintenance
+// window is not a change.
+func getPendingChanges(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface,
+	logger logr.Logger) ([]string, error) {
+	referenced, err := getReferencedHashes(ctx, r.Client, ins)
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []string
+	for _, desired := range getCreatedObjects(ins) {
+		existing := desired.DeepCopyObject().(client.Object)
+		if err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing); err != nil {
+			if !k8sErrors.IsNotFound(err) {
+				return nil, err
+			}
+			changes = append(changes, desired.GetObjectKind().GroupVersionKind().Kind+"/"+desired.GetName())
+		}
+	}
+
+	for _, desired := range getDesiredObjects(ins, referenced) {
+		if deploy, ok := desired.(*appsv1.Deployment); ok {
+			if _, _, err := holdPodTemplate(ctx, r, ins, deploy, logger); err != nil {
+				return nil, err
+			}
+		}
+
+		kind := desired.GetObjectKind().GroupVersionKind().Kind
+		existing := desired.DeepCopyObject().(client.Object)
+		if err := r.Get(ctx, client.ObjectKeyFromObject(desired), existing); err != nil {
+			if !k8sErrors.IsNotFound(err) {
+				return nil, err
+			}
+			changes = append(changes, kind+"/"+desired.GetName())
+			continue
+		}
+
+		if !isDerivative(desired, existing) {
+			changes = append(changes, kind+"/"+desired.GetName())
+		}
+	}
+	return changes, nil
+}
+
+// isDerivative returns true if every field set in desired has the same value in existing,
+// fields defaulted by the API server or added by other controllers are ignored
+func isDerivative(desired, existing client.Object) bool {
+	desired = desired.DeepCopyObject().(client.Object)
+	desired.GetObjectKind().SetGroupVersionKind(existing.GetObjectKind().GroupVersionKind())
+	desired.SetOwnerReferences(nil)
+	desired.SetManagedFields(nil)
+	desired.SetCreationTimestamp(metav1.Time{})
+	return equality.Semantic.DeepDerivative(desired, existing)
+}
@@ -0,0 +1,70 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetPendingChanges(t *testing.T) {
+	ins := &edgev1alpha1.NeuronEX{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuronex", Namespace: "default"},
+		Spec: edgev1alpha1.NeuronEXSpec{
+			Neuron:              corev1.Container{Name: "neuron", Image: "emqx/neuron:2.3.0"},
+			EKuiper:             corev1.Container{Name: "ekuiper", Image: "lfedge/ekuiper:1.8.0-slim"},
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{},
+		},
+	}
+	ins.Default()
+	r := newFakeController(t, ins)
+	ctx := context.Background()
+
+	// the claims and the rule set are pending like the applied resources
+	changes, err := getPendingChanges(ctx, r, ins, log)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{
+		"PersistentVolumeClaim/neuronex-neuron-data", "PersistentVolumeClaim/neuronex-ekuiper-data",
+		"PersistentVolumeClaim/neuronex-ekuiper-plugins", "ConfigMap/neuronex-ekuiper-init-rule-set",
+		"Secret/neuronex-public-key", "Deployment/neuronex",
+	}, changes)
+
+	for _, obj := range Render(ins) {
+		assert.Nil(t, r.Create(ctx, obj))
+	}
+	changes, err = getPendingChanges(ctx, r, ins, log)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	// the claims are only created, a changed template does not update them
+	ins.Spec.VolumeClaimTemplate.Spec.StorageClassName = &[]string{"fast"}[0]
+	changes, err = getPendingChanges(ctx, r, ins, log)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	// the pod template held until the next maintenance window is not pending
+	next := time.Now().Add(time.Hour)
+	ins.Spec.MaintenanceWindows = []edgev1alpha1.MaintenanceWindow{{
+		Schedule: next.UTC().Format("4 15 * * *"),
+		Duration: metav1.Duration{Duration: time.Minute},
+	}}
+	ins.Spec.Neuron.Image = "emqx/neuron:2.3.1"
+	changes, err = getPendingChanges(ctx, r, ins, log)
+	assert.Nil(t, err)
+	assert.Empty(t, changes)
+
+	ins.Spec.MaintenanceWindows = nil
+	changes, err = getPendingChanges(ctx, r, ins, log)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"Deployment/neuronex"}, changes)
+	assert.Nil(t, r.Delete(ctx, &corev1.PersistentVolumeClaim{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "neuronex-ekuiper-data"},
+	}))
+	changes, err = getPendingChanges(ctx, r, ins, log)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"PersistentVolumeClaim/neuronex-ekuiper-data", "Deployment/neuronex"}, changes)
+}
@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	configv1alpha1 "github.com/emqx/edge-operator/api/config/v1alpha1"
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// startingReasons are the waiting reasons of a container that starts normally
+var startingReasons = map[string]struct{}{
+	"ContainerCreating": {},
+	"PodInitializing":   {},
+}
+
+// getInstancePods returns the pods of the instance that are not being deleted
+func getInstancePods(ctx context.Context, c client.Reader, ins edgev1alpha1.EdgeInterface) ([]corev1.Pod, error) {
+	pods, err := listInstancePods(ctx, c, ins)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []corev1.Pod
+	for i := range pods {
+		if pods[i].DeletionTimestamp == nil {
+			result = append(result, pods[i])
+		}
+	}
+	return result, nil
+}
+
+// listInstancePods returns the pods of the instance, including the terminating ones
+func listInstancePods(ctx context.Context, c client.Reader, ins edgev1alpha1.EdgeInterface) ([]corev1.Pod, error) {
+	pods := &corev1.PodList{}
+	if err := c.List(ctx, pods, client.InNamespace(ins.GetNamespace()), client.MatchingLabels{
+		edgev1alpha1.InstanceKey:  ins.GetName(),
+		edgev1alpha1.ComponentKey: string(ins.GetComponentType()),
+	}); err != nil {
+		return nil, err
+	}
+	sort.Slice(pods.Items, func(i, j int) bool { return pods.Items[i].Name < pods.Items[j].Name })
+	return pods.Items, nil
+}
+
+// getInstancePVCs returns the persistent volume claims of the instance that exist
+func getInstancePVCs(ctx context.Context, c client.Reader, ins edgev1alpha1.EdgeInterface) ([]corev1.PersistentVolumeClaim, error) {
+	var result []corev1.PersistentVolumeClaim
+	for _, desired := range getPVCs(ins) {
+		pvc := &corev1.PersistentVolumeClaim{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(&desired), pvc); err != nil {
+			if k8sErrors.IsNotFound(err) {
+				continue
+			}
+			return nil, err
+		}
+		result = append(result, *pvc)
+	}
+	return result, nil
+}
+
+// getContainerStatuses returns the state of every init container and container of the pods
+func getContainerStatuses(pods []corev1.Pod) []edgev1alpha1.EdgeContainerStatus {
+	var result []edgev1alpha1.EdgeContainerStatus
+	for _, pod := range pods {
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			status := edgev1alpha1.EdgeContainerStatus{
+				Name:         cs.Name,
+				Pod:          pod.Name,
+				Ready:        cs.Ready,
+				RestartCount: cs.RestartCount,
+			}
+			if terminated := cs.LastTerminationState.Terminated; terminated != nil {
+				status.LastTerminationReason = terminated.Reason
+				status.Message = terminated.Message
+			}
+			if waiting := cs.State.Waiting; waiting != nil {
+				status.WaitingReason = waiting.Reason
+				if waiting.Message != "" {
+					status.Message = waiting.Message
+				}
+			}
+			result = append(result, status)
+		}
+	}
+	return result
+}
+
+// getRunningVersion returns the tag, or else the digest, of the image that the ready container runs in the
+// newest pod, or "" if the container is not ready in any pod
+func getRunningVersion(pods []corev1.Pod, container string) string {
+	var newest *corev1.Pod
+	var version string
+	for i := range pods {
+		pod := &pods[i]
+		if newest != nil && !newest.CreationTimestamp.Before(&pod.CreationTimestamp) {
+			continue
+		}
+		for _, cs := range pod.Status.ContainerStatuses {
+			if cs.Name != container || !cs.Ready {
+				continue
+			}
+			newest, version = pod, ""
+			// the runtime can report the image by its ID when the tag is not known
+			if !strings.HasPrefix(cs.Image, "sha256:") {
+				version = configv1alpha1.ImageVersion(cs.Image)
+			}
+			if _, digest, found := strings.Cut(cs.ImageID, "@"); version == "" && found {
+				version = digest
+			}
+		}
+	}
+	return version
+}
+
+// setPodConditions sets the PodScheduled and VolumesBound conditions of the status, and removes them
+// when there is no pod or no persistent volume claim left to report on
+func setPodConditions(status *edgev1alpha1.EdgeStatus, generation int64, pods []corev1.Pod, pvcs []corev1.PersistentVolumeClaim) {
+	for _, c := range []struct {
+		conditionType string
+		condition     *metav1.Condition
+	}{
+		{edgev1alpha1.ConditionPodScheduled, getPodScheduledCondition(pods)},
+		{edgev1alpha1.ConditionVolumesBound, getVolumesBoundCondition(pvcs)},
+	} {
+		if c.condition == nil {
+			meta.RemoveStatusCondition(&status.Conditions, c.conditionType)
+			continue
+		}
+		c.condition.ObservedGeneration = generation
+		meta.SetStatusCondition(&status.Conditions, *c.condition)
+	}
+}
+
+// getPodScheduledCondition returns the PodScheduled condition of the instance from the conditions of its
+// pods, or nil if there is no pod
+func getPodScheduledCondition(pods []corev1.Pod) *metav1.Condition {
+	if len(pods) == 0 {
+		return nil
+	}
+	for _, pod := range pods {
+		for _, c := range pod.Status.Conditions {
+			if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse {
+				reason := c.Reason
+				if reason == "" {
+					reason = corev1.PodReasonUnschedulable
+				}
+				return &metav1.Condition{
+					Type:    edgev1alpha1.ConditionPodScheduled,
+					Status:  metav1.ConditionFalse,
+					Reason:  reason,
+					Message: fmt.Sprintf("pod %s: %s", pod.Name, c.Message),
+				}
+			}
+		}
+	}
+	return &metav1.Condition{
+		Type:    edgev1alpha1.ConditionPodScheduled,
+		Status:  metav1.ConditionTrue,
+		Reason:  "Scheduled",
+		Message: "All pods are scheduled",
+	}
+}
+
+// getVolumesBoundCondition returns the VolumesBound condition of the instance, or nil if it has no
+// persistent volume claim
+func getVolumesBoundCondition(pvcs []corev1.PersistentVolumeClaim) *metav1.Condition {
+	if len(pvcs) == 0 {
+		return nil
+	}
+	var pending, lost []string
+	for _, pvc := range pvcs {
+		switch pvc.Status.Phase {
+		case corev1.ClaimBound:
+		case corev1.ClaimLost:
+			lost = append(lost, pvc.Name)
+		default:
+			pending = append(pending, pvc.Name)
+		}
+	}
+	switch {
+	case len(lost) > 0:
+		return &metav1.Condition{
+			Type:    edgev1alpha1.ConditionVolumesBound,
+			Status:  metav1.ConditionFalse,
+			Reason:  string(corev1.ClaimLost),
+			Message: "PersistentVolumeClaims lost their volume: " + strings.Join(lost, ", "),
+		}
+	case len(pending) > 0:
+		return &metav1.Condition{
+			Type:    edgev1alpha1.ConditionVolumesBound,
+			Status:  metav1.ConditionFalse,
+			Reason:  string(corev1.ClaimPending),
+			Message: "PersistentVolumeClaims are pending: " + strings.Join(pending, ", "),
+		}
+	}
+	return &metav1.Condition{
+		Type:    edgev1alpha1.ConditionVolumesBound,
+		Status:  metav1.ConditionTrue,
+		Reason:  string(corev1.ClaimBound),
+		Message: "All PersistentVolumeClaims are bound",
+	}
+}
+
+// recordPodWarnings emits a Warning event for each failure that is new in the status
+func recordPodWarnings(recorder record.EventRecorder, ins edgev1alpha1.EdgeInterface, old, new edgev1alpha1.EdgeStatus) {
+	previous := map[string]edgev1alpha1.EdgeContainerStatus{}
+	for _, cs := range old.Containers {
+		previous[cs.Pod+"/"+cs.Name] = cs
+	}
+	for _, cs := range new.Containers {
+		before := previous[cs.Pod+"/"+cs.Name]
+		if _, ok := startingReasons[cs.WaitingReason]; cs.WaitingReason != "" && !ok && cs.WaitingReason != before.WaitingReason {
+			recorder.Eventf(ins, corev1.EventTypeWarning, cs.WaitingReason, "Container %s of pod %s is waiting: %s",
+				cs.Name, cs.Pod, cs.Message)
+		}
+		if cs.RestartCount > before.RestartCount && cs.LastTerminationReason != "" {
+			recorder.Eventf(ins, corev1.EventTypeWarning, "ContainerRestarted",
+				"Container %s of pod %s restarted %d times, last terminated with %s", cs.Name, cs.Pod,
+				cs.RestartCount, cs.LastTerminationReason)
+		}
+	}
+
+	for _, conditionType := range []string{edgev1alpha1.ConditionPodScheduled, edgev1alpha1.ConditionVolumesBound} {
+		condition := meta.FindStatusCondition(new.Conditions, conditionType)
+		if condition == nil || condition.Status != metav1.ConditionFalse {
+			continue
+		}
+		if before := meta.FindStatusCondition(old.Conditions, conditionType); before != nil &&
+			before.Status == condition.Status && before.Reason == condition.Reason && before.Message == condition.Message {
+			continue
+		}
+		recorder.Event(ins, corev1.EventTypeWarning, condition.Reason, condition.Message)
+	}
+}
+
+// enqueuePodOwner returns a handler that reconciles the instance of the component running the pod
+func enqueuePodOwner(component edgev1alpha1.ComponentType) handler.EventHandler {
+	return handler.EnqueueRequestsFromMapFunc(func(_ context.Context, obj client.Object) []reconcile.Request {
+		labels := obj.GetLabels()
+		if labels[edgev1alpha1.ComponentKey] != string(component) || labels[edgev1alpha1.InstanceKey] == "" {
+			return nil
+		}
+		return []reconcile.Request{{NamespacedName: client.ObjectKey{
+			Namespace: obj.GetNamespace(),
+			Name:      labels[edgev1alpha1.InstanceKey],
+		}}}
+	})
+}
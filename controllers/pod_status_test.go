@@ -0,0 +1,183 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+)
+
+func TestGetContainerStatuses(t *testing.T) {
+	pods := []corev1.Pod{{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuronex-0"},
+		Status: corev1.PodStatus{InitContainerStatuses: []corev1.ContainerStatus{
+			{
+				Name: "init-config",
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+					Reason: "ImagePullBackOff", Message: "Back-off pulling image",
+				}},
+			},
+		}, ContainerStatuses: []corev1.ContainerStatus{
+			{
+				Name:         "neuron",
+				RestartCount: 3,
+				State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{
+					Reason: "CrashLoopBackOff", Message: "back-off 40s restarting failed container",
+				}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{
+					Reason: "OOMKilled",
+				}},
+			},
+			{
+				Name:  "ekuiper",
+				Ready: true,
+				State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}},
+			},
+		}},
+	}}
+	assert.Equal(t, []edgev1alpha1.EdgeContainerStatus{
+		{
+			Name: "init-config", Pod: "neuronex-0", WaitingReason: "ImagePullBackOff", Message: "Back-off pulling image",
+		},
+		{
+			Name: "neuron", Pod: "neuronex-0", RestartCount: 3, WaitingReason: "CrashLoopBackOff",
+			LastTerminationReason: "OOMKilled", Message: "back-off 40s restarting failed container",
+		},
+		{Name: "ekuiper", Pod: "neuronex-0", Ready: true},
+	}, getContainerStatuses(pods))
+}
+
+func TestGetPodScheduledCondition(t *testing.T) {
+	assert.Nil(t, getPodScheduledCondition(nil))
+
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "neuron-0"}}
+	assert.Equal(t, metav1.ConditionTrue, getPodScheduledCondition([]corev1.Pod{pod}).Status)
+
+	pod.Status.Conditions = []corev1.PodCondition{{
+		Type:    corev1.PodScheduled,
+		Status:  corev1.ConditionFalse,
+		Reason:  corev1.PodReasonUnschedulable,
+		Message: "0/1 nodes are available: 1 Insufficient memory.",
+	}}
+	condition := getPodScheduledCondition([]corev1.Pod{pod})
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, corev1.PodReasonUnschedulable, condition.Reason)
+	assert.Equal(t, "pod neuron-0: 0/1 nodes are available: 1 Insufficient memory.", condition.Message)
+}
+
+func TestSetPodConditions(t *testing.T) {
+	status := edgev1alpha1.EdgeStatus{}
+	pod := corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "neuron-0"}}
+	pod.Status.Conditions = []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse}}
+	pvc := corev1.PersistentVolumeClaim{ObjectMeta: metav1.ObjectMeta{Name: "data"}}
+	setPodConditions(&status, 2, []corev1.Pod{pod}, []corev1.PersistentVolumeClaim{pvc})
+	assert.Len(t, status.Conditions, 2)
+	assert.True(t, meta.IsStatusConditionFalse(status.Conditions, edgev1alpha1.ConditionPodScheduled))
+	assert.True(t, meta.IsStatusConditionFalse(status.Conditions, edgev1alpha1.ConditionVolumesBound))
+	assert.Equal(t, int64(2), status.Conditions[0].ObservedGeneration)
+
+	// The pod is scheduled and the claim was deleted
+	pod.Status.Conditions[0].Status = corev1.ConditionTrue
+	setPodConditions(&status, 3, []corev1.Pod{pod}, nil)
+	assert.True(t, meta.IsStatusConditionTrue(status.Conditions, edgev1alpha1.ConditionPodScheduled))
+	assert.Nil(t, meta.FindStatusCondition(status.Conditions, edgev1alpha1.ConditionVolumesBound))
+
+	// There is no pod left
+	setPodConditions(&status, 3, nil, nil)
+	assert.Empty(t, status.Conditions)
+}
+
+func TestGetVolumesBoundCondition(t *testing.T) {
+	assert.Nil(t, getVolumesBoundCondition(nil))
+
+	pvc := func(name string, phase corev1.PersistentVolumeClaimPhase) corev1.PersistentVolumeClaim {
+		return corev1.PersistentVolumeClaim{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.PersistentVolumeClaimStatus{Phase: phase},
+		}
+	}
+	condition := getVolumesBoundCondition([]corev1.PersistentVolumeClaim{pvc("a", corev1.ClaimBound)})
+	assert.Equal(t, metav1.ConditionTrue, condition.Status)
+
+	condition = getVolumesBoundCondition([]corev1.PersistentVolumeClaim{
+		pvc("a", corev1.ClaimBound), pvc("b", corev1.ClaimPending),
+	})
+	assert.Equal(t, metav1.ConditionFalse, condition.Status)
+	assert.Equal(t, "Pending", condition.Reason)
+	assert.Contains(t, condition.Message, "b")
+}
+
+func TestRecordPodWarnings(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{ObjectMeta: metav1.ObjectMeta{Name: "neuron"}}
+	recorder := record.NewFakeRecorder(10)
+
+	old := edgev1alpha1.EdgeStatus{Containers: []edgev1alpha1.EdgeContainerStatus{
+		{Name: "neuron", Pod: "neuron-0", WaitingReason: "ContainerCreating"},
+	}}
+	new := edgev1alpha1.EdgeStatus{
+		Containers: []edgev1alpha1.EdgeContainerStatus{
+			{Name: "neuron", Pod: "neuron-0", WaitingReason: "ImagePullBackOff", Message: "pull access denied"},
+		},
+		Conditions: []metav1.Condition{{
+			Type: edgev1alpha1.ConditionVolumesBound, Status: metav1.ConditionFalse, Reason: "Pending", Message: "pending",
+		}},
+	}
+	recordPodWarnings(recorder, ins, old, new)
+	assert.Len(t, recorder.Events, 2)
+	assert.Equal(t, "Warning ImagePullBackOff Container neuron of pod neuron-0 is waiting: pull access denied", <-recorder.Events)
+	assert.Equal(t, "Warning Pending pending", <-recorder.Events)
+
+	// the same failures are not recorded again
+	recordPodWarnings(recorder, ins, new, new)
+	assert.Len(t, recorder.Events, 0)
+
+	restarted := *new.DeepCopy()
+	restarted.Containers[0].RestartCount = 1
+	restarted.Containers[0].LastTerminationReason = "OOMKilled"
+	recordPodWarnings(recorder, ins, new, restarted)
+	assert.Equal(t, "Warning ContainerRestarted Container neuron of pod neuron-0 restarted 1 times, last terminated with OOMKilled", <-recorder.Events)
+}
+
+func TestSetVersions(t *testing.T) {
+	ins := &edgev1alpha1.NeuronEX{Spec: edgev1alpha1.NeuronEXSpec{
+		Neuron:  corev1.Container{Name: "neuron", Image: "emqx/neuron:2.4.0"},
+		EKuiper: corev1.Container{Name: "ekuiper", Image: "lfedge/ekuiper:1.8.0-slim"},
+	}}
+	pod := func(name string, created time.Time, statuses ...corev1.ContainerStatus) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, CreationTimestamp: metav1.NewTime(created)},
+			Status:     corev1.PodStatus{ContainerStatuses: statuses},
+		}
+	}
+	now := time.Now()
+	pods := []corev1.Pod{
+		pod("neuronex-b", now,
+			corev1.ContainerStatus{Name: "neuron", Image: "docker.io/emqx/neuron:2.4.0"},
+			corev1.ContainerStatus{Name: "ekuiper", Image: "sha256:89ab", ImageID: "docker.io/lfedge/ekuiper@sha256:4567", Ready: true},
+		),
+		pod("neuronex-a", now.Add(-time.Minute),
+			corev1.ContainerStatus{Name: "neuron", Image: "docker.io/emqx/neuron:2.3.0", Ready: true},
+			corev1.ContainerStatus{Name: "ekuiper", Image: "docker.io/lfedge/ekuiper:1.8.0-slim", Ready: true},
+		),
+	}
+
+	// the new neuron container is not ready yet, the versions are the ones that run
+	status := edgev1alpha1.EdgeStatus{}
+	setVersions(&status, ins, pods)
+	assert.Equal(t, "2.3.0", status.NeuronVersion)
+	assert.Equal(t, "sha256:4567", status.EKuiperVersion)
+
+	pods[0].Status.ContainerStatuses[0].Ready = true
+	setVersions(&status, ins, pods)
+	assert.Equal(t, "2.4.0", status.NeuronVersion)
+
+	// the versions are kept while no container is ready
+	setVersions(&status, ins, nil)
+	assert.Equal(t, "2.4.0", status.NeuronVersion)
+	assert.Equal(t, "sha256:4567", status.EKuiperVersion)
+}
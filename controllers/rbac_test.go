@@ -0,0 +1,169 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/yaml"
+)
+
+// managerRoles are the rules of the operator role in the kustomize and helm manifests
+func managerRoles(t *testing.T) map[string][]rbacv1.PolicyRule {
+	readRules := func(file string) []rbacv1.PolicyRule {
+		data, err := os.ReadFile(file)
+		assert.Nil(t, err)
+		role := &rbacv1.ClusterRole{}
+		document, _, _ := strings.Cut(string(data), "\n---\n")
+		assert.Nil(t, yaml.Unmarshal([]byte(document), role))
+		return role.Rules
+	}
+
+	roles := map[string][]rbacv1.PolicyRule{}
+	roles["../config/rbac/role.yaml"] = readRules("../config/rbac/role.yaml")
+
+	// a Role grants the namespaced resources only, the nodes are granted by a ClusterRole
+	file := "../config/rbac/namespaced/role.yaml"
+	for _, rule := range readRules(file) {
+		var resources []string
+		for _, resource := range rule.Resources {
+			if resource != "nodes" && resource != "persistentvolumes" {
+				resources = append(resources, resource)
+			}
+		}
+		rule.Resources = resources
+		roles[file] = append(roles[file], rule)
+	}
+	roles[file] = append(roles[file], readRules("../config/rbac/namespaced/node_role.yaml")...)
+
+	// the helm chart shares the rules of the Role and the ClusterRole in a template
+	file = "../deploy/charts/edge-operator/templates/controller-manager-rbac.yaml"
+	data, err := os.ReadFile(file)
+	assert.Nil(t, err)
+	_, rules, found := strings.Cut(string(data), `{{- define "edge-operator.managerRules" }}`)
+	assert.True(t, found)
+	rules, _, found = strings.Cut(rules, "{{- end }}")
+	assert.True(t, found)
+	var chartRules []rbacv1.PolicyRule
+	assert.Nil(t, yaml.Unmarshal([]byte(rules), &chartRules))
+	roles[file] = chartRules
+	return roles
+}
+
+func allowed(rules []rbacv1.PolicyRule, group, resource, verb string) bool {
+	contains := func(values []string, value string) bool {
+		for _, v := range values {
+			if v == value || v == rbacv1.ResourceAll {
+				return true
+			}
+		}
+		return false
+	}
+	for _, rule := range rules {
+		if contains(rule.APIGroups, group) && contains(rule.Resources, resource) && contains(rule.Verbs, verb) {
+			return true
+		}
+	}
+	return false
+}
+
+var errNotAllowed = errors.New("not allowed by the role")
+
+// rbacClient fails the requests that the rules of a role do not allow with Forbidden, like the API server
+type rbacClient struct {
+	client.Client
+	rules []rbacv1.PolicyRule
+}
+
+func (c *rbacClient) check(obj client.Object, subresource, verb string) error {
+	gvk, err := apiutil.GVKForObject(obj, c.Scheme())
+	if err != nil {
+		return err
+	}
+	resource := strings.ToLower(gvk.Kind) + "s"
+	if subresource != "" {
+		resource += "/" + subresource
+	}
+	if !allowed(c.rules, gvk.Group, resource, verb) {
+		return k8sErrors.NewForbidden(schema.GroupResource{Group: gvk.Group, Resource: resource}, obj.GetName(), errNotAllowed)
+	}
+	return nil
+}
+
+func (c *rbacClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+	if err := c.check(obj, "", "get"); err != nil {
+		return err
+	}
+	return c.Client.Get(ctx, key, obj, opts...)
+}
+
+func (c *rbacClient) List(ctx context.Context, list client.ObjectList, opts ...client.ListOption) error {
+	gvk, err := apiutil.GVKForObject(list, c.Scheme())
+	if err != nil {
+		return err
+	}
+	resource := strings.ToLower(strings.TrimSuffix(gvk.Kind, "List")) + "s"
+	if !allowed(c.rules, gvk.Group, resource, "list") {
+		return k8sErrors.NewForbidden(schema.GroupResource{Group: gvk.Group, Resource: resource}, "", errNotAllowed)
+	}
+	return c.Client.List(ctx, list, opts...)
+}
+
+func (c *rbacClient) Create(ctx context.Context, obj client.Object, opts ...client.CreateOption) error {
+	if err := c.check(obj, "", "create"); err != nil {
+		return err
+	}
+	return c.Client.Create(ctx, obj, opts...)
+}
+
+func (c *rbacClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	if err := c.check(obj, "", "update"); err != nil {
+		return err
+	}
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *rbacClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	if err := c.check(obj, "", "patch"); err != nil {
+		return err
+	}
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *rbacClient) Delete(ctx context.Context, obj client.Object, opts ...client.DeleteOption) error {
+	if err := c.check(obj, "", "delete"); err != nil {
+		return err
+	}
+	return c.Client.Delete(ctx, obj, opts...)
+}
+
+func (c *rbacClient) Status() client.StatusWriter {
+	return &rbacStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type rbacStatusWriter struct {
+	client.StatusWriter
+	client *rbacClient
+}
+
+func (w *rbacStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+	if err := w.client.check(obj, "status", "update"); err != nil {
+		return err
+	}
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+func (w *rbacStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.SubResourcePatchOption) error {
+	if err := w.client.check(obj, "status", "patch"); err != nil {
+		return err
+	}
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
@@ -0,0 +1,306 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// dockerHub is the registry of the images without registry, its API is served on dockerHubAPI
+const (
+	dockerHub    = "docker.io"
+	dockerHubAPI = "registry-1.docker.io"
+)
+
+// manifestMediaTypes are the media types of the manifests and indexes that a tag can point to
+var manifestMediaTypes = []string{
+	"application/vnd.oci.image.index.v1+json",
+	"application/vnd.docker.distribution.manifest.list.v2+json",
+	"application/vnd.oci.image.manifest.v1+json",
+	"application/vnd.docker.distribution.manifest.v2+json",
+}
+
+// imageReference is an image split in its registry, repository, and tag or digest
+type imageReference struct {
+	registry   string
+	repository string
+	tag        string
+	digest     string
+}
+
+func parseImage(image string) imageReference {
+	ref := imageReference{}
+	name, digest, _ := strings.Cut(image, "@")
+	ref.digest = digest
+	if i := strings.LastIndex(name, ":"); i > strings.LastIndex(name, "/") {
+		name, ref.tag = name[:i], name[i+1:]
+	}
+	if ref.tag == "" && ref.digest == "" {
+		ref.tag = "latest"
+	}
+
+	first, rest, found := strings.Cut(name, "/")
+	switch {
+	case found && (strings.ContainsAny(first, ".:") || first == "localhost"):
+		ref.registry, ref.repository = first, rest
+	case found:
+		ref.registry, ref.repository = dockerHub, name
+	default:
+		ref.registry, ref.repository = dockerHub, "library/"+name
+	}
+	return ref
+}
+
+// baseURL returns the URL of the registry API, the registries on the loopback interface are served on
+// plain HTTP like docker does
+func (ref imageReference) baseURL() string {
+	host := ref.registry
+	if host == dockerHub {
+		host = dockerHubAPI
+	}
+	hostname := host
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		hostname = h
+	}
+	if ip := net.ParseIP(hostname); hostname == "localhost" || (ip != nil && ip.IsLoopback()) {
+		return "http://" + host
+	}
+	return "https://" + host
+}
+
+// registryAuth is the username and password of a registry
+type registryAuth struct {
+	username string
+	password string
+}
+
+// registryClient resolves the tags of images to digests with the OCI distribution API
+type registryClient struct {
+	auths  map[string]registryAuth
+	client *http.Client
+}
+
+func newRegistryClient(auths map[string]registryAuth) *registryClient {
+	return &registryClient{auths: auths, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+// resolve returns the digest of the manifest that the tag of the image points to, or the digest of an
+// image that already has one
+func (c *registryClient) resolve(ctx context.Context, image string) (string, error) {
+	ref := parseImage(image)
+	if ref.digest != "" {
+		return ref.digest, nil
+	}
+
+	manifestURL := fmt.Sprintf("%s/v2/%s/manifests/%s", ref.baseURL(), ref.repository, ref.tag)
+	resp, err := c.do(ctx, http.MethodHead, manifestURL, ref)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+
+	// the registries that omit the header on HEAD requests are asked for the manifest itself
+	resp, err = c.do(ctx, http.MethodGet, manifestURL, ref)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if digest := resp.Header.Get("Docker-Content-Digest"); digest != "" {
+		return digest, nil
+	}
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", sha256.Sum256(data)), nil
+}
+
+// do sends a request to the registry, it answers the authentication challenge of the registry with the
+// credentials of the registry or anonymously
+func (c *registryClient) do(ctx context.Context, method, url string, ref imageReference) (*http.Response, error) {
+	resp, err := c.send(ctx, method, url, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		resp.Body.Close()
+		authorization, err := c.authorize(ctx, resp.Header.Get("WWW-Authenticate"), ref)
+		if err != nil {
+			return nil, err
+		}
+		if resp, err = c.send(ctx, method, url, authorization); err != nil {
+			return nil, err
+		}
+	}
+	if resp.StatusCode != http.StatusOK {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s %s: %s", method, url, resp.Status)
+	}
+	return resp, nil
+}
+
+func (c *registryClient) send(ctx context.Context, method, url, authorization string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", strings.Join(manifestMediaTypes, ", "))
+	if authorization != "" {
+		req.Header.Set("Authorization", authorization)
+	}
+	return c.client.Do(req)
+}
+
+// authorize returns the Authorization header answering a challenge of the registry
+func (c *registryClient) authorize(ctx context.Context, challenge string, ref imageReference) (string, error) {
+	auth, hasAuth := c.auths[ref.registry]
+	scheme, params := parseChallenge(challenge)
+	switch strings.ToLower(scheme) {
+	case "basic":
+		if !hasAuth {
+			return "", fmt.Errorf("registry %s requires credentials", ref.registry)
+		}
+		return "Basic " + basicAuth(auth), nil
+	case "bearer":
+	default:
+		return "", fmt.Errorf("registry %s requires an unsupported authentication %q", ref.registry, challenge)
+	}
+
+	tokenURL, err := url.Parse(params["realm"])
+	if err != nil || params["realm"] == "" {
+		return "", fmt.Errorf("registry %s has an invalid token realm %q", ref.registry, params["realm"])
+	}
+	query := tokenURL.Query()
+	if params["service"] != "" {
+		query.Set("service", params["service"])
+	}
+	query.Set("scope", fmt.Sprintf("repository:%s:pull", ref.repository))
+	tokenURL.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, tokenURL.String(), nil)
+	if err != nil {
+		return "", err
+	}
+	if hasAuth {
+		req.Header.Set("Authorization", "Basic "+basicAuth(auth))
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a token of registry %s: %s", ref.registry, resp.Status)
+	}
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.Token == "" {
+		token.Token = token.AccessToken
+	}
+	return "Bearer " + token.Token, nil
+}
+
+// parseChallenge splits a WWW-Authenticate header such as `Bearer realm="...",service="..."` in its
+// scheme and parameters
+func parseChallenge(challenge string) (string, map[string]string) {
+	scheme, rest, _ := strings.Cut(strings.TrimSpace(challenge), " ")
+	params := map[string]string{}
+	for rest != "" {
+		var key, value string
+		key, rest, _ = strings.Cut(strings.TrimLeft(rest, " ,"), "=")
+		if strings.HasPrefix(rest, `"`) {
+			value, rest, _ = strings.Cut(rest[1:], `"`)
+		} else {
+			value, rest, _ = strings.Cut(rest, ",")
+		}
+		params[strings.ToLower(strings.TrimSpace(key))] = value
+	}
+	return scheme, params
+}
+
+func basicAuth(auth registryAuth) string {
+	return base64.StdEncoding.EncodeToString([]byte(auth.username + ":" + auth.password))
+}
+
+// getRegistryAuths returns the credentials of the registries from the image pull secrets of the pod
+func getRegistryAuths(ctx context.Context, c client.Reader, ins edgev1alpha1.EdgeInterface) (map[string]registryAuth, error) {
+	auths := map[string]registryAuth{}
+	for _, ref := range getImagePullSecrets(ins) {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: ref.Name}, secret); err != nil {
+			return nil, err
+		}
+
+		var config struct {
+			Auths map[string]dockerConfigEntry `json:"auths"`
+		}
+		switch secret.Type {
+		case corev1.SecretTypeDockerConfigJson:
+			if err := json.Unmarshal(secret.Data[corev1.DockerConfigJsonKey], &config); err != nil {
+				return nil, fmt.Errorf("secret %s: %w", ref.Name, err)
+			}
+		case corev1.SecretTypeDockercfg:
+			if err := json.Unmarshal(secret.Data[corev1.DockerConfigKey], &config.Auths); err != nil {
+				return nil, fmt.Errorf("secret %s: %w", ref.Name, err)
+			}
+		default:
+			continue
+		}
+		for server, entry := range config.Auths {
+			auths[registryHost(server)] = entry.registryAuth()
+		}
+	}
+	return auths, nil
+}
+
+// dockerConfigEntry is the entry of a registry in a docker config file
+type dockerConfigEntry struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+	Auth     string `json:"auth"`
+}
+
+func (e dockerConfigEntry) registryAuth() registryAuth {
+	if e.Username == "" && e.Auth != "" {
+		if data, err := base64.StdEncoding.DecodeString(e.Auth); err == nil {
+			username, password, _ := strings.Cut(string(data), ":")
+			return registryAuth{username: username, password: password}
+		}
+	}
+	return registryAuth{username: e.Username, password: e.Password}
+}
+
+// registryHost returns the registry of a server in a docker config file, such as https://index.docker.io/v1/
+func registryHost(server string) string {
+	host := server
+	if u, err := url.Parse(server); err == nil && u.Host != "" {
+		host = u.Host
+	}
+	host, _, _ = strings.Cut(host, "/")
+	switch host {
+	case "index.docker.io", dockerHubAPI:
+		return dockerHub
+	}
+	return host
+}
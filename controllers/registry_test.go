@@ -0,0 +1,93 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseImage(t *testing.T) {
+	assert.Equal(t, imageReference{registry: "docker.io", repository: "library/busybox", tag: "latest"}, parseImage("busybox"))
+	assert.Equal(t, imageReference{registry: "docker.io", repository: "emqx/neuron", tag: "2.3.0"}, parseImage("emqx/neuron:2.3.0"))
+	assert.Equal(t, imageReference{registry: "localhost:5000", repository: "emqx/neuron", tag: "2.3.0", digest: "sha256:0123"},
+		parseImage("localhost:5000/emqx/neuron:2.3.0@sha256:0123"))
+	assert.Equal(t, imageReference{registry: "quay.io", repository: "prometheus/busybox", digest: "sha256:0123"},
+		parseImage("quay.io/prometheus/busybox@sha256:0123"))
+
+	assert.Equal(t, "https://registry-1.docker.io", parseImage("busybox").baseURL())
+	assert.Equal(t, "http://127.0.0.1:5000", parseImage("127.0.0.1:5000/busybox").baseURL())
+	assert.Equal(t, "https://registry.example.com:5000", parseImage("registry.example.com:5000/busybox").baseURL())
+}
+
+func TestParseChallenge(t *testing.T) {
+	scheme, params := parseChallenge(`Bearer realm="https://auth.docker.io/token",service="registry.docker.io",scope="repository:library/busybox:pull"`)
+	assert.Equal(t, "Bearer", scheme)
+	assert.Equal(t, map[string]string{
+		"realm":   "https://auth.docker.io/token",
+		"service": "registry.docker.io",
+		"scope":   "repository:library/busybox:pull",
+	}, params)
+}
+
+func TestRegistryHost(t *testing.T) {
+	assert.Equal(t, "docker.io", registryHost("https://index.docker.io/v1/"))
+	assert.Equal(t, "registry.example.com:5000", registryHost("registry.example.com:5000"))
+	assert.Equal(t, "registry.example.com", registryHost("https://registry.example.com"))
+	assert.Equal(t, registryAuth{username: "user", password: "pass"}, dockerConfigEntry{Auth: "dXNlcjpwYXNz"}.registryAuth())
+}
+
+// newFakeRegistry serves the digests of the tags of its repositories behind a token authentication, like
+// Docker Hub does
+func newFakeRegistry(t *testing.T, digests map[string]string) (*httptest.Server, *int) {
+	resolved := new(int)
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			username, password, _ := r.BasicAuth()
+			assert.Equal(t, "user", username)
+			assert.Equal(t, "pass", password)
+			assert.Equal(t, "registry.test", r.URL.Query().Get("service"))
+			_, _ = w.Write([]byte(`{"token": "secret"}`))
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry.test"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		assert.Contains(t, r.Header.Get("Accept"), "application/vnd.oci.image.index.v1+json")
+		repository, tag, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, "/v2/"), "/manifests/")
+		digest, ok := digests[repository+":"+tag]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		*resolved++
+		w.Header().Set("Docker-Content-Digest", digest)
+	}))
+	return server, resolved
+}
+
+func TestRegistryClientResolve(t *testing.T) {
+	server, _ := newFakeRegistry(t, map[string]string{"emqx/neuron:2.3.0": "sha256:0123"})
+	defer server.Close()
+	host := strings.TrimPrefix(server.URL, "http://")
+
+	c := newRegistryClient(map[string]registryAuth{host: {username: "user", password: "pass"}})
+	digest, err := c.resolve(context.Background(), host+"/emqx/neuron:2.3.0")
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:0123", digest)
+
+	_, err = c.resolve(context.Background(), host+"/emqx/neuron:2.4.0")
+	assert.ErrorContains(t, err, "404 Not Found")
+
+	// an image with a digest is not resolved
+	digest, err = c.resolve(context.Background(), "registry.invalid/emqx/neuron:2.3.0@sha256:4567")
+	assert.Nil(t, err)
+	assert.Equal(t, "sha256:4567", digest)
+}
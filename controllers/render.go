@@ -0,0 +1,14 @@
+package controllers
+
+import (
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Render returns the child resources that the operator creates for the instance, in the order
+// they are reconciled. It does not need a cluster, the instance is expected to be defaulted
+// by the webhook beforehand. The Secrets and ConfigMaps referenced by the pod are not read, so
+// they are left out of the config hash of the pod template.
+func Render(ins edgev1alpha1.EdgeInterface) []client.Object {
+	return append(getCreatedObjects(ins), getDesiredObjects(ins, nil)...)
+}
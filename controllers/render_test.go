@@ -0,0 +1,59 @@
+package controllers
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"sigs.k8s.io/yaml"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the render tests")
+
+func TestRender(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, edgev1alpha1.AddToScheme(scheme))
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+
+	inputs, err := filepath.Glob(filepath.Join("testdata", "render", "*.yaml"))
+	assert.Nil(t, err)
+	for _, input := range inputs {
+		if strings.HasSuffix(input, ".golden.yaml") {
+			continue
+		}
+
+		t.Run(filepath.Base(input), func(t *testing.T) {
+			data, err := os.ReadFile(input)
+			assert.Nil(t, err)
+			obj, _, err := decoder.Decode(data, nil, nil)
+			assert.Nil(t, err)
+
+			ins := obj.(edgev1alpha1.EdgeInterface)
+			ins.Default()
+			assert.Nil(t, ins.ValidateCreate())
+
+			got := &bytes.Buffer{}
+			for _, child := range Render(ins) {
+				out, err := yaml.Marshal(child)
+				assert.Nil(t, err)
+				got.WriteString("---\n")
+				got.Write(out)
+			}
+
+			golden := strings.TrimSuffix(input, ".yaml") + ".golden.yaml"
+			if *updateGolden {
+				assert.Nil(t, os.WriteFile(golden, got.Bytes(), 0644))
+			}
+			want, err := os.ReadFile(golden)
+			assert.Nil(t, err)
+			assert.Equal(t, string(want), got.String())
+		})
+	}
+}
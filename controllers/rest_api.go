@@ -0,0 +1,75 @@
+package controllers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// restAPI is a JSON client of the REST API of a pod
+type restAPI struct {
+	baseURL string
+	token   string
+	client  *http.Client
+}
+
+// restError is a response with an unexpected status
+type restError struct {
+	statusCode int
+	message    string
+}
+
+func (e *restError) Error() string {
+	return e.message
+}
+
+func isRESTNotFound(err error) bool {
+	var restErr *restError
+	return errors.As(err, &restErr) && restErr.statusCode == http.StatusNotFound
+}
+
+func newRESTAPI(baseURL, token string) restAPI {
+	return restAPI{baseURL: baseURL, token: token, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (api restAPI) do(ctx context.Context, method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, api.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if api.token != "" {
+		req.Header.Set("Authorization", "Bearer "+api.token)
+	}
+
+	resp, err := api.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &restError{statusCode: resp.StatusCode,
+			message: fmt.Sprintf("%s %s: %s: %s", method, path, resp.Status, bytes.TrimSpace(data))}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,287 @@
+package controllers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/emqx/edge-operator/internal"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+type rollbackEKuiper struct{}
+
+func (rb rollbackEKuiper) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.EKuiper) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"roll back eKuiper")
+	return rollback(ctx, r, instance, logger)
+}
+
+type rollbackNeuron struct{}
+
+func (rb rollbackNeuron) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"roll back Neuron")
+	return rollback(ctx, r, instance, logger)
+}
+
+type rollbackNeuronEX struct{}
+
+func (rb rollbackNeuronEX) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.NeuronEX) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"roll back NeuronEX")
+	return rollback(ctx, r, instance, logger)
+}
+
+type recordEKuiperRevision struct{}
+
+func (rr recordEKuiperRevision) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.EKuiper) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"record eKuiper revision")
+	return recordRevision(ctx, r, instance, logger)
+}
+
+type recordNeuronRevision struct{}
+
+func (rr recordNeuronRevision) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"record Neuron revision")
+	return recordRevision(ctx, r, instance, logger)
+}
+
+type recordNeuronEXRevision struct{}
+
+func (rr recordNeuronEXRevision) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.NeuronEX) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"record NeuronEX revision")
+	return recordRevision(ctx, r, instance, logger)
+}
+
+// recordRevision stores the spec that was reconciled in a ControllerRevision owned by the instance, like the
+// revisions of a StatefulSet. A spec that was recorded before becomes the newest revision again. The revision
+// is the last good one once the pods of the Deployment are all updated and ready, but not while its pod
+// template is held for a maintenance window.
+func recordRevision(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	data, err := getRevisionData(ins)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+	hash := sha256.Sum256(data)
+	name := internal.GetResNameOnPanic(ins, hex.EncodeToString(hash[:])[:10])
+
+	revisions, err := getRevisions(ctx, r.Client, ins)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+	var current *appsv1.ControllerRevision
+	var latest int64
+	for i := range revisions {
+		if revisions[i].Name == name {
+			current = &revisions[i]
+		}
+		if revisions[i].Revision > latest {
+			latest = revisions[i].Revision
+		}
+	}
+
+	if current == nil {
+		current = &appsv1.ControllerRevision{
+			ObjectMeta: internal.GetObjectMetadata(ins, name),
+			Data:       runtime.RawExtension{Raw: data},
+			Revision:   latest + 1,
+		}
+		if err := ctrl.SetControllerReference(ins, current, r.Scheme()); err != nil {
+			return &requeue{curError: err}
+		}
+		logger.Info("Create ControllerRevision", "name", name, "revision", current.Revision)
+		if err := r.Create(ctx, current); err != nil {
+			return &requeue{curError: err}
+		}
+		revisions = append(revisions, *current)
+	} else if current.Revision != latest {
+		patch := client.MergeFrom(current.DeepCopy())
+		current.Revision = latest + 1
+		logger.Info("Update ControllerRevision", "name", name, "revision", current.Revision)
+		if err := r.Patch(ctx, current, patch); err != nil {
+			return &requeue{curError: err}
+		}
+	}
+
+	status := ins.GetStatus()
+	lastGood := status.LastGoodRevision
+	if status.Phase == edgev1alpha1.CRReady &&
+		!meta.IsStatusConditionTrue(status.Conditions, edgev1alpha1.ConditionPendingRestart) {
+		deploy := &appsv1.Deployment{}
+		err := r.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: getDeploymentName(ins)}, deploy)
+		if err != nil && !k8sErrors.IsNotFound(err) {
+			return &requeue{curError: err}
+		}
+		if err == nil && isRolledOut(ins, deploy) {
+			lastGood = name
+		}
+	}
+	if status.CurrentRevision != name || status.LastGoodRevision != lastGood {
+		status.CurrentRevision = name
+		status.LastGoodRevision = lastGood
+		ins.SetStatus(&status)
+		if err := r.Status().Update(ctx, ins); err != nil {
+			return &requeue{curError: err}
+		}
+	}
+
+	// the oldest revisions beyond the limit are deleted, but never the current and the last good one
+	sort.Slice(revisions, func(i, j int) bool { return revisions[i].Revision < revisions[j].Revision })
+	extra := len(revisions) - int(ins.GetRevisionHistoryLimit())
+	for i := 0; i < len(revisions) && extra > 0; i++ {
+		if revisions[i].Name == name || revisions[i].Name == lastGood {
+			continue
+		}
+		logger.Info("Delete ControllerRevision", "name", revisions[i].Name, "revision", revisions[i].Revision)
+		if err := r.Delete(ctx, &revisions[i]); err != nil && !k8sErrors.IsNotFound(err) {
+			return &requeue{curError: err}
+		}
+		extra--
+	}
+	return nil
+}
+
+// rollback restores the spec of the revision named by the rollback annotation and removes the annotation. The
+// apply now annotation is set, so that the restored pod template is not held for a maintenance window.
+func rollback(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	value, ok := ins.GetAnnotations()[edgev1alpha1.RollbackAnnotation]
+	if !ok {
+		return nil
+	}
+
+	revision, err := findRollbackRevision(ctx, r.Client, ins, value)
+	if err != nil {
+		return rollbackFailed(ctx, r, ins, err)
+	}
+	var data struct {
+		Spec interface{} `json:"spec"`
+	}
+	if err := json.Unmarshal(revision.Data.Raw, &data); err != nil {
+		return rollbackFailed(ctx, r, ins, fmt.Errorf("revision %d is invalid: %w", revision.Revision, err))
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ins)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+	obj := &unstructured.Unstructured{Object: content}
+	gvk, err := apiutil.GVKForObject(ins, r.Scheme())
+	if err != nil {
+		return &requeue{curError: err}
+	}
+	obj.SetGroupVersionKind(gvk)
+	obj.Object["spec"] = data.Spec
+	// the restored spec is applied at once, even outside of the maintenance windows
+	annotations := obj.GetAnnotations()
+	delete(annotations, edgev1alpha1.RollbackAnnotation)
+	annotations[edgev1alpha1.ApplyNowAnnotation] = "true"
+	obj.SetAnnotations(annotations)
+
+	logger.Info("Roll back", "revision", revision.Revision, "name", revision.Name)
+	if err := r.Update(ctx, obj); err != nil {
+		if k8sErrors.IsInvalid(err) || k8sErrors.IsForbidden(err) || k8sErrors.IsBadRequest(err) {
+			return rollbackFailed(ctx, r, ins, fmt.Errorf("revision %d is rejected: %w", revision.Revision, err))
+		}
+		return &requeue{curError: err}
+	}
+	r.Recorder.Eventf(ins, corev1.EventTypeNormal, "RolledBack", "Restored the spec of revision %d", revision.Revision)
+	// the restored spec is reconciled next
+	return &requeue{message: fmt.Sprintf("rolled back to revision %d", revision.Revision)}
+}
+
+// findRollbackRevision returns the revision numbered by the value of the rollback annotation, or the last
+// good revision
+func findRollbackRevision(ctx context.Context, c client.Reader, ins edgev1alpha1.EdgeInterface, value string) (
+	*appsv1.ControllerRevision, error) {
+	number, err := edgev1alpha1.ParseRollback(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q %w", edgev1alpha1.RollbackAnnotation, value, err)
+	}
+	revisions, err := getRevisions(ctx, c, ins)
+	if err != nil {
+		return nil, err
+	}
+	for i := range revisions {
+		if number == 0 && revisions[i].Name == ins.GetStatus().LastGoodRevision ||
+			number != 0 && revisions[i].Revision == number {
+			return &revisions[i], nil
+		}
+	}
+	if number == 0 {
+		return nil, fmt.Errorf("there is no last good revision")
+	}
+	return nil, fmt.Errorf("revision %d is not found", number)
+}
+
+// rollbackFailed reports a rollback that cannot be done and removes the annotation, so that the current
+// spec keeps being reconciled
+func rollbackFailed(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, err error) *requeue {
+	r.Recorder.Eventf(ins, corev1.EventTypeWarning, "RollbackFailed", "Cannot roll back: %s", err)
+	patch := client.MergeFrom(ins.DeepCopyObject().(client.Object))
+	annotations := ins.GetAnnotations()
+	delete(annotations, edgev1alpha1.RollbackAnnotation)
+	ins.SetAnnotations(annotations)
+	if err := r.Patch(ctx, ins, patch); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
+
+// getRevisions returns the ControllerRevisions of the instance
+func getRevisions(ctx context.Context, c client.Reader, ins edgev1alpha1.EdgeInterface) ([]appsv1.ControllerRevision, error) {
+	list := &appsv1.ControllerRevisionList{}
+	if err := c.List(ctx, list, client.InNamespace(ins.GetNamespace()), client.MatchingLabels{
+		edgev1alpha1.InstanceKey:  ins.GetName(),
+		edgev1alpha1.ComponentKey: string(ins.GetComponentType()),
+	}); err != nil {
+		return nil, err
+	}
+	var revisions []appsv1.ControllerRevision
+	for i := range list.Items {
+		if metav1.IsControlledBy(&list.Items[i], ins) {
+			revisions = append(revisions, list.Items[i])
+		}
+	}
+	return revisions, nil
+}
+
+// getRevisionData returns the JSON encoding of the spec of the instance
+func getRevisionData(ins edgev1alpha1.EdgeInterface) ([]byte, error) {
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ins)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(map[string]interface{}{"spec": content["spec"]})
+}
+
+// isRolledOut returns whether all the pods of the Deployment run its latest template and are ready, but for
+// the standby pod in high availability mode
+func isRolledOut(ins edgev1alpha1.EdgeInterface, deploy *appsv1.Deployment) bool {
+	replicas := int32(1)
+	if deploy.Spec.Replicas != nil {
+		replicas = *deploy.Spec.Replicas
+	}
+	return deploy.Status.ObservedGeneration >= deploy.Generation &&
+		deploy.Status.UpdatedReplicas == replicas &&
+		deploy.Status.ReadyReplicas >= getWantedReadyReplicas(ins, replicas) &&
+		deploy.Status.Replicas == replicas
+}
@@ -0,0 +1,110 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newRevisionTest(t *testing.T) (*EdgeController, *edgev1alpha1.Neuron) {
+	ins := getFakeNeuron()
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default", Generation: 1},
+		Spec:       appsv1.DeploymentSpec{Replicas: &[]int32{1}[0]},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 1, Replicas: 1, UpdatedReplicas: 1, ReadyReplicas: 1},
+	}
+	return newFakeController(t, ins, deploy), ins
+}
+
+func TestRecordRevision(t *testing.T) {
+	r, ins := newRevisionTest(t)
+	ctx := context.Background()
+	revisions := func() map[string]int64 {
+		list, err := getRevisions(ctx, r.Client, ins)
+		assert.Nil(t, err)
+		result := map[string]int64{}
+		for _, revision := range list {
+			result[revision.Name] = revision.Revision
+		}
+		return result
+	}
+
+	// the first revision is not good until the instance is ready
+	assert.Nil(t, recordRevision(ctx, r, ins, log))
+	first := ins.Status.CurrentRevision
+	assert.Equal(t, map[string]int64{first: 1}, revisions())
+	assert.Empty(t, ins.Status.LastGoodRevision)
+
+	ins.Status.Phase = edgev1alpha1.CRReady
+	assert.Nil(t, recordRevision(ctx, r, ins, log))
+	assert.Equal(t, first, ins.Status.LastGoodRevision)
+
+	// a new spec is recorded, the last good revision is kept until its pods are ready
+	ins.Status.Phase = edgev1alpha1.CRNotReady
+	ins.Spec.Neuron.Image = "emqx/neuron:2.4.0"
+	assert.Nil(t, recordRevision(ctx, r, ins, log))
+	second := ins.Status.CurrentRevision
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, first, ins.Status.LastGoodRevision)
+	assert.Equal(t, map[string]int64{first: 1, second: 2}, revisions())
+
+	// a spec whose pod template is held is not good, though the pods of the previous one are ready
+	ins.Status.Phase = edgev1alpha1.CRReady
+	meta.SetStatusCondition(&ins.Status.Conditions, metav1.Condition{Type: edgev1alpha1.ConditionPendingRestart,
+		Status: metav1.ConditionTrue, Reason: "WaitingForMaintenanceWindow"})
+	assert.Nil(t, recordRevision(ctx, r, ins, log))
+	assert.Equal(t, first, ins.Status.LastGoodRevision)
+	meta.RemoveStatusCondition(&ins.Status.Conditions, edgev1alpha1.ConditionPendingRestart)
+	ins.Status.Phase = edgev1alpha1.CRNotReady
+
+	// a spec recorded before becomes the newest revision
+	ins.Spec.Neuron.Image = "emqx/neuron:2.3.0"
+	assert.Nil(t, recordRevision(ctx, r, ins, log))
+	assert.Equal(t, first, ins.Status.CurrentRevision)
+	assert.Equal(t, map[string]int64{first: 3, second: 2}, revisions())
+
+	// the history limit keeps the current and the last good revision
+	ins.Spec.RevisionHistoryLimit = &[]int32{1}[0]
+	assert.Nil(t, recordRevision(ctx, r, ins, log))
+	third := ins.Status.CurrentRevision
+	assert.Equal(t, map[string]int64{first: 3, third: 4}, revisions())
+}
+
+func TestRollback(t *testing.T) {
+	r, ins := newRevisionTest(t)
+	ctx := context.Background()
+	ins.Status.Phase = edgev1alpha1.CRReady
+	assert.Nil(t, recordRevision(ctx, r, ins, log))
+	ins.Status.Phase = edgev1alpha1.CRNotReady
+	ins.Spec.Neuron.Image = "emqx/neuron:2.4.0"
+	ins.Spec.Neuron.Args = []string{"--log"}
+	assert.Nil(t, recordRevision(ctx, r, ins, log))
+
+	ins.Annotations = map[string]string{edgev1alpha1.RollbackAnnotation: edgev1alpha1.RollbackToLastGood}
+	assert.Nil(t, r.Update(ctx, ins))
+	req := rollback(ctx, r, ins, log)
+	assert.NotNil(t, req)
+	assert.Equal(t, "rolled back to revision 1", req.message)
+
+	restored := &edgev1alpha1.Neuron{}
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(ins), restored))
+	assert.Equal(t, "emqx/neuron:2.3.0", restored.Spec.Neuron.Image)
+	assert.Empty(t, restored.Spec.Neuron.Args)
+	assert.NotContains(t, restored.Annotations, edgev1alpha1.RollbackAnnotation)
+	assert.Equal(t, "true", restored.Annotations[edgev1alpha1.ApplyNowAnnotation])
+	assert.Equal(t, edgev1alpha1.CRNotReady, restored.Status.Phase)
+
+	// a missing revision is reported and the annotation is removed
+	restored.Annotations = map[string]string{edgev1alpha1.RollbackAnnotation: "5"}
+	assert.Nil(t, r.Update(ctx, restored))
+	assert.Nil(t, rollback(ctx, r, restored, log))
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(ins), restored))
+	assert.NotContains(t, restored.Annotations, edgev1alpha1.RollbackAnnotation)
+	assert.Equal(t, "emqx/neuron:2.3.0", restored.Spec.Neuron.Image)
+}
@@ -0,0 +1,317 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	emperror "emperror.dev/errors"
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// fieldManager is the field manager used by the operator for server-side apply
+const fieldManager = "edge-operator"
+
+// apply creates or updates the object with server-side apply, only the fields set in newObj are owned by the operator.
+// The object is sent without its status, null fields and the empty objects of the zero structs, so that the zero
+// values of the typed struct are not owned either.
+func (ec *EdgeController) apply(ctx context.Context, owner, newObj client.Object) error {
+	if err := ctrl.SetControllerReference(owner, newObj, ec.Scheme()); err != nil {
+		return emperror.Wrapf(err, "failed to set controller reference for %s %s",
+			newObj.GetObjectKind().GroupVersionKind().Kind, newObj.GetName())
+	}
+
+	obj, err := getApplyObject(newObj, ec.Scheme())
+	if err != nil {
+		return emperror.Wrapf(err, "failed to convert %s %s", newObj.GetObjectKind().GroupVersionKind().Kind,
+			newObj.GetName())
+	}
+	if err := ec.Patch(ctx, obj, client.Apply, client.FieldOwner(fieldManager), client.ForceOwnership); err != nil {
+		return emperror.Wrapf(err, "failed to apply %s %s", obj.GetKind(), obj.GetName())
+	}
+	return nil
+}
+
+// getApplyObject returns the unstructured apply configuration of the object
+func getApplyObject(obj client.Object, scheme *runtime.Scheme) (*unstructured.Unstructured, error) {
+	gvk, err := apiutil.GVKForObject(obj, scheme)
+	if err != nil {
+		return nil, err
+	}
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(obj)
+	if err != nil {
+		return nil, err
+	}
+	delete(content, "status")
+	pruneEmptyFields(content, reflect.TypeOf(obj))
+
+	applyObj := &unstructured.Unstructured{Object: content}
+	applyObj.SetGroupVersionKind(gvk)
+	applyObj.SetResourceVersion("")
+	applyObj.SetManagedFields(nil)
+	return applyObj, nil
+}
+
+// pruneEmptyFields removes the null fields and the empty objects of the struct fields that are not pointers,
+// which the conversion of the typed object always writes. The empty objects of pointer fields are set on
+// purpose and are kept, such as the emptyDir source of a volume or a labelSelector matching all the pods.
+// The empty objects of unknown fields are kept too.
+func pruneEmptyFields(content map[string]interface{}, typ reflect.Type) {
+	fields := getJSONFields(typ)
+	for key, value := range content {
+		fieldType, known := fields[key]
+		switch value := value.(type) {
+		case nil:
+			delete(content, key)
+		case map[string]interface{}:
+			isPointer := known && fieldType.Kind() == reflect.Pointer
+			for known && fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			switch {
+			case known && fieldType.Kind() == reflect.Struct:
+				pruneEmptyFields(value, fieldType)
+			case known && fieldType.Kind() == reflect.Map:
+				for _, item := range value {
+					if item, ok := item.(map[string]interface{}); ok {
+						pruneEmptyFields(item, fieldType.Elem())
+					}
+				}
+			default:
+				pruneEmptyFields(value, nil)
+			}
+			if len(value) == 0 && known && !isPointer {
+				delete(content, key)
+			}
+		case []interface{}:
+			var itemType reflect.Type
+			if known && fieldType.Kind() == reflect.Slice {
+				itemType = fieldType.Elem()
+			}
+			for _, item := range value {
+				if item, ok := item.(map[string]interface{}); ok {
+					pruneEmptyFields(item, itemType)
+				}
+			}
+		}
+	}
+}
+
+// getJSONFields returns the types of the fields of a struct by their JSON name, including the fields of the
+// inlined structs
+func getJSONFields(typ reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for typ != nil && typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if typ == nil || typ.Kind() != reflect.Struct {
+		return fields
+	}
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if name == "" && (field.Anonymous || strings.Contains(opts, "inline")) {
+			for inlined, inlinedType := range getJSONFields(field.Type) {
+				fields[inlined] = inlinedType
+			}
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// isReplicasOwnedByOthers returns whether an external scaler, such as a HorizontalPodAutoscaler, owns the
+// replicas of the existing object through the scale subresource. The replicas set once by kubectl scale or
+// kubectl edit are not yielded, the operator takes them back.
+func isReplicasOwnedByOthers(existingObj client.Object) (bool, error) {
+	for _, entry := range existingObj.GetManagedFields() {
+		if entry.Manager == fieldManager || entry.Subresource != "scale" || strings.HasPrefix(entry.Manager, "kubectl") ||
+			entry.FieldsV1 == nil {
+			continue
+		}
+		fields := map[string]interface{}{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+			return false, err
+		}
+		if hasField(fields, []string{"f:spec", "f:replicas"}) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// migrateToServerSideApply hands the fields of an object that was created or updated
+// with the last-applied-configuration annotation over to the server-side apply field manager,
+// so that fields removed from the desired state are pruned instead of being left behind.
+func (ec *EdgeController) migrateToServerSideApply(ctx context.Context, existingObj client.Object, logger logr.Logger) error {
+	upgraded, err := upgradeManagedFields(existingObj)
+	if err != nil {
+		return emperror.Wrapf(err, "failed to upgrade managed fields for %s %s",
+			existingObj.GetObjectKind().GroupVersionKind().Kind, existingObj.GetName())
+	}
+	if !upgraded {
+		return nil
+	}
+
+	logger.Info("Migrate "+existingObj.GetName()+" to server-side apply",
+		"kind", existingObj.GetObjectKind().GroupVersionKind().Kind)
+	if err := ec.Update(ctx, existingObj); err != nil {
+		return emperror.Wrapf(err, "failed to migrate %s %s to server-side apply",
+			existingObj.GetObjectKind().GroupVersionKind().Kind, existingObj.GetName())
+	}
+	return nil
+}
+
+// upgradeManagedFields removes the last-applied-configuration annotation and converts the
+// update entries of the manager that wrote it into an apply entry owned by fieldManager.
+// It returns false if the object does not carry the annotation.
+func upgradeManagedFields(obj client.Object) (bool, error) {
+	annotations := obj.GetAnnotations()
+	if _, ok := annotations[lastAppliedAnnotation]; !ok {
+		return false, nil
+	}
+	delete(annotations, lastAppliedAnnotation)
+	obj.SetAnnotations(annotations)
+
+	annotationPath := []string{"f:metadata", "f:annotations", "f:" + lastAppliedAnnotation}
+
+	var applyEntry *metav1.ManagedFieldsEntry
+	var applyFields map[string]interface{}
+	var managedFields []metav1.ManagedFieldsEntry
+	for _, entry := range obj.GetManagedFields() {
+		fields := map[string]interface{}{}
+		if entry.FieldsV1 != nil {
+			if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+				return false, err
+			}
+		}
+
+		isApplyEntry := entry.Manager == fieldManager && entry.Operation == metav1.ManagedFieldsOperationApply
+		isLastApplied := entry.Operation == metav1.ManagedFieldsOperationUpdate && entry.Subresource == "" &&
+			hasField(fields, annotationPath)
+		if !isApplyEntry && !isLastApplied {
+			managedFields = append(managedFields, entry)
+			continue
+		}
+
+		if applyEntry == nil {
+			applyEntry = entry.DeepCopy()
+			applyFields = map[string]interface{}{}
+		}
+		mergeFields(applyFields, fields)
+	}
+
+	if applyEntry != nil {
+		removeField(applyFields, annotationPath)
+		raw, err := json.Marshal(applyFields)
+		if err != nil {
+			return false, err
+		}
+		applyEntry.Manager = fieldManager
+		applyEntry.Operation = metav1.ManagedFieldsOperationApply
+		applyEntry.FieldsType = "FieldsV1"
+		applyEntry.FieldsV1 = &metav1.FieldsV1{Raw: raw}
+		managedFields = append(managedFields, *applyEntry)
+	}
+	obj.SetManagedFields(managedFields)
+	return true, nil
+}
+
+// takeOverManagedFields hands the fields set by all the managers of an adopted object over to the
+// server-side apply field manager, so that the fields the operator does not generate are pruned
+func takeOverManagedFields(obj client.Object) error {
+	var applyEntry *metav1.ManagedFieldsEntry
+	applyFields := map[string]interface{}{}
+	var managedFields []metav1.ManagedFieldsEntry
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Subresource != "" {
+			managedFields = append(managedFields, entry)
+			continue
+		}
+		fields := map[string]interface{}{}
+		if entry.FieldsV1 != nil {
+			if err := json.Unmarshal(entry.FieldsV1.Raw, &fields); err != nil {
+				return err
+			}
+		}
+		if applyEntry == nil {
+			applyEntry = entry.DeepCopy()
+		}
+		mergeFields(applyFields, fields)
+	}
+	if applyEntry == nil {
+		return nil
+	}
+
+	raw, err := json.Marshal(applyFields)
+	if err != nil {
+		return err
+	}
+	applyEntry.Manager = fieldManager
+	applyEntry.Operation = metav1.ManagedFieldsOperationApply
+	applyEntry.FieldsType = "FieldsV1"
+	applyEntry.FieldsV1 = &metav1.FieldsV1{Raw: raw}
+	obj.SetManagedFields(append(managedFields, *applyEntry))
+	return nil
+}
+
+func hasField(fields map[string]interface{}, path []string) bool {
+	for i, key := range path {
+		value, ok := fields[key]
+		if !ok {
+			return false
+		}
+		if i == len(path)-1 {
+			return true
+		}
+		if fields, ok = value.(map[string]interface{}); !ok {
+			return false
+		}
+	}
+	return false
+}
+
+func removeField(fields map[string]interface{}, path []string) {
+	if len(path) == 0 {
+		return
+	}
+	if len(path) == 1 {
+		delete(fields, path[0])
+		return
+	}
+	child, ok := fields[path[0]].(map[string]interface{})
+	if !ok {
+		return
+	}
+	removeField(child, path[1:])
+	if len(child) == 0 {
+		delete(fields, path[0])
+	}
+}
+
+// mergeFields merges the FieldsV1 set src into dst
+func mergeFields(dst, src map[string]interface{}) {
+	for key, value := range src {
+		srcChild, srcOK := value.(map[string]interface{})
+		dstChild, dstOK := dst[key].(map[string]interface{})
+		if srcOK && dstOK {
+			mergeFields(dstChild, srcChild)
+			continue
+		}
+		dst[key] = value
+	}
+}
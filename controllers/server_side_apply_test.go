@@ -0,0 +1,264 @@
+package controllers
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+)
+
+func TestUpgradeManagedFields(t *testing.T) {
+	fieldsV1 := func(fields map[string]interface{}) *metav1.FieldsV1 {
+		raw, _ := json.Marshal(fields)
+		return &metav1.FieldsV1{Raw: raw}
+	}
+
+	t.Run("should do nothing without last applied annotation", func(t *testing.T) {
+		obj := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{"foo": "bar"},
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{Manager: "manager", Operation: metav1.ManagedFieldsOperationUpdate},
+				},
+			},
+		}
+		upgraded, err := upgradeManagedFields(obj)
+		assert.Nil(t, err)
+		assert.False(t, upgraded)
+		assert.Equal(t, map[string]string{"foo": "bar"}, obj.Annotations)
+		assert.Len(t, obj.ManagedFields, 1)
+	})
+
+	t.Run("should convert last applied manager to apply entry", func(t *testing.T) {
+		obj := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{
+					"foo":                 "bar",
+					lastAppliedAnnotation: "{}",
+				},
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{
+						Manager:    "manager",
+						Operation:  metav1.ManagedFieldsOperationUpdate,
+						APIVersion: "v1",
+						FieldsType: "FieldsV1",
+						FieldsV1: fieldsV1(map[string]interface{}{
+							"f:data": map[string]interface{}{"f:init.json": map[string]interface{}{}},
+							"f:metadata": map[string]interface{}{
+								"f:annotations": map[string]interface{}{
+									"f:" + lastAppliedAnnotation: map[string]interface{}{},
+								},
+							},
+						}),
+					},
+					{
+						Manager:    "kubectl-edit",
+						Operation:  metav1.ManagedFieldsOperationUpdate,
+						APIVersion: "v1",
+						FieldsType: "FieldsV1",
+						FieldsV1: fieldsV1(map[string]interface{}{
+							"f:metadata": map[string]interface{}{
+								"f:annotations": map[string]interface{}{"f:foo": map[string]interface{}{}},
+							},
+						}),
+					},
+				},
+			},
+		}
+		upgraded, err := upgradeManagedFields(obj)
+		assert.Nil(t, err)
+		assert.True(t, upgraded)
+		assert.Equal(t, map[string]string{"foo": "bar"}, obj.Annotations)
+		assert.Len(t, obj.ManagedFields, 2)
+
+		assert.Equal(t, "kubectl-edit", obj.ManagedFields[0].Manager)
+		assert.Equal(t, metav1.ManagedFieldsOperationUpdate, obj.ManagedFields[0].Operation)
+
+		entry := obj.ManagedFields[1]
+		assert.Equal(t, fieldManager, entry.Manager)
+		assert.Equal(t, metav1.ManagedFieldsOperationApply, entry.Operation)
+		assert.Equal(t, "v1", entry.APIVersion)
+		assert.JSONEq(t, `{"f:data":{"f:init.json":{}}}`, string(entry.FieldsV1.Raw))
+	})
+
+	t.Run("should merge into the existing apply entry", func(t *testing.T) {
+		obj := &corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{
+				Annotations: map[string]string{lastAppliedAnnotation: "{}"},
+				ManagedFields: []metav1.ManagedFieldsEntry{
+					{
+						Manager:    fieldManager,
+						Operation:  metav1.ManagedFieldsOperationApply,
+						APIVersion: "v1",
+						FieldsType: "FieldsV1",
+						FieldsV1: fieldsV1(map[string]interface{}{
+							"f:data": map[string]interface{}{"f:a": map[string]interface{}{}},
+						}),
+					},
+					{
+						Manager:    "manager",
+						Operation:  metav1.ManagedFieldsOperationUpdate,
+						APIVersion: "v1",
+						FieldsType: "FieldsV1",
+						FieldsV1: fieldsV1(map[string]interface{}{
+							"f:data": map[string]interface{}{"f:b": map[string]interface{}{}},
+							"f:metadata": map[string]interface{}{
+								"f:annotations": map[string]interface{}{
+									"f:" + lastAppliedAnnotation: map[string]interface{}{},
+								},
+							},
+						}),
+					},
+				},
+			},
+		}
+		upgraded, err := upgradeManagedFields(obj)
+		assert.Nil(t, err)
+		assert.True(t, upgraded)
+		assert.Empty(t, obj.Annotations)
+		assert.Len(t, obj.ManagedFields, 1)
+		assert.Equal(t, fieldManager, obj.ManagedFields[0].Manager)
+		assert.JSONEq(t, `{"f:data":{"f:a":{},"f:b":{}}}`, string(obj.ManagedFields[0].FieldsV1.Raw))
+	})
+}
+
+func TestTakeOverManagedFields(t *testing.T) {
+	fieldsV1 := func(fields map[string]interface{}) *metav1.FieldsV1 {
+		raw, _ := json.Marshal(fields)
+		return &metav1.FieldsV1{Raw: raw}
+	}
+
+	obj := &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			ManagedFields: []metav1.ManagedFieldsEntry{
+				{
+					Manager:   "kubectl-client-side-apply",
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					FieldsV1:  fieldsV1(map[string]interface{}{"f:spec": map[string]interface{}{"f:ports": map[string]interface{}{}}}),
+				},
+				{
+					Manager:   "kubectl-edit",
+					Operation: metav1.ManagedFieldsOperationUpdate,
+					FieldsV1:  fieldsV1(map[string]interface{}{"f:spec": map[string]interface{}{"f:type": map[string]interface{}{}}}),
+				},
+				{
+					Manager:     "kube-controller-manager",
+					Operation:   metav1.ManagedFieldsOperationUpdate,
+					Subresource: "status",
+				},
+			},
+		},
+	}
+	assert.Nil(t, takeOverManagedFields(obj))
+	assert.Len(t, obj.ManagedFields, 2)
+	assert.Equal(t, "kube-controller-manager", obj.ManagedFields[0].Manager)
+	assert.Equal(t, fieldManager, obj.ManagedFields[1].Manager)
+	assert.Equal(t, metav1.ManagedFieldsOperationApply, obj.ManagedFields[1].Operation)
+	assert.JSONEq(t, `{"f:spec": {"f:ports": {}, "f:type": {}}}`, string(obj.ManagedFields[1].FieldsV1.Raw))
+}
+
+func TestGetApplyObject(t *testing.T) {
+	scheme := runtime.NewScheme()
+	assert.Nil(t, clientgoscheme.AddToScheme(scheme))
+
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default", ResourceVersion: "1"},
+		Spec: appsv1.DeploymentSpec{
+			Template: corev1.PodTemplateSpec{
+				Spec: corev1.PodSpec{
+					Containers: []corev1.Container{{Name: "neuron", Image: "emqx/neuron:2.4.0"}},
+					Volumes: []corev1.Volume{{
+						Name:         "data",
+						VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}},
+					}},
+					Affinity: &corev1.Affinity{PodAffinity: &corev1.PodAffinity{
+						RequiredDuringSchedulingIgnoredDuringExecution: []corev1.PodAffinityTerm{{
+							LabelSelector:     &metav1.LabelSelector{},
+							NamespaceSelector: &metav1.LabelSelector{},
+							TopologyKey:       corev1.LabelHostname,
+						}},
+					}},
+					TopologySpreadConstraints: []corev1.TopologySpreadConstraint{{
+						MaxSkew:           1,
+						TopologyKey:       corev1.LabelHostname,
+						WhenUnsatisfiable: corev1.DoNotSchedule,
+						LabelSelector:     &metav1.LabelSelector{},
+					}},
+				},
+			},
+		},
+	}
+	obj, err := getApplyObject(deploy, scheme)
+	assert.Nil(t, err)
+	assert.Equal(t, "apps/v1", obj.GetAPIVersion())
+	assert.Equal(t, "Deployment", obj.GetKind())
+	assert.Empty(t, obj.GetResourceVersion())
+	assert.NotContains(t, obj.Object, "status")
+	assert.NotContains(t, obj.Object["metadata"], "creationTimestamp")
+	_, found, _ := unstructured.NestedFieldNoCopy(obj.Object, "spec", "replicas")
+	assert.False(t, found)
+	_, found, _ = unstructured.NestedFieldNoCopy(obj.Object, "spec", "template", "metadata")
+	assert.False(t, found)
+
+	podSpec, _, _ := unstructured.NestedMap(obj.Object, "spec", "template", "spec")
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "neuron", "image": "emqx/neuron:2.4.0"}},
+		podSpec["containers"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"name": "data", "emptyDir": map[string]interface{}{}}},
+		podSpec["volumes"])
+	// the empty selectors set in the spec match all the pods and namespaces
+	assert.Equal(t, map[string]interface{}{"podAffinity": map[string]interface{}{
+		"requiredDuringSchedulingIgnoredDuringExecution": []interface{}{map[string]interface{}{
+			"labelSelector":     map[string]interface{}{},
+			"namespaceSelector": map[string]interface{}{},
+			"topologyKey":       corev1.LabelHostname,
+		}},
+	}}, podSpec["affinity"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"maxSkew":           int64(1),
+		"topologyKey":       corev1.LabelHostname,
+		"whenUnsatisfiable": string(corev1.DoNotSchedule),
+		"labelSelector":     map[string]interface{}{},
+	}}, podSpec["topologySpreadConstraints"])
+}
+
+func TestIsReplicasOwnedByOthers(t *testing.T) {
+	fieldsV1 := func(fields string) *metav1.FieldsV1 {
+		return &metav1.FieldsV1{Raw: []byte(fields)}
+	}
+	deploy := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{ManagedFields: []metav1.ManagedFieldsEntry{
+		{Manager: fieldManager, Operation: metav1.ManagedFieldsOperationApply,
+			FieldsV1: fieldsV1(`{"f:spec": {"f:replicas": {}, "f:template": {}}}`)},
+		{Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate, Subresource: "status",
+			FieldsV1: fieldsV1(`{"f:status": {"f:replicas": {}}}`)},
+	}}}
+	owned, err := isReplicasOwnedByOthers(deploy)
+	assert.Nil(t, err)
+	assert.False(t, owned)
+
+	// the replicas set once with kubectl are taken back
+	deploy.ManagedFields = append(deploy.ManagedFields, metav1.ManagedFieldsEntry{
+		Manager: "kubectl-edit", Operation: metav1.ManagedFieldsOperationUpdate,
+		FieldsV1: fieldsV1(`{"f:spec": {"f:replicas": {}}}`),
+	}, metav1.ManagedFieldsEntry{
+		Manager: "kubectl", Operation: metav1.ManagedFieldsOperationUpdate, Subresource: "scale",
+		FieldsV1: fieldsV1(`{"f:spec": {"f:replicas": {}}}`),
+	})
+	owned, err = isReplicasOwnedByOthers(deploy)
+	assert.Nil(t, err)
+	assert.False(t, owned)
+
+	// a HorizontalPodAutoscaler scales the Deployment through the scale subresource
+	deploy.ManagedFields = append(deploy.ManagedFields, metav1.ManagedFieldsEntry{
+		Manager: "kube-controller-manager", Operation: metav1.ManagedFieldsOperationUpdate, Subresource: "scale",
+		FieldsV1: fieldsV1(`{"f:spec": {"f:replicas": {}}}`),
+	})
+	owned, err = isReplicasOwnedByOthers(deploy)
+	assert.Nil(t, err)
+	assert.True(t, owned)
+}
@@ -27,13 +27,18 @@ import (
 	. "github.com/onsi/gomega"
 
 	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"github.com/stretchr/testify/assert"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/envtest"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
@@ -92,9 +97,10 @@ var _ = BeforeSuite(func() {
 
 	eventRecorder := mock.GetEventRecorderFor("mockEventRecorder")
 	client := mgr.GetClient()
-	Expect(NewNeuronEXReconciler(client, eventRecorder).SetupWithManager(mgr)).Should(Succeed())
-	Expect(NewNeuronReconciler(client, eventRecorder).SetupWithManager(mgr)).Should(Succeed())
-	Expect(NewEKuiperReconciler(client, eventRecorder).SetupWithManager(mgr)).Should(Succeed())
+	options := Options{ServerSideApply: true}
+	Expect(NewNeuronEXReconciler(client, eventRecorder, options).SetupWithManager(mgr)).Should(Succeed())
+	Expect(NewNeuronReconciler(client, eventRecorder, options).SetupWithManager(mgr)).Should(Succeed())
+	Expect(NewEKuiperReconciler(client, eventRecorder, options).SetupWithManager(mgr)).Should(Succeed())
 
 	go func() {
 		defer GinkgoRecover()
@@ -185,6 +191,33 @@ func getEKuiper() *edgev1alpha1.EKuiper {
 	return ekuiper
 }
 
+// getFakeNeuron returns a defaulted Neuron with a version tag, for the tests on a fake client
+func getFakeNeuron() *edgev1alpha1.Neuron {
+	neuron := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default", UID: "uid"},
+		Spec: edgev1alpha1.NeuronSpec{
+			Neuron: corev1.Container{Name: "neuron", Image: "emqx/neuron:2.3.0"},
+		},
+	}
+	neuron.Default()
+	return neuron
+}
+
+// newFakeClient returns a fake client holding the instance and the objects, the status of the instance is
+// only written through the status subresource like on the API server
+func newFakeClient(t *testing.T, ins client.Object, objs ...client.Object) client.Client {
+	s := runtime.NewScheme()
+	assert.Nil(t, scheme.AddToScheme(s))
+	assert.Nil(t, edgev1alpha1.AddToScheme(s))
+	assert.Nil(t, snapshotv1.AddToScheme(s))
+	return fake.NewClientBuilder().WithScheme(s).WithStatusSubresource(ins).WithObjects(append(objs, ins)...).Build()
+}
+
+// newFakeController returns an EdgeController on a fake client holding the instance and the objects
+func newFakeController(t *testing.T, ins client.Object, objs ...client.Object) *EdgeController {
+	return NewEdgeController(newFakeClient(t, ins, objs...), record.NewFakeRecorder(20), Options{})
+}
+
 func deepCopyEdgeEdgeInterface(ins edgev1alpha1.EdgeInterface) edgev1alpha1.EdgeInterface {
 	var got edgev1alpha1.EdgeInterface
 	switch res := ins.(type) {
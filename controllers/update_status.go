@@ -2,9 +2,12 @@ package controllers
 
 import (
 	"context"
+	"reflect"
+
 	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
 	"github.com/go-logr/logr"
 	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -41,7 +44,7 @@ func updateStatus(ctx context.Context, r *EdgeController, instance edgev1alpha1.
 	deploy := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
 			Namespace: instance.GetNamespace(),
-			Name:      instance.GetName(),
+			Name:      getDeploymentName(instance),
 		},
 	}
 
@@ -52,17 +55,24 @@ func updateStatus(ctx context.Context, r *EdgeController, instance edgev1alpha1.
 		return nil
 	}
 
-	phase := edgev1alpha1.CRNotReady
-	if deploy.Status.ReadyReplicas == deploy.Status.Replicas {
-		phase = edgev1alpha1.CRReady
+	pods, err := getInstancePods(ctx, r.Client, instance)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+	pvcs, err := getInstancePVCs(ctx, r.Client, instance)
+	if err != nil {
+		return &requeue{curError: err}
 	}
 
-	if instance.GetStatus().Phase != phase {
-		instance.SetStatus(
-			&edgev1alpha1.EdgeStatus{
-				Phase: phase,
-			},
-		)
+	old := instance.GetStatus()
+	status := *old.DeepCopy()
+	status.Phase = getPhase(instance, deploy)
+	setVersions(&status, instance, pods)
+	status.Containers = getContainerStatuses(pods)
+	setPodConditions(&status, instance.GetGeneration(), pods, pvcs)
+	if !reflect.DeepEqual(old, status) {
+		recordPodWarnings(r.Recorder, instance, old, status)
+		instance.SetStatus(&status)
 		logger.Info("Update status", "current", instance.GetStatus())
 		if err := r.Status().Update(ctx, instance); err != nil {
 			return &requeue{curError: err}
@@ -70,3 +80,35 @@ func updateStatus(ctx context.Context, r *EdgeController, instance edgev1alpha1.
 	}
 	return nil
 }
+
+// getPhase returns whether the pods of the Deployment are ready
+func getPhase(ins edgev1alpha1.EdgeInterface, deploy *appsv1.Deployment) edgev1alpha1.CRPhase {
+	if deploy.Status.ReadyReplicas >= getWantedReadyReplicas(ins, deploy.Status.Replicas) {
+		return edgev1alpha1.CRReady
+	}
+	return edgev1alpha1.CRNotReady
+}
+
+// getWantedReadyReplicas returns how many of the replicas are ready once the instance is ready. In high
+// availability mode the standby pod stays unready behind the active readiness gate, the active pod is enough.
+func getWantedReadyReplicas(ins edgev1alpha1.EdgeInterface, replicas int32) int32 {
+	if ins.GetHighAvailability() != nil && replicas > 1 {
+		return 1
+	}
+	return replicas
+}
+
+// setVersions records the versions of the neuron and ekuiper images that the ready containers run, the
+// versions are kept while no container is ready
+func setVersions(status *edgev1alpha1.EdgeStatus, ins edgev1alpha1.EdgeInterface, pods []corev1.Pod) {
+	if neuron := ins.GetNeuron(); neuron != nil {
+		if version := getRunningVersion(pods, neuron.Name); version != "" {
+			status.NeuronVersion = version
+		}
+	}
+	if ekuiper := ins.GetEKuiper(); ekuiper != nil {
+		if version := getRunningVersion(pods, ekuiper.Name); version != "" {
+			status.EKuiperVersion = version
+		}
+	}
+}
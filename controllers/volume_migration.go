@@ -0,0 +1,370 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/emqx/edge-operator/internal"
+	"github.com/go-logr/logr"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const migrateVolumesJob = "migrate-volumes"
+
+type migrateEKuiperVolumes struct{}
+
+func (m migrateEKuiperVolumes) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.EKuiper) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"migrate eKuiper volumes")
+	return migrateVolumes(ctx, r, instance, logger)
+}
+
+type migrateNeuronVolumes struct{}
+
+func (m migrateNeuronVolumes) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"migrate Neuron volumes")
+	return migrateVolumes(ctx, r, instance, logger)
+}
+
+type migrateNeuronEXVolumes struct{}
+
+func (m migrateNeuronEXVolumes) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.NeuronEX) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"migrate NeuronEX volumes")
+	return migrateVolumes(ctx, r, instance, logger)
+}
+
+// migrateVolumes copies the persistent data to the claims of a new volume claim template before the
+// Deployment mounts them. The pods are stopped while the data is copied. The data of emptyDir volumes is
+// deleted with the pods and can not be copied consistently from running ones, the pods keep them until the
+// user confirms that the data is discarded. The source claims are kept as rollback points, reverting the
+// template before the end of the copy rolls the migration back.
+func migrateVolumes(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	deploy := &appsv1.Deployment{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: getDeploymentName(ins)}, deploy); err != nil {
+		if k8sErrors.IsNotFound(err) {
+			return nil
+		}
+		return &requeue{curError: err}
+	}
+
+	status := ins.GetStatus()
+	migration := status.VolumeMigration.DeepCopy()
+	volumes := getMigratedVolumes(ins, deploy)
+	if len(volumes) == 0 {
+		if migration == nil || migration.Phase == edgev1alpha1.VolumeMigrationCompleted ||
+			migration.Phase == edgev1alpha1.VolumeMigrationDiscarded || migration.Phase == edgev1alpha1.VolumeMigrationRolledBack {
+			return removeDiscardData(ctx, r, ins)
+		}
+		if migration.Job != "" {
+			if err := deleteMigrationJob(ctx, r, ins, migration.Job); err != nil {
+				return &requeue{curError: err}
+			}
+		}
+		logger.Info("Roll back volume migration")
+		r.Recorder.Event(ins, corev1.EventTypeNormal, "VolumeMigrationRolledBack",
+			"The volume claim template was reverted, the pods keep their volumes")
+		migration.Phase = edgev1alpha1.VolumeMigrationRolledBack
+		migration.Message = ""
+		return setVolumeMigration(ctx, r, ins, migration)
+	}
+
+	if migration == nil || !reflect.DeepEqual(migration.Volumes, volumes) ||
+		migration.Phase == edgev1alpha1.VolumeMigrationRolledBack {
+		// the pods are restarted, the Deployment holds the new claims until the next maintenance window
+		if held, _ := isRestartHeld(ins); held {
+			return nil
+		}
+		if emptyDirs := getEmptyDirVolumes(volumes); len(emptyDirs) > 0 {
+			logger.Info("Hold the migration of emptyDir volumes", "volumes", volumes)
+			r.Recorder.Eventf(ins, corev1.EventTypeWarning, "VolumeMigrationAwaitingConfirmation",
+				"The data of the emptyDir volumes %s can not be copied to the new PersistentVolumeClaims, set the "+
+					"%s annotation to true to discard it", getVolumeNames(emptyDirs), edgev1alpha1.DiscardDataAnnotation)
+			migration = &edgev1alpha1.VolumeMigration{
+				Phase:     edgev1alpha1.VolumeMigrationAwaitingConfirmation,
+				Volumes:   volumes,
+				StartTime: &metav1.Time{Time: time.Now()},
+				Message: fmt.Sprintf("the data of emptyDir volumes can not be copied, set the %s annotation to "+
+					"true to discard it", edgev1alpha1.DiscardDataAnnotation),
+			}
+		} else {
+			logger.Info("Start volume migration", "volumes", volumes)
+			r.Recorder.Eventf(ins, corev1.EventTypeNormal, "VolumeMigrationStarted",
+				"Copying the data of %s to the new PersistentVolumeClaims", getVolumeNames(volumes))
+			migration = &edgev1alpha1.VolumeMigration{
+				Phase:     edgev1alpha1.VolumeMigrationStopping,
+				Job:       internal.GetResNameOnPanic(ins, migrateVolumesJob),
+				Volumes:   volumes,
+				StartTime: &metav1.Time{Time: time.Now()},
+			}
+		}
+		if req := setVolumeMigration(ctx, r, ins, migration); req != nil {
+			return req
+		}
+	}
+
+	switch migration.Phase {
+	case edgev1alpha1.VolumeMigrationCompleted, edgev1alpha1.VolumeMigrationDiscarded:
+		// the Deployment mounts the new claims next
+		return nil
+	case edgev1alpha1.VolumeMigrationAwaitingConfirmation:
+		if ins.GetAnnotations()[edgev1alpha1.DiscardDataAnnotation] != "true" {
+			// the Deployment keeps the emptyDir volumes
+			return &requeue{delay: time.Minute, message: migration.Message}
+		}
+		emptyDirs := getEmptyDirVolumes(migration.Volumes)
+		logger.Info("Discard the data of emptyDir volumes", "volumes", emptyDirs)
+		r.Recorder.Eventf(ins, corev1.EventTypeWarning, "VolumeMigrationDiscarded",
+			"The data of the emptyDir volumes %s is discarded, the pods mount the new PersistentVolumeClaims",
+			getVolumeNames(emptyDirs))
+		if len(emptyDirs) < len(migration.Volumes) {
+			// the data of the other claims is still copied
+			migration.Phase = edgev1alpha1.VolumeMigrationStopping
+			migration.Job = internal.GetResNameOnPanic(ins, migrateVolumesJob)
+			migration.Message = ""
+			if req := setVolumeMigration(ctx, r, ins, migration); req != nil {
+				return req
+			}
+			return startMigrationJob(ctx, r, ins, deploy, migration, logger)
+		}
+		migration.Phase = edgev1alpha1.VolumeMigrationDiscarded
+		migration.CompletionTime = &metav1.Time{Time: time.Now()}
+		migration.Message = "the data of emptyDir volumes is discarded"
+		return setVolumeMigration(ctx, r, ins, migration)
+	case edgev1alpha1.VolumeMigrationStopping:
+		return startMigrationJob(ctx, r, ins, deploy, migration, logger)
+	}
+
+	job := &batchv1.Job{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: migration.Job}, job); err != nil {
+		if !k8sErrors.IsNotFound(err) {
+			return &requeue{curError: err}
+		}
+		// a deleted Job is run again
+		return startMigrationJob(ctx, r, ins, deploy, migration, logger)
+	}
+
+	for _, c := range job.Status.Conditions {
+		if c.Status != corev1.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			logger.Info("Volume migration completed", "job", job.Name)
+			r.Recorder.Eventf(ins, corev1.EventTypeNormal, "VolumeMigrationCompleted",
+				"The data of %s was copied to the new PersistentVolumeClaims", getVolumeNames(migration.Volumes))
+			if err := deleteMigrationJob(ctx, r, ins, job.Name); err != nil {
+				return &requeue{curError: err}
+			}
+			migration.Phase = edgev1alpha1.VolumeMigrationCompleted
+			migration.CompletionTime = &metav1.Time{Time: time.Now()}
+			migration.Message = ""
+			return setVolumeMigration(ctx, r, ins, migration)
+		case batchv1.JobFailed:
+			if migration.Phase != edgev1alpha1.VolumeMigrationFailed {
+				r.Recorder.Eventf(ins, corev1.EventTypeWarning, "VolumeMigrationFailed",
+					"Job %s failed to copy the data: %s", job.Name, c.Message)
+				migration.Phase = edgev1alpha1.VolumeMigrationFailed
+				migration.Message = fmt.Sprintf("Job %s failed: %s", job.Name, c.Message)
+				if req := setVolumeMigration(ctx, r, ins, migration); req != nil {
+					return req
+				}
+			}
+			return &requeue{delay: time.Minute, message: fmt.Sprintf(
+				"the volume migration failed, revert the volume claim template or delete Job %s to retry", job.Name)}
+		}
+	}
+	return &requeue{delay: 5 * time.Second, message: fmt.Sprintf("Job %s is copying the data of the volumes", job.Name)}
+}
+
+// startMigrationJob stops the pods, then creates the copy Job
+func startMigrationJob(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, deploy *appsv1.Deployment,
+	migration *edgev1alpha1.VolumeMigration, logger logr.Logger) *requeue {
+	pods, err := getInstancePods(ctx, r.Client, ins)
+	if err != nil {
+		return &requeue{curError: err}
+	}
+	if req := stopPods(ctx, r, deploy, pods, "the volumes are migrated", logger); req != nil {
+		return req
+	}
+
+	job := getMigrationJob(ins, migration)
+	logger.Info("Create volume migration Job", "job", job.Name)
+	if err := r.create(ctx, ins, job); err != nil {
+		if !k8sErrors.IsAlreadyExists(err) {
+			return &requeue{curError: err}
+		}
+	}
+	migration.Phase = edgev1alpha1.VolumeMigrationCopying
+	migration.Message = ""
+	if req := setVolumeMigration(ctx, r, ins, migration); req != nil {
+		return req
+	}
+	return &requeue{delay: 5 * time.Second, message: fmt.Sprintf("Job %s is copying the data of the volumes", job.Name)}
+}
+
+// stopPods scales the Deployment to zero and returns a requeue until its pods are gone, the next update of
+// the Deployment scales it up again. The operator is the field manager of the scale down, so that it is not
+// taken for an external scaler.
+func stopPods(ctx context.Context, r *EdgeController, deploy *appsv1.Deployment, pods []corev1.Pod, reason string,
+	logger logr.Logger) *requeue {
+	if deploy.Spec.Replicas == nil || *deploy.Spec.Replicas != 0 {
+		logger.Info("Scale down until "+reason, "deployment", deploy.Name)
+		patch := client.MergeFrom(deploy.DeepCopy())
+		deploy.Spec.Replicas = &[]int32{0}[0]
+		if err := r.Patch(ctx, deploy, patch, client.FieldOwner(fieldManager)); err != nil {
+			return &requeue{curError: err}
+		}
+	}
+	if len(pods) > 0 {
+		return &requeue{delay: 5 * time.Second, message: "waiting for the pods to stop before " + reason}
+	}
+	return nil
+}
+
+// getMigratedVolumes returns the persistent volumes that the Deployment mounts from an emptyDir or another
+// claim than the one generated for them
+func getMigratedVolumes(ins edgev1alpha1.EdgeInterface, deploy *appsv1.Deployment) []edgev1alpha1.MigratedVolume {
+	existing := map[string]corev1.VolumeSource{}
+	for _, vol := range deploy.Spec.Template.Spec.Volumes {
+		existing[vol.Name] = vol.VolumeSource
+	}
+
+	var volumes []edgev1alpha1.MigratedVolume
+	for _, vol := range getVolumeList(ins) {
+		target := vol.volumeSource.PersistentVolumeClaim
+		source, ok := existing[vol.name]
+		if target == nil || !ok {
+			continue
+		}
+		switch {
+		case source.EmptyDir != nil:
+			volumes = append(volumes, edgev1alpha1.MigratedVolume{Name: vol.name, TargetClaim: target.ClaimName})
+		case source.PersistentVolumeClaim != nil && source.PersistentVolumeClaim.ClaimName != target.ClaimName:
+			volumes = append(volumes, edgev1alpha1.MigratedVolume{
+				Name:        vol.name,
+				SourceClaim: source.PersistentVolumeClaim.ClaimName,
+				TargetClaim: target.ClaimName,
+			})
+		}
+	}
+	return volumes
+}
+
+// getMigrationJob returns the Job copying the data of the source claims to the target claims. The target
+// claims are emptied first, so that a retried copy or a migration back to a former claim leaves no stale files.
+func getMigrationJob(ins edgev1alpha1.EdgeInterface, migration *edgev1alpha1.VolumeMigration) *batchv1.Job {
+	podSpec := corev1.PodSpec{
+		RestartPolicy:    corev1.RestartPolicyNever,
+		ImagePullSecrets: getImagePullSecrets(ins),
+	}
+	container := corev1.Container{
+		Name:  "copy",
+		Image: edgev1alpha1.MigrationImage(),
+	}
+	var commands []string
+	for _, vol := range migration.Volumes {
+		// the data of the emptyDir volumes is discarded
+		if vol.SourceClaim == "" {
+			continue
+		}
+		podSpec.Volumes = append(podSpec.Volumes, corev1.Volume{
+			Name: "source-" + vol.Name,
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: vol.SourceClaim,
+				ReadOnly:  true,
+			}},
+		}, corev1.Volume{
+			Name: "target-" + vol.Name,
+			VolumeSource: corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+				ClaimName: vol.TargetClaim,
+			}},
+		})
+		container.VolumeMounts = append(container.VolumeMounts,
+			corev1.VolumeMount{Name: "source-" + vol.Name, MountPath: "/source/" + vol.Name, ReadOnly: true},
+			corev1.VolumeMount{Name: "target-" + vol.Name, MountPath: "/target/" + vol.Name},
+		)
+		commands = append(commands, fmt.Sprintf("find /target/%s -mindepth 1 -delete", vol.Name),
+			fmt.Sprintf("cp -a /source/%s/. /target/%s/", vol.Name, vol.Name))
+	}
+	container.Command = []string{"sh", "-c", strings.Join(commands, " && ")}
+	podSpec.Containers = []corev1.Container{container}
+
+	job := &batchv1.Job{
+		ObjectMeta: internal.GetObjectMetadata(ins, migration.Job),
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &[]int32{2}[0],
+			// the pod template has none of the labels of the instance, the copy pod is not one of its pods
+			Template: corev1.PodTemplateSpec{Spec: podSpec},
+		},
+	}
+	job.SetGroupVersionKind(batchv1.SchemeGroupVersion.WithKind("Job"))
+	return job
+}
+
+// removeDiscardData removes the annotation confirming that the data of emptyDir volumes is discarded once
+// the pods mount the new claims, so that it does not confirm a later migration
+func removeDiscardData(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface) *requeue {
+	if _, ok := ins.GetAnnotations()[edgev1alpha1.DiscardDataAnnotation]; !ok {
+		return nil
+	}
+	patch := client.MergeFrom(ins.DeepCopyObject().(client.Object))
+	annotations := ins.GetAnnotations()
+	delete(annotations, edgev1alpha1.DiscardDataAnnotation)
+	ins.SetAnnotations(annotations)
+	if err := r.Patch(ctx, ins, patch); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
+
+func deleteMigrationJob(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, name string) error {
+	job := &batchv1.Job{ObjectMeta: metav1.ObjectMeta{Namespace: ins.GetNamespace(), Name: name}}
+	if err := r.Delete(ctx, job, client.PropagationPolicy(metav1.DeletePropagationBackground)); err != nil &&
+		!k8sErrors.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// setVolumeMigration records the progress of the volume migration in the status of the instance
+func setVolumeMigration(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface,
+	migration *edgev1alpha1.VolumeMigration) *requeue {
+	status := ins.GetStatus()
+	status.VolumeMigration = migration
+	ins.SetStatus(&status)
+	if err := r.Status().Update(ctx, ins); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
+
+// getEmptyDirVolumes returns the migrated volumes whose data is in an emptyDir, it can not be copied
+func getEmptyDirVolumes(volumes []edgev1alpha1.MigratedVolume) []edgev1alpha1.MigratedVolume {
+	var emptyDirs []edgev1alpha1.MigratedVolume
+	for _, vol := range volumes {
+		if vol.SourceClaim == "" {
+			emptyDirs = append(emptyDirs, vol)
+		}
+	}
+	return emptyDirs
+}
+
+func getVolumeNames(volumes []edgev1alpha1.MigratedVolume) string {
+	names := make([]string, 0, len(volumes))
+	for _, vol := range volumes {
+		names = append(names, vol.Name)
+	}
+	return strings.Join(names, ", ")
+}
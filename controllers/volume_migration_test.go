@@ -0,0 +1,244 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func newMigrationTest(t *testing.T, source corev1.VolumeSource, objs ...client.Object) (*EdgeController, *edgev1alpha1.Neuron) {
+	ins := getFakeNeuron()
+	ins.Spec.VolumeClaimTemplate = &corev1.PersistentVolumeClaimTemplate{
+		ObjectMeta: metav1.ObjectMeta{Name: "fast"},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &[]int32{1}[0],
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{{Name: "neuron-data", VolumeSource: source}},
+			}},
+		},
+	}
+	return newFakeController(t, ins, append(objs, deploy)...), ins
+}
+
+func TestMigrateVolumes(t *testing.T) {
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "neuron-0", Namespace: "default", Labels: map[string]string{
+		edgev1alpha1.InstanceKey:  "neuron",
+		edgev1alpha1.ComponentKey: string(edgev1alpha1.ComponentTypeNeuron),
+	}}}
+	r, ins := newMigrationTest(t, corev1.VolumeSource{PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{
+		ClaimName: "neuron-neuron-data",
+	}}, pod)
+	ctx := context.Background()
+	migrate := func() *requeue {
+		req := migrateVolumes(ctx, r, ins, log)
+		assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(ins), ins))
+		return req
+	}
+
+	// the pods are stopped first
+	req := migrate()
+	assert.NotNil(t, req)
+	assert.Equal(t, edgev1alpha1.VolumeMigrationStopping, ins.Status.VolumeMigration.Phase)
+	assert.Equal(t, []edgev1alpha1.MigratedVolume{
+		{Name: "neuron-data", SourceClaim: "neuron-neuron-data", TargetClaim: "fast-neuron-data"},
+	}, ins.Status.VolumeMigration.Volumes)
+	deploy := &appsv1.Deployment{}
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "neuron"}, deploy))
+	assert.Equal(t, int32(0), *deploy.Spec.Replicas)
+
+	// then the data is copied
+	assert.Nil(t, r.Delete(ctx, pod))
+	assert.NotNil(t, migrate())
+	assert.Equal(t, edgev1alpha1.VolumeMigrationCopying, ins.Status.VolumeMigration.Phase)
+	job := &batchv1.Job{}
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "neuron-migrate-volumes"}, job))
+	podSpec := job.Spec.Template.Spec
+	assert.Equal(t, []string{"sh", "-c",
+		"find /target/neuron-data -mindepth 1 -delete && cp -a /source/neuron-data/. /target/neuron-data/"},
+		podSpec.Containers[0].Command)
+	assert.Equal(t, "neuron-neuron-data", podSpec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	assert.True(t, podSpec.Volumes[0].PersistentVolumeClaim.ReadOnly)
+	assert.Equal(t, "fast-neuron-data", podSpec.Volumes[1].PersistentVolumeClaim.ClaimName)
+
+	// a failed copy keeps the pods stopped
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobFailed, Status: corev1.ConditionTrue, Message: "BackoffLimitExceeded"}}
+	assert.Nil(t, r.Status().Update(ctx, job))
+	assert.NotNil(t, migrate())
+	assert.Equal(t, edgev1alpha1.VolumeMigrationFailed, ins.Status.VolumeMigration.Phase)
+
+	// a deleted Job is run again
+	assert.Nil(t, r.Delete(ctx, job))
+	assert.NotNil(t, migrate())
+	assert.Equal(t, edgev1alpha1.VolumeMigrationCopying, ins.Status.VolumeMigration.Phase)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(job), job))
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	assert.Nil(t, r.Status().Update(ctx, job))
+	assert.Nil(t, migrate())
+	assert.Equal(t, edgev1alpha1.VolumeMigrationCompleted, ins.Status.VolumeMigration.Phase)
+	assert.NotNil(t, ins.Status.VolumeMigration.CompletionTime)
+	assert.True(t, k8sErrors.IsNotFound(r.Get(ctx, client.ObjectKeyFromObject(job), job)))
+	assert.Nil(t, migrate())
+}
+
+func TestMigrateEmptyDirVolumes(t *testing.T) {
+	pod := &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron-0", Namespace: "default", Labels: map[string]string{
+			edgev1alpha1.InstanceKey:  "neuron",
+			edgev1alpha1.ComponentKey: string(edgev1alpha1.ComponentTypeNeuron),
+		}},
+		Status: corev1.PodStatus{Phase: corev1.PodRunning},
+	}
+	r, ins := newMigrationTest(t, corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}, pod)
+	ctx := context.Background()
+
+	// the data of emptyDir volumes can not be copied, the pods keep them until the loss is confirmed
+	req := migrateVolumes(ctx, r, ins, log)
+	assert.NotNil(t, req)
+	assert.Nil(t, req.curError)
+	assert.Equal(t, edgev1alpha1.VolumeMigrationAwaitingConfirmation, ins.Status.VolumeMigration.Phase)
+	assert.Equal(t, "the data of emptyDir volumes can not be copied, set the edge.emqx.io/discard-emptydir-data "+
+		"annotation to true to discard it", ins.Status.VolumeMigration.Message)
+	assert.Equal(t, "Warning VolumeMigrationAwaitingConfirmation The data of the emptyDir volumes neuron-data can "+
+		"not be copied to the new PersistentVolumeClaims, set the edge.emqx.io/discard-emptydir-data annotation "+
+		"to true to discard it", <-r.Recorder.(*record.FakeRecorder).Events)
+	jobs := &batchv1.JobList{}
+	assert.Nil(t, r.List(ctx, jobs))
+	assert.Empty(t, jobs.Items)
+	deploy := &appsv1.Deployment{}
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "neuron"}, deploy))
+	assert.Equal(t, int32(1), *deploy.Spec.Replicas)
+	assert.NotNil(t, migrateVolumes(ctx, r, ins, log))
+	assert.Equal(t, edgev1alpha1.VolumeMigrationAwaitingConfirmation, ins.Status.VolumeMigration.Phase)
+
+	// the confirmed migration lets the pods mount the new claims
+	ins.Annotations = map[string]string{edgev1alpha1.DiscardDataAnnotation: "true"}
+	assert.Nil(t, r.Update(ctx, ins))
+	assert.Nil(t, migrateVolumes(ctx, r, ins, log))
+	assert.Equal(t, edgev1alpha1.VolumeMigrationDiscarded, ins.Status.VolumeMigration.Phase)
+	assert.NotNil(t, ins.Status.VolumeMigration.CompletionTime)
+	assert.Equal(t, "Warning VolumeMigrationDiscarded The data of the emptyDir volumes neuron-data is discarded, "+
+		"the pods mount the new PersistentVolumeClaims", <-r.Recorder.(*record.FakeRecorder).Events)
+	assert.Nil(t, migrateVolumes(ctx, r, ins, log))
+
+	// the confirmation is removed once the Deployment mounts the new claims
+	deploy.Spec.Template.Spec.Volumes[0].VolumeSource = corev1.VolumeSource{
+		PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "fast-neuron-data"},
+	}
+	assert.Nil(t, r.Update(ctx, deploy))
+	assert.Nil(t, migrateVolumes(ctx, r, ins, log))
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(ins), ins))
+	assert.NotContains(t, ins.Annotations, edgev1alpha1.DiscardDataAnnotation)
+}
+
+func TestRollBackVolumeMigration(t *testing.T) {
+	r, ins := newMigrationTest(t, corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}})
+	ctx := context.Background()
+
+	assert.NotNil(t, migrateVolumes(ctx, r, ins, log))
+	assert.Equal(t, edgev1alpha1.VolumeMigrationAwaitingConfirmation, ins.Status.VolumeMigration.Phase)
+
+	ins.Spec.VolumeClaimTemplate = nil
+	assert.Nil(t, migrateVolumes(ctx, r, ins, log))
+	assert.Equal(t, edgev1alpha1.VolumeMigrationRolledBack, ins.Status.VolumeMigration.Phase)
+}
+
+func TestGetMigrationJob(t *testing.T) {
+	ins := &edgev1alpha1.NeuronEX{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuronex", Namespace: "default"},
+		Spec: edgev1alpha1.NeuronEXSpec{
+			EdgePodSpec: edgev1alpha1.EdgePodSpec{ImagePullSecrets: []corev1.LocalObjectReference{{Name: "registry"}}},
+		},
+	}
+	migration := &edgev1alpha1.VolumeMigration{
+		Job: "neuronex-migrate-volumes",
+		Volumes: []edgev1alpha1.MigratedVolume{
+			{Name: "neuron-data", SourceClaim: "neuronex-neuron-data", TargetClaim: "fast-neuron-data"},
+			{Name: "ekuiper-data", SourceClaim: "neuronex-ekuiper-data", TargetClaim: "fast-ekuiper-data"},
+		},
+	}
+
+	job := getMigrationJob(ins, migration)
+	podSpec := job.Spec.Template.Spec
+	assert.Empty(t, podSpec.NodeName)
+	assert.Equal(t, "busybox:1.36", podSpec.Containers[0].Image)
+	assert.Equal(t, []corev1.LocalObjectReference{{Name: "registry"}}, podSpec.ImagePullSecrets)
+	assert.Equal(t, "neuronex-neuron-data", podSpec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, "fast-ekuiper-data", podSpec.Volumes[3].PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, []string{"sh", "-c", "find /target/neuron-data -mindepth 1 -delete && " +
+		"cp -a /source/neuron-data/. /target/neuron-data/ && find /target/ekuiper-data -mindepth 1 -delete && " +
+		"cp -a /source/ekuiper-data/. /target/ekuiper-data/"},
+		podSpec.Containers[0].Command)
+	assert.Empty(t, job.Spec.Template.Labels)
+}
+
+func TestMigrateMixedVolumes(t *testing.T) {
+	ins := &edgev1alpha1.EKuiper{
+		ObjectMeta: metav1.ObjectMeta{Name: "ekuiper", Namespace: "default", UID: "uid"},
+		Spec: edgev1alpha1.EKuiperSpec{
+			EKuiper: corev1.Container{Name: "ekuiper", Image: "lfedge/ekuiper:1.8.0-slim"},
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{
+				ObjectMeta: metav1.ObjectMeta{Name: "fast"},
+			},
+		},
+	}
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "ekuiper", Namespace: "default"},
+		Spec: appsv1.DeploymentSpec{
+			Replicas: &[]int32{1}[0],
+			Template: corev1.PodTemplateSpec{Spec: corev1.PodSpec{
+				Volumes: []corev1.Volume{
+					{Name: "ekuiper-data", VolumeSource: corev1.VolumeSource{EmptyDir: &corev1.EmptyDirVolumeSource{}}},
+					{Name: "ekuiper-plugins", VolumeSource: corev1.VolumeSource{
+						PersistentVolumeClaim: &corev1.PersistentVolumeClaimVolumeSource{ClaimName: "ekuiper-ekuiper-plugins"},
+					}},
+				},
+			}},
+		},
+	}
+	r := newFakeController(t, ins, deploy)
+	ctx := context.Background()
+
+	// the migration waits for the confirmation that the data of the emptyDir volume is discarded
+	assert.NotNil(t, migrateVolumes(ctx, r, ins, log))
+	assert.Equal(t, edgev1alpha1.VolumeMigrationAwaitingConfirmation, ins.Status.VolumeMigration.Phase)
+	assert.Equal(t, []edgev1alpha1.MigratedVolume{
+		{Name: "ekuiper-data", TargetClaim: "fast-ekuiper-data"},
+		{Name: "ekuiper-plugins", SourceClaim: "ekuiper-ekuiper-plugins", TargetClaim: "fast-ekuiper-plugins"},
+	}, ins.Status.VolumeMigration.Volumes)
+	assert.Equal(t, "Warning VolumeMigrationAwaitingConfirmation The data of the emptyDir volumes ekuiper-data can "+
+		"not be copied to the new PersistentVolumeClaims, set the edge.emqx.io/discard-emptydir-data annotation "+
+		"to true to discard it", <-r.Recorder.(*record.FakeRecorder).Events)
+
+	// the data of the other claim is still copied once confirmed
+	ins.Annotations = map[string]string{edgev1alpha1.DiscardDataAnnotation: "true"}
+	assert.Nil(t, r.Update(ctx, ins))
+	assert.NotNil(t, migrateVolumes(ctx, r, ins, log))
+	assert.Equal(t, edgev1alpha1.VolumeMigrationCopying, ins.Status.VolumeMigration.Phase)
+	job := &batchv1.Job{}
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "ekuiper-migrate-volumes"}, job))
+	podSpec := job.Spec.Template.Spec
+	assert.Len(t, podSpec.Volumes, 2)
+	assert.Equal(t, "ekuiper-ekuiper-plugins", podSpec.Volumes[0].PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, "fast-ekuiper-plugins", podSpec.Volumes[1].PersistentVolumeClaim.ClaimName)
+	assert.Equal(t, []string{"sh", "-c",
+		"find /target/ekuiper-plugins -mindepth 1 -delete && cp -a /source/ekuiper-plugins/. /target/ekuiper-plugins/"},
+		podSpec.Containers[0].Command)
+
+	job.Status.Conditions = []batchv1.JobCondition{{Type: batchv1.JobComplete, Status: corev1.ConditionTrue}}
+	assert.Nil(t, r.Status().Update(ctx, job))
+	assert.Nil(t, migrateVolumes(ctx, r, ins, log))
+	assert.Equal(t, edgev1alpha1.VolumeMigrationCompleted, ins.Status.VolumeMigration.Phase)
+}
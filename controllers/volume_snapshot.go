@@ -0,0 +1,205 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/emqx/edge-operator/internal"
+	"github.com/go-logr/logr"
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	k8sErrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+type takeEKuiperSnapshots struct{}
+
+func (t takeEKuiperSnapshots) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.EKuiper) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"take eKuiper snapshots")
+	return takeSnapshots(ctx, r, instance, logger)
+}
+
+type takeNeuronSnapshots struct{}
+
+func (t takeNeuronSnapshots) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.Neuron) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"take Neuron snapshots")
+	return takeSnapshots(ctx, r, instance, logger)
+}
+
+type takeNeuronEXSnapshots struct{}
+
+func (t takeNeuronEXSnapshots) reconcile(ctx context.Context, r *EdgeController, instance *edgev1alpha1.NeuronEX) *requeue {
+	logger := log.WithValues("namespace", instance.Namespace, "instance", instance.Name, "reconciler",
+		"take NeuronEX snapshots")
+	return takeSnapshots(ctx, r, instance, logger)
+}
+
+// takeSnapshots takes a VolumeSnapshot of each PersistentVolumeClaim of the instance when the snapshot
+// annotation gets a new value. The pods are stopped until the CSI driver has cut the snapshots unless
+// quiescing is disabled, they are started again while the snapshots are uploaded. VolumeSnapshots are not
+// watched since their CRDs may not be installed, their progress is polled.
+func takeSnapshots(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface, logger logr.Logger) *requeue {
+	spec := ins.GetVolumeSnapshots()
+	name := ins.GetAnnotations()[edgev1alpha1.SnapshotAnnotation]
+	if spec == nil || name == "" || ins.GetVolumeClaimTemplate() == nil {
+		return nil
+	}
+
+	status := ins.GetStatus()
+	snapshot := status.Snapshot.DeepCopy()
+	if snapshot == nil || snapshot.Name != name {
+		snapshot = &edgev1alpha1.SnapshotStatus{
+			Name:      name,
+			Phase:     edgev1alpha1.SnapshotCreating,
+			StartTime: &metav1.Time{Time: time.Now()},
+		}
+		if spec.GetQuiesce() {
+			snapshot.Phase = edgev1alpha1.SnapshotQuiescing
+		}
+		for _, volume := range getSnapshotVolumes(ins) {
+			snapshot.VolumeSnapshots = append(snapshot.VolumeSnapshots,
+				edgev1alpha1.GetVolumeSnapshotName(ins.GetName(), volume, name))
+		}
+		logger.Info("Start snapshot", "snapshot", name)
+		r.Recorder.Eventf(ins, corev1.EventTypeNormal, "SnapshotStarted",
+			"Taking VolumeSnapshots %s", strings.Join(snapshot.VolumeSnapshots, ", "))
+		if req := setSnapshotStatus(ctx, r, ins, snapshot); req != nil {
+			return req
+		}
+	}
+
+	switch snapshot.Phase {
+	case edgev1alpha1.SnapshotCompleted, edgev1alpha1.SnapshotFailed:
+		return nil
+	case edgev1alpha1.SnapshotQuiescing:
+		deploy := &appsv1.Deployment{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: getDeploymentName(ins)}, deploy); err != nil {
+			if !k8sErrors.IsNotFound(err) {
+				return &requeue{curError: err}
+			}
+		} else {
+			pods, err := getInstancePods(ctx, r.Client, ins)
+			if err != nil {
+				return &requeue{curError: err}
+			}
+			if req := stopPods(ctx, r, deploy, pods, "the snapshots are taken", logger); req != nil {
+				return req
+			}
+		}
+		snapshot.Phase = edgev1alpha1.SnapshotCreating
+		if req := setSnapshotStatus(ctx, r, ins, snapshot); req != nil {
+			return req
+		}
+	}
+
+	taken, ready := true, true
+	for _, volume := range getSnapshotVolumes(ins) {
+		vs := &snapshotv1.VolumeSnapshot{}
+		vsName := edgev1alpha1.GetVolumeSnapshotName(ins.GetName(), volume, name)
+		if err := r.Get(ctx, types.NamespacedName{Namespace: ins.GetNamespace(), Name: vsName}, vs); err != nil {
+			if !k8sErrors.IsNotFound(err) {
+				return &requeue{curError: err}
+			}
+			vs = getVolumeSnapshot(ins, volume, vsName)
+			logger.Info("Create VolumeSnapshot", "name", vsName)
+			// the snapshots outlive the instance, they are not owned by it
+			if err := r.Create(ctx, vs); err != nil {
+				return &requeue{curError: err}
+			}
+		}
+
+		if vs.Status != nil && vs.Status.Error != nil && vs.Status.Error.Message != nil {
+			logger.Info("VolumeSnapshot failed", "name", vsName, "error", *vs.Status.Error.Message)
+			r.Recorder.Eventf(ins, corev1.EventTypeWarning, "SnapshotFailed",
+				"VolumeSnapshot %s failed: %s", vsName, *vs.Status.Error.Message)
+			snapshot.Phase = edgev1alpha1.SnapshotFailed
+			snapshot.Message = fmt.Sprintf("VolumeSnapshot %s failed: %s", vsName, *vs.Status.Error.Message)
+			// the pods are started again
+			return setSnapshotStatus(ctx, r, ins, snapshot)
+		}
+		if vs.Status == nil || vs.Status.CreationTime == nil {
+			taken = false
+		}
+		if vs.Status == nil || vs.Status.ReadyToUse == nil || !*vs.Status.ReadyToUse {
+			ready = false
+		}
+	}
+
+	switch {
+	case ready:
+		logger.Info("Snapshot completed", "snapshot", name)
+		r.Recorder.Eventf(ins, corev1.EventTypeNormal, "SnapshotCompleted",
+			"VolumeSnapshots %s are ready to use", strings.Join(snapshot.VolumeSnapshots, ", "))
+		snapshot.Phase = edgev1alpha1.SnapshotCompleted
+		snapshot.CompletionTime = &metav1.Time{Time: time.Now()}
+		return setSnapshotStatus(ctx, r, ins, snapshot)
+	case taken:
+		if snapshot.Phase != edgev1alpha1.SnapshotTaken {
+			snapshot.Phase = edgev1alpha1.SnapshotTaken
+			if req := setSnapshotStatus(ctx, r, ins, snapshot); req != nil {
+				return req
+			}
+		}
+		// the pods are started while the snapshots are uploaded
+		return &requeue{delay: 10 * time.Second, delayedRequeue: true,
+			message: fmt.Sprintf("waiting for the snapshots %s to be ready to use", name)}
+	default:
+		return &requeue{delay: 2 * time.Second, message: fmt.Sprintf("waiting for the snapshots %s to be taken", name)}
+	}
+}
+
+// getSnapshotVolumes returns the names of the volumes of the instance that are mounted from claims
+func getSnapshotVolumes(ins edgev1alpha1.EdgeInterface) []string {
+	var names []string
+	for _, vol := range getVolumeList(ins) {
+		if vol.volumeSource.PersistentVolumeClaim != nil {
+			names = append(names, vol.name)
+		}
+	}
+	return names
+}
+
+func getVolumeSnapshot(ins edgev1alpha1.EdgeInterface, volume, name string) *snapshotv1.VolumeSnapshot {
+	claim := getClaimName(ins, volume)
+	vs := &snapshotv1.VolumeSnapshot{
+		ObjectMeta: internal.GetObjectMetadata(ins, name),
+		Spec: snapshotv1.VolumeSnapshotSpec{
+			Source:                  snapshotv1.VolumeSnapshotSource{PersistentVolumeClaimName: &claim},
+			VolumeSnapshotClassName: ins.GetVolumeSnapshots().VolumeSnapshotClassName,
+		},
+	}
+	vs.SetGroupVersionKind(snapshotv1.SchemeGroupVersion.WithKind("VolumeSnapshot"))
+	return vs
+}
+
+// getRestoreDataSource returns the VolumeSnapshot a PersistentVolumeClaim of the instance is restored from
+func getRestoreDataSource(ins edgev1alpha1.EdgeInterface, volume string) *corev1.TypedLocalObjectReference {
+	spec := ins.GetVolumeSnapshots()
+	if spec == nil || spec.RestoreFrom == nil {
+		return nil
+	}
+	group := snapshotv1.SchemeGroupVersion.Group
+	return &corev1.TypedLocalObjectReference{
+		APIGroup: &group,
+		Kind:     "VolumeSnapshot",
+		Name:     edgev1alpha1.GetVolumeSnapshotName(spec.RestoreFrom.Instance, volume, spec.RestoreFrom.Name),
+	}
+}
+
+func setSnapshotStatus(ctx context.Context, r *EdgeController, ins edgev1alpha1.EdgeInterface,
+	snapshot *edgev1alpha1.SnapshotStatus) *requeue {
+	status := ins.GetStatus()
+	status.Snapshot = snapshot
+	ins.SetStatus(&status)
+	if err := r.Status().Update(ctx, ins); err != nil {
+		return &requeue{curError: err}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
+	"github.com/stretchr/testify/assert"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestTakeSnapshots(t *testing.T) {
+	ins := &edgev1alpha1.NeuronEX{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        "neuronex",
+			Namespace:   "default",
+			Annotations: map[string]string{edgev1alpha1.SnapshotAnnotation: "daily-1"},
+		},
+		Spec: edgev1alpha1.NeuronEXSpec{
+			Neuron:              corev1.Container{Name: "neuron"},
+			EKuiper:             corev1.Container{Name: "ekuiper"},
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{},
+			VolumeSnapshots:     &edgev1alpha1.VolumeSnapshots{VolumeSnapshotClassName: &[]string{"csi-snapclass"}[0]},
+		},
+	}
+	ins.Default()
+	deploy := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuronex", Namespace: "default"},
+		Spec:       appsv1.DeploymentSpec{Replicas: &[]int32{1}[0]},
+	}
+	pod := &corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "neuronex-0", Namespace: "default", Labels: map[string]string{
+		edgev1alpha1.InstanceKey:  "neuronex",
+		edgev1alpha1.ComponentKey: string(edgev1alpha1.ComponentTypeNeuronEx),
+	}}}
+	r := newFakeController(t, ins, deploy, pod)
+	ctx := context.Background()
+	take := func() *requeue {
+		assert.Nil(t, r.Update(ctx, ins))
+		req := takeSnapshots(ctx, r, ins, log)
+		assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(ins), ins))
+		return req
+	}
+
+	// the pods are stopped first
+	req := take()
+	assert.NotNil(t, req)
+	assert.False(t, req.delayedRequeue)
+	assert.Equal(t, edgev1alpha1.SnapshotQuiescing, ins.Status.Snapshot.Phase)
+	assert.Equal(t, []string{"neuronex-neuron-data-daily-1", "neuronex-ekuiper-data-daily-1",
+		"neuronex-ekuiper-plugins-daily-1"}, ins.Status.Snapshot.VolumeSnapshots)
+	assert.Nil(t, r.Get(ctx, client.ObjectKeyFromObject(deploy), deploy))
+	assert.Equal(t, int32(0), *deploy.Spec.Replicas)
+
+	// then the snapshots are created
+	assert.Nil(t, r.Delete(ctx, pod))
+	req = take()
+	assert.NotNil(t, req)
+	assert.False(t, req.delayedRequeue)
+	assert.Equal(t, edgev1alpha1.SnapshotCreating, ins.Status.Snapshot.Phase)
+	vsList := &snapshotv1.VolumeSnapshotList{}
+	assert.Nil(t, r.List(ctx, vsList))
+	assert.Len(t, vsList.Items, 3)
+	vs := &snapshotv1.VolumeSnapshot{}
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "neuronex-neuron-data-daily-1"}, vs))
+	assert.Equal(t, "neuronex-neuron-data", *vs.Spec.Source.PersistentVolumeClaimName)
+	assert.Equal(t, "csi-snapclass", *vs.Spec.VolumeSnapshotClassName)
+	assert.Empty(t, vs.OwnerReferences)
+
+	// the pods are started again once the snapshots are taken
+	setStatus := func(status *snapshotv1.VolumeSnapshotStatus) {
+		for i := range vsList.Items {
+			vsList.Items[i].Status = status
+			assert.Nil(t, r.Update(ctx, &vsList.Items[i]))
+		}
+	}
+	setStatus(&snapshotv1.VolumeSnapshotStatus{CreationTime: &[]metav1.Time{metav1.Now()}[0]})
+	req = take()
+	assert.True(t, req.delayedRequeue)
+	assert.Equal(t, 10*time.Second, req.delay)
+	assert.Equal(t, edgev1alpha1.SnapshotTaken, ins.Status.Snapshot.Phase)
+
+	assert.Nil(t, r.List(ctx, vsList))
+	setStatus(&snapshotv1.VolumeSnapshotStatus{CreationTime: &[]metav1.Time{metav1.Now()}[0], ReadyToUse: &[]bool{true}[0]})
+	assert.Nil(t, take())
+	assert.Equal(t, edgev1alpha1.SnapshotCompleted, ins.Status.Snapshot.Phase)
+	assert.NotNil(t, ins.Status.Snapshot.CompletionTime)
+	assert.Nil(t, take())
+
+	// a new snapshot set fails without quiescing
+	ins.Annotations[edgev1alpha1.SnapshotAnnotation] = "daily-2"
+	ins.Spec.VolumeSnapshots.Quiesce = &[]bool{false}[0]
+	req = take()
+	assert.NotNil(t, req)
+	assert.Equal(t, edgev1alpha1.SnapshotCreating, ins.Status.Snapshot.Phase)
+	assert.Nil(t, r.Get(ctx, client.ObjectKey{Namespace: "default", Name: "neuronex-ekuiper-data-daily-2"}, vs))
+	vs.Status = &snapshotv1.VolumeSnapshotStatus{Error: &snapshotv1.VolumeSnapshotError{Message: &[]string{"no space left"}[0]}}
+	assert.Nil(t, r.Update(ctx, vs))
+	assert.Nil(t, take())
+	assert.Equal(t, edgev1alpha1.SnapshotFailed, ins.Status.Snapshot.Phase)
+	assert.Equal(t, "VolumeSnapshot neuronex-ekuiper-data-daily-2 failed: no space left", ins.Status.Snapshot.Message)
+}
+
+func TestGetPVCsRestoreFrom(t *testing.T) {
+	ins := &edgev1alpha1.Neuron{
+		ObjectMeta: metav1.ObjectMeta{Name: "neuron", Namespace: "default"},
+		Spec: edgev1alpha1.NeuronSpec{
+			Neuron:              corev1.Container{Name: "neuron"},
+			VolumeClaimTemplate: &corev1.PersistentVolumeClaimTemplate{},
+			VolumeSnapshots: &edgev1alpha1.VolumeSnapshots{
+				RestoreFrom: &edgev1alpha1.SnapshotReference{Instance: "old-neuron", Name: "daily-1"},
+			},
+		},
+	}
+	pvcs := getPVCs(ins)
+	assert.Len(t, pvcs, 1)
+	assert.Equal(t, &corev1.TypedLocalObjectReference{
+		APIGroup: &[]string{"snapshot.storage.k8s.io"}[0],
+		Kind:     "VolumeSnapshot",
+		Name:     "old-neuron-neuron-data-daily-1",
+	}, pvcs[0].Spec.DataSource)
+	assert.Nil(t, ins.Spec.VolumeClaimTemplate.Spec.DataSource)
+}
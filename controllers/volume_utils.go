@@ -1,6 +1,9 @@
 package controllers
 
 import (
+	"fmt"
+	"sort"
+
 	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
 	"github.com/emqx/edge-operator/internal"
 	corev1 "k8s.io/api/core/v1"
@@ -20,11 +23,16 @@ const (
 	ekuiperRuleSet = "ekuiper-init-rule-set"
 	sharedTmp      = "shared-tmp"
 	publicKey      = "public-key"
+	neuronConfig   = "neuron-config"
 )
 
 type mountAttr struct {
 	path     string
 	readOnly bool
+	// subPaths mounts each of the given files of the volume in path, instead of the whole volume
+	subPaths []string
+	// filesOnly never mounts the whole volume, even when there are no subPaths
+	filesOnly bool
 }
 
 type volumeInfo struct {
@@ -36,7 +44,7 @@ type volumeInfo struct {
 func getPersistentVolumeSource(ins edgev1alpha1.EdgeInterface, name string) (volumeSource corev1.VolumeSource) {
 	if ins.GetVolumeClaimTemplate() != nil {
 		volumeSource.PersistentVolumeClaim = &corev1.PersistentVolumeClaimVolumeSource{
-			ClaimName: internal.GetResNameOnPanic(ins.GetVolumeClaimTemplate(), name),
+			ClaimName: getClaimName(ins, name),
 		}
 		return
 	}
@@ -135,6 +143,61 @@ func getEkuiperInitRuleSetVol(ins edgev1alpha1.EdgeInterface) volumeInfo {
 	}
 }
 
+// getNeuronConfigVol mounts the files of the neuron config ConfigMap one by one, so that the other
+// files of the config directory in the image are kept. The whole ConfigMap is never mounted.
+func getNeuronConfigVol(ins edgev1alpha1.EdgeInterface) volumeInfo {
+	configMap := getNeuronConfigMap(ins)
+	files := make([]string, 0, len(configMap.Data))
+	for name := range configMap.Data {
+		files = append(files, name)
+	}
+	sort.Strings(files)
+
+	return volumeInfo{
+		name: neuronConfig,
+		mounts: map[mountTo]mountAttr{
+			mountToNeuron: {
+				path:      "/opt/neuron/config",
+				readOnly:  true,
+				subPaths:  files,
+				filesOnly: true,
+			},
+		},
+		volumeSource: corev1.VolumeSource{
+			ConfigMap: &corev1.ConfigMapVolumeSource{
+				LocalObjectReference: corev1.LocalObjectReference{
+					Name: configMap.Name,
+				},
+				DefaultMode: &[]int32{0444}[0],
+			},
+		},
+	}
+}
+
+// getDeviceVols mounts the devices of the node in the neuron container, except the ones exposed by a
+// device plugin that mounts them itself
+func getDeviceVols(ins edgev1alpha1.EdgeInterface) []volumeInfo {
+	var vols []volumeInfo
+	for i, device := range ins.GetDevices() {
+		if device.Resource != "" {
+			continue
+		}
+		vols = append(vols, volumeInfo{
+			name: fmt.Sprintf("%s%d", edgev1alpha1.DeviceVolumePrefix, i),
+			mounts: map[mountTo]mountAttr{
+				mountToNeuron: {path: device.GetContainerPath()},
+			},
+			volumeSource: corev1.VolumeSource{
+				HostPath: &corev1.HostPathVolumeSource{
+					Path: device.HostPath,
+					Type: &[]corev1.HostPathType{corev1.HostPathCharDev}[0],
+				},
+			},
+		})
+	}
+	return vols
+}
+
 func getShardTmpVol() volumeInfo {
 	return volumeInfo{
 		name: sharedTmp,
@@ -153,6 +216,14 @@ func getShardTmpVol() volumeInfo {
 }
 
 func getVolumeList(ins edgev1alpha1.EdgeInterface) []volumeInfo {
+	vols := getComponentVolumeList(ins)
+	if hasNeuronConfigFiles(ins) {
+		vols = append(vols, getNeuronConfigVol(ins))
+	}
+	return append(vols, getDeviceVols(ins)...)
+}
+
+func getComponentVolumeList(ins edgev1alpha1.EdgeInterface) []volumeInfo {
 	switch ins.GetComponentType() {
 	case edgev1alpha1.ComponentTypeNeuronEx:
 		return []volumeInfo{
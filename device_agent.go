@@ -0,0 +1,50 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"os"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/emqx/edge-operator/internal/device"
+)
+
+// runDeviceAgent labels the node of the agent with its serial ports and USB devices, it runs on every
+// node as a DaemonSet
+func runDeviceAgent(args []string) error {
+	fs := flag.NewFlagSet("device-agent", flag.ContinueOnError)
+	var nodeName string
+	fs.StringVar(&nodeName, "node-name", os.Getenv("NODE_NAME"), "The name of the node to label, defaults to $NODE_NAME.")
+	var sysfs string
+	fs.StringVar(&sysfs, "sysfs", "/sys", "The path of sysfs to scan.")
+	var linkDir string
+	fs.StringVar(&linkDir, "link-dir", "",
+		"Where the /dev/edge directory of the node is mounted to link the serial ports of the USB devices, no link is made if empty.")
+	var interval time.Duration
+	fs.DurationVar(&interval, "interval", 30*time.Second, "The interval between two scans.")
+	opts := zap.Options{
+		Development: true,
+		TimeEncoder: zapcore.RFC3339TimeEncoder,
+	}
+	opts.BindFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if nodeName == "" {
+		return errors.New("the node name must be set with --node-name or $NODE_NAME")
+	}
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		return err
+	}
+	agent := &device.Agent{Client: c, NodeName: nodeName, Sysfs: sysfs, LinkDir: linkDir}
+	agent.Run(ctrl.SetupSignalHandler(), interval)
+	return nil
+}
@@ -0,0 +1,154 @@
+package device
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var log = ctrl.Log.WithName("device-agent")
+
+// Labels returns the node labels that advertise the devices
+func Labels(devices []Device) map[string]string {
+	labels := map[string]string{}
+	for _, device := range devices {
+		var keys []string
+		if device.TTY != "" {
+			keys = append(keys, edgev1alpha1.TTYDeviceLabel(device.TTY))
+		}
+		if device.VendorID != "" {
+			keys = append(keys, edgev1alpha1.USBDeviceLabel(device.VendorID, device.ProductID))
+		}
+		for _, key := range keys {
+			if len(validation.IsQualifiedName(key)) == 0 {
+				labels[key] = "true"
+			}
+		}
+	}
+	return labels
+}
+
+// Links returns the names of the links to the serial ports of the USB devices, with the port they link
+// to. When several ports share a vendor and product, the first one is linked.
+func Links(devices []Device) map[string]string {
+	links := map[string]string{}
+	for _, device := range devices {
+		if device.TTY == "" || device.VendorID == "" {
+			continue
+		}
+		name := filepath.Base(edgev1alpha1.USBDeviceLink(device.VendorID, device.ProductID))
+		if _, ok := links[name]; !ok {
+			links[name] = device.TTY
+		}
+	}
+	return links
+}
+
+// Agent keeps the labels of its node and the links to the USB serial ports up to date with the devices
+// found in sysfs
+type Agent struct {
+	Client   client.Client
+	NodeName string
+	Sysfs    string
+	// LinkDir is where the /dev/edge directory of the node is mounted, the links are not managed if empty
+	LinkDir string
+}
+
+// Run syncs the devices every interval until the context is done
+func (a *Agent) Run(ctx context.Context, interval time.Duration) {
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := a.Sync(ctx); err != nil {
+			log.Error(err, "failed to sync the devices", "node", a.NodeName)
+		}
+	}, interval)
+}
+
+// Sync scans the devices once and updates the node labels and the links
+func (a *Agent) Sync(ctx context.Context) error {
+	devices, err := Scan(a.Sysfs)
+	if err != nil {
+		return err
+	}
+	if a.LinkDir != "" {
+		if err := syncLinks(a.LinkDir, Links(devices)); err != nil {
+			return err
+		}
+	}
+	return a.syncLabels(ctx, Labels(devices))
+}
+
+// syncLabels sets the device labels of the node and removes the ones of the devices that are gone
+func (a *Agent) syncLabels(ctx context.Context, labels map[string]string) error {
+	node := &corev1.Node{}
+	if err := a.Client.Get(ctx, client.ObjectKey{Name: a.NodeName}, node); err != nil {
+		return err
+	}
+	current := map[string]string{}
+	for key, value := range node.Labels {
+		if strings.HasPrefix(key, edgev1alpha1.DeviceLabelPrefix) {
+			current[key] = value
+		}
+	}
+	if reflect.DeepEqual(current, labels) {
+		return nil
+	}
+
+	patch := client.MergeFrom(node.DeepCopy())
+	if node.Labels == nil {
+		node.Labels = map[string]string{}
+	}
+	for key := range current {
+		delete(node.Labels, key)
+	}
+	for key, value := range labels {
+		node.Labels[key] = value
+	}
+	log.Info("Update the device labels", "node", a.NodeName, "labels", labels)
+	return a.Client.Patch(ctx, node, patch)
+}
+
+// syncLinks makes the links of the directory point to the serial ports, the other links are removed
+func syncLinks(dir string, links map[string]string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.Type()&os.ModeSymlink == 0 {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		target, err := os.Readlink(path)
+		if err != nil {
+			return err
+		}
+		tty, ok := links[entry.Name()]
+		if ok && target == filepath.Join("..", tty) {
+			delete(links, entry.Name())
+			continue
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	for name, tty := range links {
+		if err := os.Symlink(filepath.Join("..", tty), filepath.Join(dir, name)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
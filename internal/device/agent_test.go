@@ -0,0 +1,53 @@
+package device
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAgentSync(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{
+		Name: "edge",
+		Labels: map[string]string{
+			corev1.LabelHostname:                "edge",
+			"device.edge.emqx.io/ttyUSB1":       "true",
+			"device.edge.emqx.io/usb-0403-6001": "true",
+		},
+	}}
+	agent := &Agent{
+		Client:   fake.NewClientBuilder().WithObjects(node).Build(),
+		NodeName: "edge",
+		Sysfs:    fakeSysfs(t),
+		LinkDir:  filepath.Join(t.TempDir(), "edge"),
+	}
+	assert.Nil(t, os.MkdirAll(agent.LinkDir, 0755))
+	assert.Nil(t, os.Symlink("../ttyUSB1", filepath.Join(agent.LinkDir, "usb-067b-2303")))
+
+	assert.Nil(t, agent.Sync(context.Background()))
+	assert.Nil(t, agent.Client.Get(context.Background(), client.ObjectKeyFromObject(node), node))
+	assert.Equal(t, map[string]string{
+		corev1.LabelHostname:                "edge",
+		"device.edge.emqx.io/ttyS0":         "true",
+		"device.edge.emqx.io/ttyUSB0":       "true",
+		"device.edge.emqx.io/usb-0403-6001": "true",
+		"device.edge.emqx.io/usb-046d-c52b": "true",
+	}, node.Labels)
+
+	entries, err := os.ReadDir(agent.LinkDir)
+	assert.Nil(t, err)
+	assert.Len(t, entries, 1)
+	target, err := os.Readlink(filepath.Join(agent.LinkDir, "usb-0403-6001"))
+	assert.Nil(t, err)
+	assert.Equal(t, "../ttyUSB0", target)
+
+	// nothing changes on the next sync
+	assert.Nil(t, agent.Sync(context.Background()))
+}
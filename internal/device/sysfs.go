@@ -0,0 +1,139 @@
+package device
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// usbHubClass is the bDeviceClass of the USB hubs, which are not advertised
+const usbHubClass = "09"
+
+// Device is a serial port or a USB device of the node
+type Device struct {
+	// TTY is the name of the serial port in /dev, empty for a USB device without serial port
+	TTY string
+	// VendorID and ProductID identify a USB device, they are empty for a serial port of the board
+	VendorID  string
+	ProductID string
+}
+
+// Scan returns the serial ports found in the class/tty directory of sysfs, with the vendor and product of
+// their USB device, followed by the other USB devices found in bus/usb/devices
+func Scan(sysfs string) ([]Device, error) {
+	devices, err := scanTTYs(sysfs)
+	if err != nil {
+		return nil, err
+	}
+
+	found := map[[2]string]struct{}{}
+	for _, device := range devices {
+		found[[2]string{device.VendorID, device.ProductID}] = struct{}{}
+	}
+	usbDevices, err := scanUSB(sysfs)
+	if err != nil {
+		return nil, err
+	}
+	for _, device := range usbDevices {
+		key := [2]string{device.VendorID, device.ProductID}
+		if _, ok := found[key]; !ok {
+			devices = append(devices, device)
+			found[key] = struct{}{}
+		}
+	}
+	return devices, nil
+}
+
+// scanTTYs returns the serial ports backed by a device. The virtual terminals have no device, and the
+// ports of a serial driver that found no UART have the type 0.
+func scanTTYs(sysfs string) ([]Device, error) {
+	root, err := filepath.EvalSymlinks(sysfs)
+	if err != nil {
+		return nil, err
+	}
+	entries, err := os.ReadDir(filepath.Join(root, "class", "tty"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	for _, entry := range entries {
+		dir := filepath.Join(root, "class", "tty", entry.Name())
+		deviceDir, err := filepath.EvalSymlinks(filepath.Join(dir, "device"))
+		if err != nil {
+			continue
+		}
+		if portType, err := readAttr(dir, "type"); err == nil && portType == "0" {
+			continue
+		}
+		device := Device{TTY: entry.Name()}
+		device.VendorID, device.ProductID = findUSBDevice(root, deviceDir)
+		devices = append(devices, device)
+	}
+	sort.Slice(devices, func(i, j int) bool { return devices[i].TTY < devices[j].TTY })
+	return devices, nil
+}
+
+// findUSBDevice returns the vendor and product of the USB device that contains the device directory, if any
+func findUSBDevice(root, dir string) (vendorID, productID string) {
+	for ; strings.HasPrefix(dir, root+string(filepath.Separator)); dir = filepath.Dir(dir) {
+		vendorID, err := readAttr(dir, "idVendor")
+		if err != nil {
+			continue
+		}
+		productID, err := readAttr(dir, "idProduct")
+		if err != nil {
+			continue
+		}
+		return vendorID, productID
+	}
+	return "", ""
+}
+
+// scanUSB returns the USB devices other than the hubs
+func scanUSB(sysfs string) ([]Device, error) {
+	entries, err := os.ReadDir(filepath.Join(sysfs, "bus", "usb", "devices"))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	for _, entry := range entries {
+		dir := filepath.Join(sysfs, "bus", "usb", "devices", entry.Name())
+		if class, err := readAttr(dir, "bDeviceClass"); err == nil && class == usbHubClass {
+			continue
+		}
+		vendorID, err := readAttr(dir, "idVendor")
+		if err != nil {
+			continue
+		}
+		productID, err := readAttr(dir, "idProduct")
+		if err != nil {
+			continue
+		}
+		devices = append(devices, Device{VendorID: vendorID, ProductID: productID})
+	}
+	sort.Slice(devices, func(i, j int) bool {
+		if devices[i].VendorID != devices[j].VendorID {
+			return devices[i].VendorID < devices[j].VendorID
+		}
+		return devices[i].ProductID < devices[j].ProductID
+	})
+	return devices, nil
+}
+
+func readAttr(dir, name string) (string, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil {
+		return "", err
+	}
+	return strings.ToLower(strings.TrimSpace(string(data))), nil
+}
@@ -0,0 +1,71 @@
+package device
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeSysfs builds a sysfs tree with a USB serial adapter, a USB receiver without serial port, a root hub,
+// a serial port of the board, a serial port without UART and a virtual terminal
+func fakeSysfs(t *testing.T) string {
+	root := t.TempDir()
+	write := func(path, content string) {
+		path = filepath.Join(root, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		assert.Nil(t, os.WriteFile(path, []byte(content+"\n"), 0644))
+	}
+	link := func(path, target string) {
+		path = filepath.Join(root, path)
+		assert.Nil(t, os.MkdirAll(filepath.Dir(path), 0755))
+		rel, err := filepath.Rel(filepath.Dir(path), filepath.Join(root, target))
+		assert.Nil(t, err)
+		assert.Nil(t, os.Symlink(rel, path))
+	}
+
+	usb := "devices/pci0000:00/0000:00:14.0/usb1"
+	write(usb+"/idVendor", "1d6b")
+	write(usb+"/idProduct", "0002")
+	write(usb+"/bDeviceClass", "09")
+	write(usb+"/1-1/idVendor", "0403")
+	write(usb+"/1-1/idProduct", "6001")
+	write(usb+"/1-1/bDeviceClass", "00")
+	write(usb+"/1-1/1-1:1.0/ttyUSB0/tty/ttyUSB0/dev", "188:0")
+	link(usb+"/1-1/1-1:1.0/ttyUSB0/tty/ttyUSB0/device", usb+"/1-1/1-1:1.0/ttyUSB0")
+	write(usb+"/1-2/idVendor", "046D")
+	write(usb+"/1-2/idProduct", "C52B")
+	write(usb+"/1-2/bDeviceClass", "00")
+	link("bus/usb/devices/usb1", usb)
+	link("bus/usb/devices/1-1", usb+"/1-1")
+	link("bus/usb/devices/1-2", usb+"/1-2")
+	link("bus/usb/devices/1-1:1.0", usb+"/1-1/1-1:1.0")
+
+	serial := "devices/platform/serial8250"
+	write(serial+"/tty/ttyS0/type", "4")
+	link(serial+"/tty/ttyS0/device", serial)
+	write(serial+"/tty/ttyS1/type", "0")
+	link(serial+"/tty/ttyS1/device", serial)
+	write("devices/virtual/tty/tty0/dev", "4:0")
+
+	link("class/tty/ttyUSB0", usb+"/1-1/1-1:1.0/ttyUSB0/tty/ttyUSB0")
+	link("class/tty/ttyS0", serial+"/tty/ttyS0")
+	link("class/tty/ttyS1", serial+"/tty/ttyS1")
+	link("class/tty/tty0", "devices/virtual/tty/tty0")
+	return root
+}
+
+func TestScan(t *testing.T) {
+	devices, err := Scan(fakeSysfs(t))
+	assert.Nil(t, err)
+	assert.Equal(t, []Device{
+		{TTY: "ttyS0"},
+		{TTY: "ttyUSB0", VendorID: "0403", ProductID: "6001"},
+		{VendorID: "046d", ProductID: "c52b"},
+	}, devices)
+
+	devices, err = Scan(t.TempDir())
+	assert.Nil(t, err)
+	assert.Empty(t, devices)
+}
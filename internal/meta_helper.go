@@ -1,6 +1,7 @@
 package internal
 
 import (
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -8,12 +9,20 @@ import (
 // GetObjectMetadata returns the ObjectMetadata for a component
 func GetObjectMetadata(ins metav1.Object, name string) metav1.ObjectMeta {
 	metadata := &metav1.ObjectMeta{
-		Name:        name,
-		Namespace:   ins.GetNamespace(),
-		Labels:      ins.GetLabels(),
-		Annotations: ins.GetAnnotations(),
+		Name:      name,
+		Namespace: ins.GetNamespace(),
+		Labels:    ins.GetLabels(),
+	}
+	// the annotations that control the instance itself are not propagated to the components
+	for key, value := range ins.GetAnnotations() {
+		if key == corev1.LastAppliedConfigAnnotation || edgev1alpha1.IsInstanceAnnotation(key) {
+			continue
+		}
+		if metadata.Annotations == nil {
+			metadata.Annotations = make(map[string]string)
+		}
+		metadata.Annotations[key] = value
 	}
-	delete(metadata.Annotations, corev1.LastAppliedConfigAnnotation)
 	if metadata.Namespace == "" {
 		metadata.Namespace = corev1.NamespaceDefault
 	}
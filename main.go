@@ -18,7 +18,11 @@ package main
 
 import (
 	"flag"
+	"fmt"
 	"os"
+	"strings"
+	// the time zones of the maintenance windows are resolved in distroless images
+	_ "time/tzdata"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -29,11 +33,14 @@ import (
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
+	configv1alpha1 "github.com/emqx/edge-operator/api/config/v1alpha1"
 	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
 	"github.com/emqx/edge-operator/controllers"
+	snapshotv1 "github.com/kubernetes-csi/external-snapshotter/client/v4/apis/volumesnapshot/v1"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -46,6 +53,8 @@ func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 
 	utilruntime.Must(edgev1alpha1.AddToScheme(scheme))
+	utilruntime.Must(configv1alpha1.AddToScheme(scheme))
+	utilruntime.Must(snapshotv1.AddToScheme(scheme))
 	//+kubebuilder:scaffold:scheme
 }
 
@@ -54,19 +63,52 @@ func init() {
 //+kubebuilder:rbac:groups="",resources=persistentvolumes,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups="",resources=persistentvolumeclaims,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update;patch
-//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups="",resources=pods/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch
+//+kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch;create;update;patch
 //+kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch
+//+kubebuilder:rbac:groups=apps,resources=controllerrevisions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=batch,resources=jobs,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=snapshot.storage.k8s.io,resources=volumesnapshots,verbs=get;list;watch;create;delete
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		if err := render(os.Args[2:], os.Stdout); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "device-agent" {
+		if err := runDeviceAgent(os.Args[2:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var serverSideApply bool
+	var watchNamespaces string
+	var configFile string
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.BoolVar(&serverSideApply, "server-side-apply", true,
+		"Manage the child resources with server-side apply. "+
+			"Disabling this falls back to the last-applied-configuration annotation and full updates.")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"The comma separated namespaces to watch, all namespaces are watched if empty. "+
+			"Watching only some namespaces needs the Role based RBAC in each of them instead of the ClusterRole.")
+	flag.StringVar(&configFile, "config", "",
+		"The operator configuration file with the default images, probe timings and controller options. "+
+			"Command-line flags override the manager options set in this file.")
 	opts := zap.Options{
 		Development: true,
 		TimeEncoder: zapcore.RFC3339TimeEncoder,
@@ -76,13 +118,9 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
-		Scheme:                 scheme,
-		MetricsBindAddress:     metricsAddr,
-		Port:                   9443,
-		HealthProbeBindAddress: probeAddr,
-		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "e9df4402.edge.emqx.io",
+	namespaces := parseNamespaces(watchNamespaces)
+	mgrOptions := ctrl.Options{
+		Scheme: scheme,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -94,7 +132,39 @@ func main() {
 		// if you are doing or is intended to do any operation such as perform cleanups
 		// after the manager stops then its usage might be unsafe.
 		// LeaderElectionReleaseOnCancel: true,
+	}
+	switch len(namespaces) {
+	case 0:
+		setupLog.Info("watching all namespaces")
+	case 1:
+		setupLog.Info("watching a single namespace", "namespace", namespaces[0])
+		mgrOptions.Namespace = namespaces[0]
+	default:
+		setupLog.Info("watching multiple namespaces", "namespaces", namespaces)
+		mgrOptions.NewCache = cache.MultiNamespacedCacheBuilder(namespaces)
+	}
+
+	operatorConfig, err := loadOperatorConfig(configFile)
+	if err != nil {
+		setupLog.Error(err, "unable to load the operator config file")
+		os.Exit(1)
+	}
+	// the options of the config file are applied first, the flags only override them when they are set
+	setFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) { setFlags[f.Name] = true })
+	mgrOptions, err = getManagerOptions(mgrOptions, operatorConfig, managerFlags{
+		metricsAddr:          metricsAddr,
+		probeAddr:            probeAddr,
+		enableLeaderElection: enableLeaderElection,
+		set:                  setFlags,
 	})
+	if err != nil {
+		setupLog.Error(err, "unable to load the operator config file")
+		os.Exit(1)
+	}
+	edgev1alpha1.SetOperatorConfig(operatorConfig)
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), mgrOptions)
 	if err != nil {
 		setupLog.Error(err, "unable to start manager")
 		os.Exit(1)
@@ -102,17 +172,20 @@ func main() {
 
 	eventRecorder := mgr.GetEventRecorderFor("neuronEX-controller")
 	client := mgr.GetClient()
-	if err = controllers.NewNeuronEXReconciler(client, eventRecorder).SetupWithManager(mgr); err != nil {
+	options := controllers.Options{
+		ServerSideApply: serverSideApply,
+	}
+	if err = controllers.NewNeuronEXReconciler(client, eventRecorder, options).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "NeuronEX")
 		os.Exit(1)
 	}
 	eventRecorder = mgr.GetEventRecorderFor("eKuiper-controller")
-	if err = controllers.NewEKuiperReconciler(client, eventRecorder).SetupWithManager(mgr); err != nil {
+	if err = controllers.NewEKuiperReconciler(client, eventRecorder, options).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "EKuiper")
 		os.Exit(1)
 	}
 	eventRecorder = mgr.GetEventRecorderFor("neuron-controller")
-	if err = controllers.NewNeuronReconciler(client, eventRecorder).SetupWithManager(mgr); err != nil {
+	if err = controllers.NewNeuronReconciler(client, eventRecorder, options).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Neuron")
 		os.Exit(1)
 	}
@@ -120,15 +193,15 @@ func main() {
 	//+kubebuilder:scaffold:builder
 
 	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
-		if err = (&edgev1alpha1.Neuron{}).SetupWebhookWithManager(mgr); err != nil {
+		if err = (&edgev1alpha1.Neuron{}).SetupWebhookWithManager(mgr, namespaces...); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "Neuron")
 			os.Exit(1)
 		}
-		if err = (&edgev1alpha1.EKuiper{}).SetupWebhookWithManager(mgr); err != nil {
+		if err = (&edgev1alpha1.EKuiper{}).SetupWebhookWithManager(mgr, namespaces...); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "EKuiper")
 			os.Exit(1)
 		}
-		if err = (&edgev1alpha1.NeuronEX{}).SetupWebhookWithManager(mgr); err != nil {
+		if err = (&edgev1alpha1.NeuronEX{}).SetupWebhookWithManager(mgr, namespaces...); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "NeuronEX")
 			os.Exit(1)
 		}
@@ -149,3 +222,14 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// parseNamespaces splits the comma separated namespaces, ignoring empty entries
+func parseNamespaces(s string) []string {
+	var namespaces []string
+	for _, namespace := range strings.Split(s, ",") {
+		if namespace = strings.TrimSpace(namespace); namespace != "" {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	return namespaces
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+	"sigs.k8s.io/yaml"
+
+	edgev1alpha1 "github.com/emqx/edge-operator/api/v1alpha1"
+	"github.com/emqx/edge-operator/controllers"
+)
+
+// render prints the manifests that the operator generates for the custom resources in a file,
+// without connecting to a cluster
+func render(args []string, stdout io.Writer) error {
+	fs := flag.NewFlagSet("render", flag.ContinueOnError)
+	var file string
+	fs.StringVar(&file, "f", "", "The file that contains the Neuron, EKuiper or NeuronEX resources to render, \"-\" reads from stdin.")
+	var configFile string
+	fs.StringVar(&configFile, "config", "", "The operator configuration file to read the defaults from.")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if file == "" {
+		return errors.New("the file to render must be set with -f")
+	}
+	config, err := loadOperatorConfig(configFile)
+	if err != nil {
+		return err
+	}
+	edgev1alpha1.SetOperatorConfig(config)
+
+	var in io.Reader = os.Stdin
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	decoder := serializer.NewCodecFactory(scheme).UniversalDeserializer()
+	reader := utilyaml.NewYAMLReader(bufio.NewReader(in))
+	for {
+		doc, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if len(bytes.TrimSpace(doc)) == 0 {
+			continue
+		}
+
+		obj, _, err := decoder.Decode(doc, nil, nil)
+		if err != nil {
+			return err
+		}
+		ins, ok := obj.(edgev1alpha1.EdgeInterface)
+		if !ok {
+			return fmt.Errorf("unsupported kind %s", obj.GetObjectKind().GroupVersionKind().Kind)
+		}
+
+		ins.Default()
+		if err := ins.ValidateCreate(); err != nil {
+			return fmt.Errorf("%s %s is invalid: %w", obj.GetObjectKind().GroupVersionKind().Kind, ins.GetName(), err)
+		}
+		for _, warning := range edgev1alpha1.GetWarnings(ins) {
+			fmt.Fprintf(os.Stderr, "Warning: %s %s: %s\n", obj.GetObjectKind().GroupVersionKind().Kind, ins.GetName(), warning)
+		}
+
+		for _, child := range controllers.Render(ins) {
+			out, err := yaml.Marshal(child)
+			if err != nil {
+				return err
+			}
+			if _, err := fmt.Fprintf(stdout, "---\n%s", out); err != nil {
+				return err
+			}
+		}
+	}
+}